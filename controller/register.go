@@ -3,6 +3,7 @@
 package controller
 
 import (
+	"GoAPIfy/controller/trash"
 	"GoAPIfy/controller/user"
 	"GoAPIfy/service/appService"
 	"GoAPIfy/service/auth"
@@ -11,7 +12,8 @@ import (
 // Handlers defines a struct containing all the application's handlers, each of which
 // is responsible for handling a different type of request.
 type Handlers struct {
-	UserHandler *user.UserHandler // The user handler manages user-related requests
+	UserHandler  *user.UserHandler   // The user handler manages user-related requests
+	TrashHandler *trash.TrashHandler // The trash handler lists and restores soft deleted records
 	// Add more handlers as needed
 }
 
@@ -20,7 +22,8 @@ type Handlers struct {
 // Returns a pointer to the Handlers struct.
 func RegisterHandler(s appService.AppService, authService auth.AuthService) *Handlers {
 	return &Handlers{
-		UserHandler: user.NewUserHandler(s, authService),
+		UserHandler:  user.NewUserHandler(s, authService),
+		TrashHandler: trash.NewTrashHandler(s, authService),
 		// Initialize other handlers as needed
 	}
 }
@@ -0,0 +1,14 @@
+package trash
+
+import (
+	"GoAPIfy/controller/user"
+	"GoAPIfy/model"
+)
+
+// RegisterEntities lists the entities whose trashed records can be managed through the trash endpoints.
+// Add new entities here together with the formatter used to return them.
+func RegisterEntities(h *TrashHandler) {
+	Register(h, "user", func(u model.User) interface{} { return user.UserFormatter(u) })
+
+	// Register more entities as needed
+}
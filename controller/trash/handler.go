@@ -0,0 +1,151 @@
+// Package trash defines a generic admin controller for soft deleted records.
+// Any model embedding gorm.Model can be registered, after which its trashed records can be listed, restored and
+// permanently deleted through the same endpoints without writing a dedicated handler.
+package trash
+
+import (
+	"GoAPIfy/core"
+	"GoAPIfy/model"
+	"GoAPIfy/service/appService"
+	"GoAPIfy/service/auth"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// entity holds the typed operations of a registered entity behind an untyped interface.
+type entity struct {
+	list        func(m model.Model, page int, perPage int) (*model.Pagination, error)
+	restore     func(m model.Model, id string) (interface{}, error)
+	forceDelete func(m model.Model, id string) error
+}
+
+// TrashHandler is a struct containing methods for listing and restoring soft deleted records of registered entities.
+type TrashHandler struct {
+	s           appService.AppService
+	authService auth.AuthService
+	entities    map[string]entity
+}
+
+// NewTrashHandler creates a new TrashHandler instance with every entity listed in RegisterEntities and returns a pointer to it.
+func NewTrashHandler(s appService.AppService, authService auth.AuthService) *TrashHandler {
+	h := &TrashHandler{s, authService, map[string]entity{}}
+	RegisterEntities(h)
+	return h
+}
+
+// Register makes the trashed records of the model type T available under the given entity name.
+// The formatter converts a record into its response format, so hidden fields such as passwords are never exposed.
+func Register[T any](h *TrashHandler, name string, formatter func(T) interface{}) {
+	h.entities[name] = entity{
+		list: func(m model.Model, page int, perPage int) (*model.Pagination, error) {
+			pagination, err := model.NewRepository[T](m).OnlyTrashed().Paginate(page, perPage)
+			if err != nil {
+				return nil, err
+			}
+			records := pagination.Records.([]T)
+			formatted := make([]interface{}, len(records))
+			for i, record := range records {
+				formatted[i] = formatter(record)
+			}
+			pagination.Records = formatted
+			return pagination, nil
+		},
+		restore: func(m model.Model, id string) (interface{}, error) {
+			repository := model.NewRepository[T](m)
+			record, err := repository.OnlyTrashed().Find(id)
+			if err != nil {
+				return nil, err
+			}
+			if err := repository.Restore(&record); err != nil {
+				return nil, err
+			}
+			return formatter(record), nil
+		},
+		forceDelete: func(m model.Model, id string) error {
+			repository := model.NewRepository[T](m)
+			record, err := repository.OnlyTrashed().Find(id)
+			if err != nil {
+				return err
+			}
+			return repository.ForceDelete(&record)
+		},
+	}
+}
+
+// Index is a method for handling GET requests that list the trashed records of an entity.
+// It reads the entity name from the ":entity" path parameter and the optional "page" and "per_page" query parameters.
+func (h *TrashHandler) Index(c *gin.Context) {
+	e, ok := h.entities[c.Param("entity")]
+	if !ok {
+		core.SendResponse(c, http.StatusNotFound, core.FormatError(errors.New("unknown entity")))
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		core.SendResponse(c, http.StatusBadRequest, core.FormatError(errors.New("page must be a positive number")))
+		return
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "15"))
+	if err != nil || perPage < 1 || perPage > 100 {
+		core.SendResponse(c, http.StatusBadRequest, core.FormatError(errors.New("per_page must be between 1 and 100")))
+		return
+	}
+
+	pagination, err := e.list(h.s.Model.WithContext(c.Request.Context()), page, perPage)
+	if err != nil {
+		core.SendError(c, err)
+		return
+	}
+
+	core.SendResponse(c, http.StatusOK, pagination)
+}
+
+// Restore is a method for handling PATCH requests that restore a single trashed record of an entity.
+// It reads the entity name and the record ID from the ":entity" and ":id" path parameters.
+func (h *TrashHandler) Restore(c *gin.Context) {
+	e, ok := h.entities[c.Param("entity")]
+	if !ok {
+		core.SendResponse(c, http.StatusNotFound, core.FormatError(errors.New("unknown entity")))
+		return
+	}
+
+	record, err := e.restore(h.s.Model.WithContext(c.Request.Context()), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		core.SendResponse(c, http.StatusNotFound, core.FormatError(errors.New("trashed record not found")))
+		return
+	}
+	if err != nil {
+		core.SendError(c, err)
+		return
+	}
+
+	core.SendResponse(c, http.StatusOK, record)
+}
+
+// ForceDelete is a method for handling DELETE requests that permanently delete a single trashed record of an entity.
+// Only records that are already in the trash can be purged. It reads the entity name and the record ID from the
+// ":entity" and ":id" path parameters.
+func (h *TrashHandler) ForceDelete(c *gin.Context) {
+	e, ok := h.entities[c.Param("entity")]
+	if !ok {
+		core.SendResponse(c, http.StatusNotFound, core.FormatError(errors.New("unknown entity")))
+		return
+	}
+
+	err := e.forceDelete(h.s.Model.WithContext(c.Request.Context()), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		core.SendResponse(c, http.StatusNotFound, core.FormatError(errors.New("trashed record not found")))
+		return
+	}
+	if err != nil {
+		core.SendError(c, err)
+		return
+	}
+
+	core.SendResponse(c, http.StatusOK, gin.H{"deleted": true})
+}
@@ -0,0 +1,102 @@
+package trash
+
+import (
+	"GoAPIfy/internal/testdb"
+	"GoAPIfy/model"
+	"GoAPIfy/service/appService"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// note is the entity of the trash handler tests, its formatter hides the secret.
+type note struct {
+	gorm.Model
+	Title  string
+	Secret string
+}
+
+// newTestRouter serves the trash endpoints for the note entity, with the notes "live" (ID 1) and "trashed" (ID 2).
+func newTestRouter(t *testing.T) (*gin.Engine, *model.Repository[note]) {
+	t.Helper()
+	db := testdb.Open(t, &note{})
+	s := appService.AppService{Model: model.NewModel(db)}
+	notes := model.NewRepository[note](s.Model)
+	live, trashed := note{Title: "live", Secret: "x"}, note{Title: "trashed", Secret: "y"}
+	for _, n := range []*note{&live, &trashed} {
+		if err := notes.Create(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := notes.Delete(&trashed); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &TrashHandler{s: s, entities: map[string]entity{}}
+	Register(h, "note", func(n note) interface{} { return gin.H{"id": n.ID, "title": n.Title} })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/trash/:entity", h.Index)
+	router.PATCH("/trash/:entity/:id/restore", h.Restore)
+	router.DELETE("/trash/:entity/:id", h.ForceDelete)
+	return router, notes
+}
+
+func TestTrashEndpoints(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		wantStatus    int
+		wantLive      int64
+		wantTrashed   int64
+		wantRecordsIn string
+	}{
+		{"list trashed records", http.MethodGet, "/trash/note", http.StatusOK, 1, 1, "trashed"},
+		{"list an unknown entity", http.MethodGet, "/trash/user", http.StatusNotFound, 1, 1, ""},
+		{"list with an invalid page", http.MethodGet, "/trash/note?page=0", http.StatusBadRequest, 1, 1, ""},
+		{"restore a trashed record", http.MethodPatch, "/trash/note/2/restore", http.StatusOK, 2, 0, ""},
+		{"restore a live record", http.MethodPatch, "/trash/note/1/restore", http.StatusNotFound, 1, 1, ""},
+		{"force delete a trashed record", http.MethodDelete, "/trash/note/2", http.StatusOK, 1, 0, ""},
+		{"force delete a live record", http.MethodDelete, "/trash/note/1", http.StatusNotFound, 1, 1, ""},
+		{"force delete a missing record", http.MethodDelete, "/trash/note/9", http.StatusNotFound, 1, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, notes := newTestRouter(t)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+
+			if tt.wantRecordsIn != "" {
+				var response struct {
+					Data struct {
+						Records []map[string]interface{} `json:"records"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+				records := response.Data.Records
+				if len(records) != 1 || records[0]["title"] != tt.wantRecordsIn || records[0]["Secret"] != nil {
+					t.Errorf("records = %v, want only the formatted %q", records, tt.wantRecordsIn)
+				}
+			}
+
+			if live, _ := notes.Count(); live != tt.wantLive {
+				t.Errorf("%d live notes, want %d", live, tt.wantLive)
+			}
+			if trashed, _ := notes.OnlyTrashed().Count(); trashed != tt.wantTrashed {
+				t.Errorf("%d trashed notes, want %d", trashed, tt.wantTrashed)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ package user
 import (
 	"GoAPIfy/core"
 	"GoAPIfy/core/math"
+	"GoAPIfy/core/query"
 	"GoAPIfy/model"
 	"GoAPIfy/service/appService"
 	"GoAPIfy/service/auth"
@@ -16,17 +17,19 @@ import (
 
 // UserHandler is a struct containing methods for handling user-related requests.
 // It takes a model.Model as input, which is used to interact with the database and perform
-// CRUD operations on user data.
+// CRUD operations on user data. Every query runs with the request context, which carries the audit actor,
+// the tenant and the query log of the request.
 type UserHandler struct {
 	s           appService.AppService
 	authService auth.AuthService
+	users       *model.Repository[model.User]
 }
 
 // NewUserHandler creates a new UserHandler instance and returns a pointer to it.
 // It takes a model.Model as input, which is used to interact with the database and perform
 // CRUD operations on user data.
 func NewUserHandler(s appService.AppService, authService auth.AuthService) *UserHandler {
-	return &UserHandler{s, authService}
+	return &UserHandler{s, authService, model.NewRepository[model.User](s.Model)}
 }
 
 // CreateUser is a method for handling POST requests related to creating new users.
@@ -41,12 +44,14 @@ func (h *UserHandler) Register(c *gin.Context) {
 		// If the request body is invalid or incomplete, send an error response
 		errorMessage := core.FormatError(err)
 		core.SendResponse(c, http.StatusUnprocessableEntity, errorMessage)
+		return
 	}
 
 	// Check that the password and confirmation password match
 	if input.Password != input.CPassword {
 		errorMessage := core.FormatError(errors.New("passwords do not match"))
 		core.SendResponse(c, http.StatusUnprocessableEntity, errorMessage)
+		return
 	}
 
 	// Hash the password
@@ -54,6 +59,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	if err != nil {
 		errorMessage := core.FormatError(errors.New("failed to hash password"))
 		core.SendResponse(c, http.StatusInternalServerError, errorMessage)
+		return
 	}
 
 	// Create a new User instance with the input data and hashed password
@@ -64,10 +70,11 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// Create the user in the database
-	err = h.s.Model.Load(&user).Save()
+	err = h.users.WithContext(c.Request.Context()).Create(user)
 	if err != nil {
 		errorMessage := core.FormatError(errors.New("failed to create user"))
 		core.SendResponse(c, http.StatusInternalServerError, errorMessage)
+		return
 	}
 
 	// Generate a JWT token using the user data
@@ -75,6 +82,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	if err != nil {
 		errorMessage := core.FormatError(errors.New("failed to generate token"))
 		core.SendResponse(c, http.StatusInternalServerError, errorMessage)
+		return
 	}
 
 	// Format the user data and token into a response object
@@ -98,25 +106,27 @@ func (h *UserHandler) Login(c *gin.Context) {
 		// If the request body is invalid or incomplete, send an error response
 		errorMessage := core.FormatError(err)
 		core.SendResponse(c, http.StatusUnprocessableEntity, errorMessage)
+		return
 	}
 
 	email := input.Email       // Get the email from the input data
 	password := input.Password // Get the password from the input data
 
 	// Retrieve the user data from the database using the email address as the key
-	var userData model.User
-	err = h.s.Model.Load(&userData).Where("email = ?", email).Get()
+	userData, err := h.users.WithContext(c.Request.Context()).Where("email = ?", email).First()
 	if err != nil {
 		// If there is an error retrieving the user data, send an error response
-		// Note: this assumes that the FindByKey meths an error when the key is not found in the database
+		// Note: First returns gorm.ErrRecordNotFound when no user has the given email address
 		errorMessage := core.FormatError(errors.New("email not match"))
 		core.SendResponse(c, http.StatusUnprocessableEntity, errorMessage)
+		return
 	}
 
 	// Check that the email in the retrieved user data matches the email provided in the login input
 	if userData.Email != email {
 		errorMessage := core.FormatError(errors.New("email not match"))
 		core.SendResponse(c, http.StatusUnprocessableEntity, errorMessage)
+		return
 	}
 
 	// Check that the password provided in the login input matches the password in the retrieved user data
@@ -170,16 +180,40 @@ func (h *UserHandler) IsEmailAvailable(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	response := false
-	err = h.s.Model.Load(&user).Where("email = ?", input.Email).Get()
+	exists, err := h.users.WithContext(c.Request.Context()).Where("email = ?", input.Email).Exists()
 	if err != nil {
-		response = true
+		core.SendError(c, err)
+		return
 	}
 
-	if user.ID == 0 {
-		response = true
+	core.SendResponse(c, http.StatusOK, !exists)
+}
+
+// Index is a method for handling GET requests that list users, it is served to administrators only.
+// It supports the page and per_page query parameters, and the filter, sort and fields parameters
+// allowed by UserQueryOptions. Parameters that are not allowed result in a 400 Bad Request response.
+func (h *UserHandler) Index(c *gin.Context) {
+	var input ListInput
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := core.FormatError(err)
+		core.SendResponse(c, http.StatusBadRequest, errorMessage)
+		return
 	}
 
-	core.SendResponse(c, http.StatusOK, response)
+	q, err := query.Parse(c, UserQueryOptions)
+	if err != nil {
+		errorMessage := core.FormatError(err)
+		core.SendResponse(c, http.StatusBadRequest, errorMessage)
+		return
+	}
+
+	pagination, err := h.users.WithContext(c.Request.Context()).ApplyScope(q.Scope()).Paginate(input.Page, input.PerPage)
+	if err != nil {
+		core.SendError(c, err)
+		return
+	}
+
+	pagination.Records = UserCollectionFormatter(pagination.Records.([]model.User))
+	core.SendResponse(c, http.StatusOK, pagination)
 }
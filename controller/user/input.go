@@ -25,3 +25,9 @@ type EmailVerificationInput struct {
 type IsEmailAvailableInput struct {
 	Email string `json:"email" binding:"required"`
 }
+
+// ListInput defines the expected pagination query parameters of list endpoints.
+type ListInput struct {
+	Page    int `form:"page,default=1" binding:"min=1"`              // The page number, starting at 1
+	PerPage int `form:"per_page,default=15" binding:"min=1,max=100"` // The number of records per page
+}
@@ -0,0 +1,14 @@
+package user
+
+import "GoAPIfy/core/query"
+
+// UserQueryOptions is the allowlist of filters, sorts, includes and fields accepted by the user list endpoint.
+// Columns that are not listed here are rejected with a 400 Bad Request response.
+// Emails are not filterable nor sortable, so the list cannot be used to find out whether an address is registered.
+var UserQueryOptions = query.Options{
+	Filterable:  []string{"name", "verified_at", "created_at"},
+	Sortable:    []string{"id", "name", "created_at"},
+	Selectable:  []string{"id", "name", "email", "verified_at", "created_at", "updated_at"},
+	Includable:  map[string]string{},
+	DefaultSort: "-created_at",
+}
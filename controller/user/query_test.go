@@ -0,0 +1,34 @@
+package user
+
+import (
+	"GoAPIfy/core/query"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserQueryOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		allowed bool
+	}{
+		{"filter[name]=ann&sort=-created_at&fields=id,name,email", true},
+		{"filter[verified_at][null]=false&sort=id", true},
+		{"filter[email]=ann@example.com", false},
+		{"filter[email][like]=example.com", false},
+		{"sort=email", false},
+		{"filter[password]=x", false},
+		{"fields=password", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/v1/admin/users?"+tt.query, nil)
+			if _, err := query.Parse(c, UserQueryOptions); (err == nil) != tt.allowed {
+				t.Errorf("allowed = %v, want %v (%v)", err == nil, tt.allowed, err)
+			}
+		})
+	}
+}
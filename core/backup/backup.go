@@ -0,0 +1,213 @@
+// Package backup makes logical snapshots of model tables that do not depend on the database driver, to move data
+// between databases of different drivers, such as from a MySQL production database to a SQLite development database.
+// A snapshot is a directory holding a gzip compressed NDJSON file per table, one JSON object per row, and a
+// manifest.json file describing the tables, their columns and the order they are restored in, so that every table
+// is restored after the tables its foreign keys reference.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FormatVersion is the version of the snapshot format written by Export, Import refuses snapshots of other versions.
+const FormatVersion = 1
+
+// ManifestFile is the name of the manifest file of a snapshot.
+const ManifestFile = "manifest.json"
+
+// Column types of the manifest, the values of every type are stored in the form JSON has for them:
+// booleans, numbers, strings, RFC 3339 timestamps for times and base64 strings for bytes.
+const (
+	Bool   = "bool"
+	Int    = "int"
+	Uint   = "uint"
+	Float  = "float"
+	String = "string"
+	Time   = "time"
+	Bytes  = "bytes"
+)
+
+// Manifest describes a snapshot. Tables are listed in the order they are restored in.
+type Manifest struct {
+	Version   int       `json:"version"`
+	Driver    string    `json:"driver"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []Table   `json:"tables"`
+}
+
+// Table describes a table of a snapshot and the file holding its rows.
+// DependsOn lists the tables of the snapshot referenced by its foreign keys, and Anonymised the columns whose
+// values were replaced on export.
+type Table struct {
+	Name       string   `json:"name"`
+	File       string   `json:"file"`
+	Rows       int64    `json:"rows"`
+	Columns    []Column `json:"columns"`
+	DependsOn  []string `json:"depends_on,omitempty"`
+	Anonymised []string `json:"anonymised,omitempty"`
+}
+
+// Column describes a column of a table, Type is one of the column types of the manifest, such as Int or Time.
+type Column struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	PrimaryKey    bool   `json:"primary_key,omitempty"`
+	AutoIncrement bool   `json:"auto_increment,omitempty"`
+}
+
+// source is a table to export, with the anonymiser of each of its anonymised columns.
+type source struct {
+	Table
+	anonymise map[string]string
+}
+
+// collect returns the tables of the given models and of their many-to-many join tables, ordered so that every table
+// comes after the tables it depends on. Tables depending on each other keep the order of the models.
+func collect(db *gorm.DB, models []interface{}) ([]*source, error) {
+	var sources []*source
+	byName := map[string]*source{}
+	dependencies := map[string][]string{}
+	depend := func(table string, on string) {
+		if table != on {
+			dependencies[table] = append(dependencies[table], on)
+		}
+	}
+	add := func(s *schema.Schema) error {
+		if _, ok := byName[s.Table]; ok {
+			return nil
+		}
+		src, err := newSource(s)
+		if err != nil {
+			return err
+		}
+		byName[s.Table] = src
+		sources = append(sources, src)
+		return nil
+	}
+
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, fmt.Errorf("cannot read the schema of %T: %w", m, err)
+		}
+		if err := add(stmt.Schema); err != nil {
+			return nil, err
+		}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			switch {
+			case rel.JoinTable != nil:
+				if err := add(rel.JoinTable); err != nil {
+					return nil, err
+				}
+				depend(rel.JoinTable.Table, stmt.Schema.Table)
+				depend(rel.JoinTable.Table, rel.FieldSchema.Table)
+			case rel.Polymorphic != nil:
+				// The foreign key of a polymorphic relation references several tables and none of them in the database.
+			case rel.Type == schema.BelongsTo:
+				depend(stmt.Schema.Table, rel.FieldSchema.Table)
+			case rel.Type == schema.HasOne || rel.Type == schema.HasMany:
+				depend(rel.FieldSchema.Table, stmt.Schema.Table)
+			}
+		}
+	}
+
+	// Order the tables depth first, the dependencies outside of the snapshot are left out.
+	ordered := make([]*source, 0, len(sources))
+	state := map[string]int{}
+	var visit func(src *source)
+	visit = func(src *source) {
+		if state[src.Name] != 0 {
+			return
+		}
+		state[src.Name] = 1
+		for _, name := range dependencies[src.Name] {
+			if dep, ok := byName[name]; ok {
+				if !contains(src.DependsOn, name) {
+					src.DependsOn = append(src.DependsOn, name)
+				}
+				visit(dep)
+			}
+		}
+		ordered = append(ordered, src)
+	}
+	for _, src := range sources {
+		visit(src)
+	}
+	return ordered, nil
+}
+
+// newSource describes the table of the given schema and validates the anonymise tags of its fields.
+func newSource(s *schema.Schema) (*source, error) {
+	src := &source{Table: Table{Name: s.Table, File: s.Table + ".ndjson.gz"}, anonymise: map[string]string{}}
+	for _, name := range s.DBNames {
+		field := s.FieldsByDBName[name]
+		if field.IgnoreMigration {
+			continue
+		}
+		column := Column{Name: name, Type: columnType(field), PrimaryKey: field.PrimaryKey, AutoIncrement: field.AutoIncrement}
+		src.Columns = append(src.Columns, column)
+
+		kind, ok := field.Tag.Lookup("anonymise")
+		if !ok {
+			continue
+		}
+		if _, known := anonymisers[kind]; !known {
+			return nil, fmt.Errorf("unknown anonymiser %q on %s.%s, use %s", kind, s.Table, name, strings.Join(anonymiserNames(), ", "))
+		}
+		if kind != "null" && column.Type != String {
+			return nil, fmt.Errorf("the %s anonymiser of %s.%s needs a string column, use null", kind, s.Table, name)
+		}
+		src.anonymise[name] = kind
+	}
+	return src, nil
+}
+
+// columnType returns the manifest type of a field, from its Go type rather than its column type, which differs
+// between drivers. Serialized fields are stored as strings, or as bytes for the gob serializer.
+func columnType(field *schema.Field) string {
+	if field.Serializer != nil {
+		if strings.EqualFold(field.TagSettings["SERIALIZER"], "gob") {
+			return Bytes
+		}
+		return String
+	}
+	switch field.GORMDataType {
+	case schema.Bool, schema.Int, schema.Uint, schema.Float, schema.Time, schema.Bytes:
+		return string(field.GORMDataType)
+	}
+	return String
+}
+
+// ReadManifest reads the manifest of the snapshot in the given directory.
+func ReadManifest(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the snapshot manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+	}
+	if manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, this version of the application reads version %d", manifest.Version, FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// contains reports whether the list holds the given value.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+package backup
+
+import (
+	"GoAPIfy/internal/testdb"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bkAuthor has every column type and anonymiser, bkPost belongs to a bkAuthor and bkAuthor has many bkTag.
+type bkAuthor struct {
+	ID        uint
+	Name      string  `anonymise:"name"`
+	Email     string  `anonymise:"email"`
+	Token     string  `anonymise:"hash"`
+	Changes   string  `anonymise:"keys"`
+	Phone     *string `anonymise:"null"`
+	Active    bool
+	Score     float64
+	Level     int
+	Avatar    []byte
+	BornAt    *time.Time
+	Settings  map[string]string `gorm:"serializer:json"`
+	DeletedAt gorm.DeletedAt
+	Tags      []bkTag `gorm:"many2many:bk_author_tags"`
+}
+
+type bkPost struct {
+	ID       uint
+	AuthorID uint
+	Author   bkAuthor
+	Title    string
+}
+
+type bkTag struct {
+	ID   uint
+	Name string
+}
+
+// bkModels are the models of the snapshots, the posts first to check the tables are reordered.
+var bkModels = []interface{}{&bkPost{}, &bkAuthor{}, &bkTag{}}
+
+// seedAuthors stores ann, with every column set, two tags and a post, and bob, with the optional columns NULL,
+// soft deleted.
+func seedAuthors(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	phone := "+31 20 123 4567"
+	bornAt := time.Date(1990, 5, 17, 8, 30, 0, 0, time.UTC)
+	ann := bkAuthor{
+		Name: "Ann Smith", Email: "ann@example.org", Token: "secret", Changes: `{"email":"old@example.org","name":"Ann"}`,
+		Phone: &phone, Active: true, Score: 4.5, Level: -2, Avatar: []byte{0, 1, 2}, BornAt: &bornAt,
+		Settings: map[string]string{"theme": "dark"}, Tags: []bkTag{{Name: "go"}, {Name: "sql"}},
+	}
+	bob := bkAuthor{Name: "Bob", Email: "bob@example.org"}
+	for _, author := range []*bkAuthor{&ann, &bob} {
+		if err := db.Create(author).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&bkPost{AuthorID: ann.ID, Title: "Hello"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&bob).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// storedAuthors returns every author of the database, soft deleted ones included, with their tags.
+func storedAuthors(t *testing.T, db *gorm.DB) []bkAuthor {
+	t.Helper()
+	var authors []bkAuthor
+	if err := db.Unscoped().Preload("Tags").Order("id").Find(&authors).Error; err != nil {
+		t.Fatal(err)
+	}
+	return authors
+}
+
+func TestCollect(t *testing.T) {
+	sources, err := collect(testdb.Open(t, bkModels...), bkModels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for _, src := range sources {
+		tables = append(tables, fmt.Sprintf("%s%v", src.Name, src.DependsOn))
+	}
+	if got, want := strings.Join(tables, " "), "bk_authors[] bk_posts[bk_authors] bk_tags[] bk_author_tags[bk_authors bk_tags]"; got != want {
+		t.Errorf("tables = %q, want %q", got, want)
+	}
+
+	var columns []string
+	for _, column := range sources[0].Columns {
+		columns = append(columns, column.Name+" "+column.Type)
+	}
+	want := "id uint,name string,email string,token string,changes string,phone string,active bool,score float,level int," +
+		"avatar bytes,born_at time,settings string,deleted_at time"
+	if got := strings.Join(columns, ","); got != want {
+		t.Errorf("columns of bk_authors = %q, want %q", got, want)
+	}
+	if id := sources[0].Columns[0]; !id.PrimaryKey || !id.AutoIncrement {
+		t.Errorf("id column = %+v, want an auto-increment primary key", id)
+	}
+}
+
+func TestAnonymiseTags(t *testing.T) {
+	type unknownAnonymiser struct {
+		ID   uint
+		Name string `anonymise:"scramble"`
+	}
+	type hashedNumber struct {
+		ID    uint
+		Score int `anonymise:"hash"`
+	}
+	type nulledNumber struct {
+		ID    uint
+		Score *int `anonymise:"null"`
+	}
+	tests := []struct {
+		model   interface{}
+		wantErr string
+	}{
+		{&unknownAnonymiser{}, `unknown anonymiser "scramble" on unknown_anonymisers.name, use email, hash, keys, name, null, phone`},
+		{&hashedNumber{}, "the hash anonymiser of hashed_numbers.score needs a string column, use null"},
+		{&nulledNumber{}, ""},
+	}
+	db := testdb.Open(t, bkModels...)
+	for _, tt := range tests {
+		_, err := collect(db, []interface{}{tt.model})
+		if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+			t.Errorf("collect(%T) = %v, want %q", tt.model, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	source := testdb.Open(t, bkModels...)
+	seedAuthors(t, source)
+	dir := t.TempDir()
+	exported, err := Export(source, bkModels, dir, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []string
+	for _, table := range exported.Tables {
+		rows = append(rows, fmt.Sprintf("%s %d", table.Name, table.Rows))
+		if len(table.Anonymised) > 0 {
+			t.Errorf("%s has the anonymised columns %v without the anonymise option", table.Name, table.Anonymised)
+		}
+	}
+	if got, want := strings.Join(rows, ","), "bk_authors 2,bk_posts 1,bk_tags 2,bk_author_tags 2"; got != want {
+		t.Errorf("exported rows %q, want %q", got, want)
+	}
+	read, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Driver != "sqlite" || !reflect.DeepEqual(read.Tables, exported.Tables) {
+		t.Errorf("manifest = %+v, want the one returned by Export", read)
+	}
+
+	tests := []struct {
+		name    string
+		options ImportOptions
+		// prepare changes the target database or the snapshot before the import.
+		prepare func(t *testing.T, db *gorm.DB, dir string)
+		wantErr string
+	}{
+		{"empty database", ImportOptions{}, nil, ""},
+		{"batches of one row", ImportOptions{BatchSize: 1}, nil, ""},
+		{"truncated database", ImportOptions{Truncate: true}, func(t *testing.T, db *gorm.DB, dir string) {
+			seedAuthors(t, db)
+			if err := db.Create(&bkTag{Name: "extra"}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}, ""},
+		{"database that is not empty", ImportOptions{}, func(t *testing.T, db *gorm.DB, dir string) {
+			if err := db.Create(&bkTag{Name: "extra"}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}, "table bk_tags is not empty, import with the truncate option to replace its rows"},
+		{"missing table", ImportOptions{}, func(t *testing.T, db *gorm.DB, dir string) {
+			if err := db.Migrator().DropTable("bk_author_tags"); err != nil {
+				t.Fatal(err)
+			}
+		}, "table bk_author_tags does not exist, run the migrations first"},
+		{"missing column", ImportOptions{}, func(t *testing.T, db *gorm.DB, dir string) {
+			if err := db.Migrator().DropColumn(&bkPost{}, "title"); err != nil {
+				t.Fatal(err)
+			}
+		}, "column bk_posts.title does not exist, run the migrations first"},
+		{"rows missing from the snapshot", ImportOptions{}, func(t *testing.T, db *gorm.DB, dir string) {
+			editManifest(t, dir, func(m *Manifest) { m.Tables[2].Rows = 3 })
+		}, "cannot import bk_tags: the snapshot holds 2 rows instead of the 3 of the manifest"},
+		{"snapshot of another version", ImportOptions{}, func(t *testing.T, db *gorm.DB, dir string) {
+			editManifest(t, dir, func(m *Manifest) { m.Version = FormatVersion + 1 })
+		}, "unsupported snapshot version 2, this version of the application reads version 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := testdb.Open(t, bkModels...)
+			snapshot := copySnapshot(t, dir)
+			if tt.prepare != nil {
+				tt.prepare(t, target, snapshot)
+			}
+			_, err := Import(target, snapshot, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				// A failed import leaves the database as it was.
+				if len(storedAuthors(t, target)) != 0 {
+					t.Error("a failed import stored authors")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := storedAuthors(t, target), storedAuthors(t, source); !reflect.DeepEqual(got, want) {
+				t.Errorf("restored authors\n%+v\nwant\n%+v", got, want)
+			}
+			var posts []bkPost
+			if err := target.Order("id").Find(&posts).Error; err != nil || len(posts) != 1 || posts[0].Title != "Hello" || posts[0].AuthorID != 1 {
+				t.Errorf("restored posts %+v, %v", posts, err)
+			}
+			// The restored keys are taken, new records get the next ones.
+			tag := bkTag{Name: "new"}
+			if err := target.Create(&tag).Error; err != nil || tag.ID != 3 {
+				t.Errorf("new tag ID = %d, %v, want 3", tag.ID, err)
+			}
+		})
+	}
+}
+
+// copySnapshot copies the snapshot in dir to a new directory, so a test can change it.
+func copySnapshot(t *testing.T, dir string) string {
+	t.Helper()
+	copied := t.TempDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(copied, entry.Name()), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return copied
+}
+
+// editManifest rewrites the manifest of the snapshot in dir with the given change.
+func editManifest(t *testing.T, dir string, change func(m *Manifest)) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	change(&manifest)
+	if content, err = json.Marshal(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnonymisedExport(t *testing.T) {
+	source := testdb.Open(t, bkModels...)
+	seedAuthors(t, source)
+	// A third author shares the email and token of ann, with changes that are not JSON.
+	if err := source.Create(&bkAuthor{Name: "Ann Smith", Email: "ann@example.org", Token: "secret", Changes: "not json"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	manifest, err := Export(source, bkModels, dir, ExportOptions{Anonymise: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := manifest.Tables[0].Anonymised, []string{"name", "email", "token", "changes", "phone"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("anonymised columns of bk_authors = %v, want %v", got, want)
+	}
+	target := testdb.Open(t, bkModels...)
+	if _, err := Import(target, dir, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	original, restored := storedAuthors(t, source), storedAuthors(t, target)
+	ann, bob, third := restored[0], restored[1], restored[2]
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"names are replaced", ann.Name != original[0].Name && ann.Name != "" && bob.Name != original[1].Name},
+		{"emails are replaced with addresses at example.com", strings.HasSuffix(ann.Email, "@example.com") && len(ann.Email) == 16+len("@example.com")},
+		{"equal emails stay equal", ann.Email == third.Email},
+		{"different emails stay different", ann.Email != bob.Email},
+		{"hashes replace the value", len(ann.Token) == 16 && ann.Token != "secret"},
+		{"equal hashes stay equal", ann.Token == third.Token},
+		{"empty values are kept", bob.Token == "" && bob.Changes == ""},
+		{"the keys of JSON objects are kept", ann.Changes == `{"email":null,"name":null}`},
+		{"values that are not JSON objects are emptied", third.Changes == "{}"},
+		{"null columns are emptied", ann.Phone == nil},
+		{"other columns are kept", ann.Score == 4.5 && ann.Level == -2 && ann.Active && ann.Settings["theme"] == "dark" && len(ann.Tags) == 2},
+	}
+	for _, tt := range tests {
+		if !tt.ok {
+			t.Errorf("%s: restored ann %+v, bob %+v and the third author %+v", tt.name, ann, bob, third)
+		}
+	}
+
+	// The hashes depend on the random key of the export, so they cannot be matched against guessed values.
+	if anonymousHash([]byte("key"), "secret") == anonymousHash([]byte("other key"), "secret") {
+		t.Error("the hashes of two keys are equal")
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		column  string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{Bool, true, true, false},
+		{Bool, "true", nil, true},
+		{Int, json.Number("-12"), int64(-12), false},
+		{Int, json.Number("1.5"), nil, true},
+		{Uint, json.Number("18446744073709551615"), uint64(18446744073709551615), false},
+		{Uint, json.Number("-1"), nil, true},
+		{Float, json.Number("4.5"), 4.5, false},
+		{Time, "2026-10-18T12:00:00.5Z", time.Date(2026, 10, 18, 12, 0, 0, 500000000, time.UTC), false},
+		{Time, "yesterday", nil, true},
+		{Bytes, "AAEC", []byte{0, 1, 2}, false},
+		{Bytes, "not base64!", nil, true},
+		{String, "ann", "ann", false},
+		{String, json.Number("1"), nil, true},
+		{String, nil, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := decodeValue(Column{Name: "value", Type: tt.column}, tt.value)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("decodeValue(%s, %#v) = %#v, %v, want %#v", tt.column, tt.value, got, err, tt.want)
+		}
+	}
+}
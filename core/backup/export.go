@@ -0,0 +1,258 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExportOptions changes what Export writes.
+// Anonymise replaces the values of the columns tagged `anonymise:"<anonymiser>"` in the model, such as the emails and
+// names of users, see the anonymisers below. Empty values and NULL are left as they are.
+type ExportOptions struct {
+	Anonymise bool
+}
+
+// anonymisers replace the value of an anonymised column, key is a random key of the export:
+//   - hash replaces the value with a keyed hash, so equal values stay equal and unique values unique
+//   - email replaces the value with an address at example.com made of the hash of the value
+//   - name and phone replace the value with a fake name or phone number
+//   - null replaces the value with NULL
+//   - keys keeps the keys of a JSON object and replaces its values with null, such as the changed columns of an
+//     audit; any other JSON value is replaced with an empty object
+var anonymisers = map[string]func(key []byte, value interface{}) interface{}{
+	"hash": func(key []byte, value interface{}) interface{} {
+		return anonymousHash(key, value)
+	},
+	"email": func(key []byte, value interface{}) interface{} {
+		return anonymousHash(key, value) + "@example.com"
+	},
+	"name": func(key []byte, value interface{}) interface{} {
+		return faker.Name()
+	},
+	"phone": func(key []byte, value interface{}) interface{} {
+		return faker.Phonenumber()
+	},
+	"null": func(key []byte, value interface{}) interface{} {
+		return nil
+	},
+	"keys": func(key []byte, value interface{}) interface{} {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(fmt.Sprint(value)), &object); err != nil || object == nil {
+			return "{}"
+		}
+		for name := range object {
+			object[name] = json.RawMessage("null")
+		}
+		content, _ := json.Marshal(object)
+		return string(content)
+	},
+}
+
+// anonymiserNames returns the names of the anonymisers, sorted.
+func anonymiserNames() []string {
+	names := make([]string, 0, len(anonymisers))
+	for name := range anonymisers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// anonymousHash returns the first 16 hexadecimal characters of the HMAC-SHA256 of the value, short enough for
+// small columns. The key is never written, so the hashes cannot be matched against guessed values.
+func anonymousHash(key []byte, value interface{}) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprint(mac, value)
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Export writes a snapshot of the tables of the given models, and of their many-to-many join tables, to the given
+// directory, which is created when it does not exist. Every table is read in order of its primary key inside a single
+// read transaction, so the snapshot is consistent on the drivers that support repeatable reads, and streamed to its
+// file without holding the rows in memory. Every row is exported, including the soft deleted ones, and encrypted
+// columns are exported encrypted: restore them with the same APP_KEY.
+//
+// Example usage:
+//
+//	manifest, err := backup.Export(db, model.Registered(database.DefaultConnection), "backups/20261018120000", backup.ExportOptions{Anonymise: true})
+func Export(db *gorm.DB, models []interface{}, dir string, options ExportOptions) (*Manifest, error) {
+	sources, err := collect(db, models)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{Version: FormatVersion, Driver: db.Dialector.Name(), CreatedAt: time.Now().UTC()}
+	err = db.Session(&gorm.Session{NewDB: true}).Transaction(func(tx *gorm.DB) error {
+		for _, src := range sources {
+			if !options.Anonymise {
+				src.anonymise = nil
+			}
+			if err := exportTable(tx, dir, src, key); err != nil {
+				return fmt.Errorf("cannot export %s: %w", src.Name, err)
+			}
+			manifest.Tables = append(manifest.Tables, src.Table)
+		}
+		return nil
+	}, snapshotOptions(db))
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), content, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// snapshotOptions returns the options of the read transaction of Export. SQL Server has no read only transactions
+// and SQLite ignores the options, its transactions always read a consistent snapshot.
+func snapshotOptions(db *gorm.DB) *sql.TxOptions {
+	if db.Dialector.Name() == "sqlserver" {
+		return &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	}
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+// exportTable streams the rows of a table to its file and counts them.
+func exportTable(tx *gorm.DB, dir string, src *source, key []byte) (err error) {
+	file, err := os.Create(filepath.Join(dir, src.File))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	compressed := gzip.NewWriter(file)
+	writer := bufio.NewWriter(compressed)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	columns := make([]clause.Column, len(src.Columns))
+	var order []clause.OrderByColumn
+	for i, column := range src.Columns {
+		columns[i] = clause.Column{Name: column.Name}
+		if column.PrimaryKey {
+			order = append(order, clause.OrderByColumn{Column: columns[i]})
+		}
+	}
+	query := tx.Table(src.Name).Clauses(clause.Select{Columns: columns})
+	if len(order) > 0 {
+		query = query.Clauses(clause.OrderBy{Columns: order})
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for _, column := range src.Columns {
+		if _, ok := src.anonymise[column.Name]; ok {
+			src.Anonymised = append(src.Anonymised, column.Name)
+		}
+	}
+	values := make([]interface{}, len(src.Columns))
+	for i, column := range src.Columns {
+		values[i] = scanTarget(column.Type)
+	}
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+		record := make(map[string]interface{}, len(src.Columns))
+		for i, column := range src.Columns {
+			value := scannedValue(values[i])
+			if kind, ok := src.anonymise[column.Name]; ok && value != nil && value != "" {
+				value = anonymisers[kind](key, value)
+			}
+			record[column.Name] = value
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		src.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// scanTarget returns the value a column of the given manifest type is scanned into.
+func scanTarget(columnType string) interface{} {
+	switch columnType {
+	case Bool:
+		return &sql.NullBool{}
+	case Int, Uint:
+		return &sql.NullInt64{}
+	case Float:
+		return &sql.NullFloat64{}
+	case Time:
+		return &sql.NullTime{}
+	case Bytes:
+		return &[]byte{}
+	}
+	return &sql.NullString{}
+}
+
+// scannedValue returns the value scanned by scanTarget, or nil for NULL.
+func scannedValue(target interface{}) interface{} {
+	switch v := target.(type) {
+	case *sql.NullBool:
+		if v.Valid {
+			return v.Bool
+		}
+	case *sql.NullInt64:
+		if v.Valid {
+			return v.Int64
+		}
+	case *sql.NullFloat64:
+		if v.Valid {
+			return v.Float64
+		}
+	case *sql.NullTime:
+		if v.Valid {
+			return v.Time
+		}
+	case *[]byte:
+		if *v != nil {
+			return append([]byte{}, *v...)
+		}
+	case *sql.NullString:
+		if v.Valid {
+			return v.String
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportOptions changes how Import restores a snapshot.
+// BatchSize is the number of rows inserted by each statement, 500 when zero.
+// Truncate deletes the rows of the tables of the snapshot before restoring them, without it Import refuses to
+// restore a table that is not empty.
+type ImportOptions struct {
+	BatchSize int
+	Truncate  bool
+}
+
+// Import restores the snapshot in the given directory into the database, whatever the driver it was exported from.
+// The tables must exist with every column of the snapshot, run the migrations first. The tables are restored in the
+// order of the manifest, after the tables their foreign keys reference, and emptied in the reverse order with Truncate.
+// The whole import runs in a single transaction, so a failure leaves the database as it was. Rows are inserted as
+// they are, without model hooks, observers or audits, and keep their primary keys; the auto-increment sequences of
+// PostgreSQL are moved past the restored keys.
+//
+// Example usage:
+//
+//	manifest, err := backup.Import(db, "backups/20261018120000", backup.ImportOptions{Truncate: true})
+func Import(db *gorm.DB, dir string, options ImportOptions) (*Manifest, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 500
+	}
+
+	err = db.Session(&gorm.Session{NewDB: true}).Transaction(func(tx *gorm.DB) error {
+		for _, table := range manifest.Tables {
+			if err := checkTable(tx, table); err != nil {
+				return err
+			}
+		}
+
+		if options.Truncate {
+			for i := len(manifest.Tables) - 1; i >= 0; i-- {
+				if err := tx.Exec("DELETE FROM ?", clause.Table{Name: manifest.Tables[i].Name}).Error; err != nil {
+					return fmt.Errorf("cannot empty %s: %w", manifest.Tables[i].Name, err)
+				}
+			}
+		} else {
+			for _, table := range manifest.Tables {
+				var count int64
+				if err := tx.Table(table.Name).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					return fmt.Errorf("table %s is not empty, import with the truncate option to replace its rows", table.Name)
+				}
+			}
+		}
+
+		for _, table := range manifest.Tables {
+			if err := importTable(tx, dir, table, options.BatchSize); err != nil {
+				return fmt.Errorf("cannot import %s: %w", table.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// checkTable makes sure the table of the snapshot and every column of it exist in the database.
+func checkTable(tx *gorm.DB, table Table) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(table.Name) {
+		return fmt.Errorf("table %s does not exist, run the migrations first", table.Name)
+	}
+	columnTypes, err := migrator.ColumnTypes(table.Name)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, columnType := range columnTypes {
+		existing[strings.ToLower(columnType.Name())] = true
+	}
+	for _, column := range table.Columns {
+		if !existing[strings.ToLower(column.Name)] {
+			return fmt.Errorf("column %s.%s does not exist, run the migrations first", table.Name, column.Name)
+		}
+	}
+	return nil
+}
+
+// importTable inserts the rows of a table from its file in batches and checks their number against the manifest.
+func importTable(tx *gorm.DB, dir string, table Table, batchSize int) error {
+	file, err := os.Open(filepath.Join(dir, table.File))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(compressed)
+	decoder.UseNumber()
+
+	// SQL Server refuses explicit values for an identity column unless they are enabled for the table.
+	identity := false
+	for _, column := range table.Columns {
+		identity = identity || column.AutoIncrement
+	}
+	if identity && tx.Dialector.Name() == "sqlserver" {
+		if err := tx.Exec("SET IDENTITY_INSERT ? ON", clause.Table{Name: table.Name}).Error; err != nil {
+			return err
+		}
+		defer tx.Exec("SET IDENTITY_INSERT ? OFF", clause.Table{Name: table.Name})
+	}
+
+	var rows int64
+	batch := make([]map[string]interface{}, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Table(table.Name).Create(&batch).Error; err != nil {
+			return err
+		}
+		rows += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid row %d: %w", rows+int64(len(batch))+1, err)
+		}
+		record := make(map[string]interface{}, len(table.Columns))
+		for _, column := range table.Columns {
+			value, err := decodeValue(column, raw[column.Name])
+			if err != nil {
+				return fmt.Errorf("invalid row %d: %w", rows+int64(len(batch))+1, err)
+			}
+			record[column.Name] = value
+		}
+		batch = append(batch, record)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if rows != table.Rows {
+		return fmt.Errorf("the snapshot holds %d rows instead of the %d of the manifest", rows, table.Rows)
+	}
+
+	if tx.Dialector.Name() == "postgres" {
+		for _, column := range table.Columns {
+			if !column.AutoIncrement {
+				continue
+			}
+			err := tx.Exec("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(?), 0) + 1, false) FROM ?",
+				table.Name, column.Name, clause.Column{Name: column.Name}, clause.Table{Name: table.Name}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeValue converts a value of a snapshot row, as decoded from JSON, to the Go value of its column type.
+func decodeValue(column Column, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("invalid %s value %v for %s", column.Type, value, column.Name)
+	switch column.Type {
+	case Bool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case Int:
+		if n, ok := value.(json.Number); ok {
+			return n.Int64()
+		}
+	case Uint:
+		if n, ok := value.(json.Number); ok {
+			return strconv.ParseUint(n.String(), 10, 64)
+		}
+	case Float:
+		if n, ok := value.(json.Number); ok {
+			return n.Float64()
+		}
+	case Time:
+		if s, ok := value.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	case Bytes:
+		if s, ok := value.(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+	default:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, invalid
+}
@@ -0,0 +1,114 @@
+// Package cache stores serialized query results in Redis, or in an in-process LRU when Redis is disabled.
+// Entries are grouped by tags. Flushing a tag makes every entry stored under it unreachable at once, and concurrent
+// misses of the same key are collapsed, so only one goroutine refills an expired entry while the others wait for it.
+package cache
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Store is a key/value store with expiring entries.
+type Store interface {
+	// Get returns the value stored under key, and false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for the given duration, or without expiry when ttl is zero.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the given keys.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Cache adds tags and stampede protection on top of a Store.
+type Cache struct {
+	store Store
+	group group
+}
+
+// New creates a Cache backed by the given Redis client, or by an in-process LRU when the client is nil,
+// which is the case when ENABLE_REDIS is false. The LRU holds CACHE_LRU_SIZE entries, 1000 by default.
+func New(client *redis.Client) *Cache {
+	if client != nil {
+		return NewWithStore(NewRedisStore(client))
+	}
+
+	size, err := strconv.Atoi(os.Getenv("CACHE_LRU_SIZE"))
+	if err != nil || size < 1 {
+		size = 1000
+	}
+	return NewWithStore(NewLRUStore(size))
+}
+
+// NewWithStore creates a Cache backed by the given Store.
+func NewWithStore(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Remember returns the value stored under key for the given tags, or calls fill to compute it and stores the result for ttl.
+// Concurrent calls for the same key while it is being filled wait for the first one instead of calling fill again.
+// Errors of the underlying store are not returned: the cache degrades to calling fill, so an unavailable Redis
+// slows requests down instead of failing them. Errors returned by fill are returned as is and nothing is stored.
+func (c *Cache) Remember(ctx context.Context, key string, tags []string, ttl time.Duration, fill func() ([]byte, error)) ([]byte, error) {
+	key = c.versionedKey(ctx, key, tags)
+	if value, ok, err := c.store.Get(ctx, key); err == nil && ok {
+		return value, nil
+	}
+
+	return c.group.do(key, func() ([]byte, error) {
+		// Another goroutine may have filled the key between the miss and acquiring the flight.
+		if value, ok, err := c.store.Get(ctx, key); err == nil && ok {
+			return value, nil
+		}
+		value, err := fill()
+		if err != nil {
+			return nil, err
+		}
+		c.store.Set(ctx, key, value, ttl)
+		return value, nil
+	})
+}
+
+// Forget removes the value stored under key for the given tags.
+func (c *Cache) Forget(ctx context.Context, key string, tags ...string) error {
+	return c.store.Delete(ctx, c.versionedKey(ctx, key, tags))
+}
+
+// Flush invalidates every entry stored under one of the given tags.
+// Each tag gets a new version, so the old entries are never read again and simply expire.
+func (c *Cache) Flush(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		if err := c.store.Set(ctx, tagKey(tag), []byte(uuid.NewString()), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionedKey appends the current version of every tag to the key.
+// A tag without a version gets a new random one rather than a fixed initial value, so a tag version that was
+// evicted from the store can never come back and make entries stored before a flush readable again.
+func (c *Cache) versionedKey(ctx context.Context, key string, tags []string) string {
+	if len(tags) == 0 {
+		return "cache:" + key
+	}
+
+	versions := make([]string, len(tags))
+	for i, tag := range tags {
+		version, ok, err := c.store.Get(ctx, tagKey(tag))
+		if err != nil || !ok {
+			version = []byte(uuid.NewString())
+			c.store.Set(ctx, tagKey(tag), version, 0)
+		}
+		versions[i] = string(version)
+	}
+	return "cache:" + key + "@" + strings.Join(versions, ",")
+}
+
+func tagKey(tag string) string {
+	return "cache-tag:" + tag
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingStore is a Store whose every call fails, like an unreachable Redis.
+type failingStore struct{}
+
+var errStore = errors.New("store unavailable")
+
+func (failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errStore
+}
+func (failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errStore
+}
+func (failingStore) Delete(ctx context.Context, keys ...string) error { return errStore }
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRUStore(10)
+	store.Set(ctx, "kept", []byte("1"), time.Minute)
+	store.Set(ctx, "overwritten", []byte("2"), 0)
+	store.Set(ctx, "overwritten", []byte("3"), 0)
+	store.Set(ctx, "expired", []byte("4"), time.Nanosecond)
+	store.Set(ctx, "deleted", []byte("5"), 0)
+	store.Delete(ctx, "deleted")
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"kept", "1", true},
+		{"overwritten", "3", true},
+		{"expired", "", false},
+		{"deleted", "", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			value, ok, err := store.Get(ctx, tt.key)
+			if err != nil || ok != tt.wantOK || string(value) != tt.want {
+				t.Errorf("Get = %q, %v, %v, want %q, %v", value, ok, err, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	// The least recently used entry is evicted, not the least recently written one.
+	store = NewLRUStore(2)
+	store.Set(ctx, "a", []byte("1"), 0)
+	store.Set(ctx, "b", []byte("2"), 0)
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", []byte("3"), 0)
+	if _, ok, _ := store.Get(ctx, "a"); !ok {
+		t.Error("the recently read entry was evicted")
+	}
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Error("the least recently used entry was kept")
+	}
+}
+
+func TestRemember(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		between   func(c *Cache)
+		tags      []string
+		wantFills int
+	}{
+		{"hit", func(c *Cache) {}, []string{"table:users"}, 1},
+		{"flushed tag", func(c *Cache) { c.Flush(ctx, "table:users") }, []string{"table:users"}, 2},
+		{"one of the tags flushed", func(c *Cache) { c.Flush(ctx, "table:roles") }, []string{"table:users", "table:roles"}, 2},
+		{"other tag flushed", func(c *Cache) { c.Flush(ctx, "table:posts") }, []string{"table:users"}, 1},
+		{"forgotten key", func(c *Cache) { c.Forget(ctx, "users", "table:users") }, []string{"table:users"}, 2},
+		{"without tags", func(c *Cache) { c.Flush(ctx, "table:users") }, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWithStore(NewLRUStore(100))
+			fills := 0
+			fill := func() ([]byte, error) {
+				fills++
+				return []byte("value"), nil
+			}
+			for i := 0; i < 2; i++ {
+				value, err := c.Remember(ctx, "users", tt.tags, time.Minute, fill)
+				if err != nil || string(value) != "value" {
+					t.Fatalf("Remember = %q, %v", value, err)
+				}
+				if i == 0 {
+					tt.between(c)
+				}
+			}
+			if fills != tt.wantFills {
+				t.Errorf("filled %d times, want %d", fills, tt.wantFills)
+			}
+		})
+	}
+}
+
+func TestRememberErrors(t *testing.T) {
+	ctx := context.Background()
+
+	// An error of fill is returned and nothing is stored.
+	c := NewWithStore(NewLRUStore(100))
+	errFill := errors.New("query failed")
+	if _, err := c.Remember(ctx, "k", nil, time.Minute, func() ([]byte, error) { return nil, errFill }); !errors.Is(err, errFill) {
+		t.Errorf("Remember error = %v, want %v", err, errFill)
+	}
+	value, err := c.Remember(ctx, "k", nil, time.Minute, func() ([]byte, error) { return []byte("fresh"), nil })
+	if err != nil || string(value) != "fresh" {
+		t.Errorf("Remember after a failed fill = %q, %v, want the fresh value", value, err)
+	}
+
+	// A failing store degrades to calling fill, and reports the failed flush.
+	c = NewWithStore(failingStore{})
+	value, err = c.Remember(ctx, "k", []string{"t"}, time.Minute, func() ([]byte, error) { return []byte("fresh"), nil })
+	if err != nil || string(value) != "fresh" {
+		t.Errorf("Remember with a failing store = %q, %v, want the fresh value", value, err)
+	}
+	if err := c.Flush(ctx, "t"); !errors.Is(err, errStore) {
+		t.Errorf("Flush with a failing store = %v, want %v", err, errStore)
+	}
+}
+
+func TestRememberCollapsesConcurrentMisses(t *testing.T) {
+	c := NewWithStore(NewLRUStore(100))
+	var fills int32
+	release := make(chan struct{})
+	fill := func() ([]byte, error) {
+		atomic.AddInt32(&fills, 1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := c.Remember(context.Background(), "k", nil, time.Minute, fill); err != nil || string(value) != "value" {
+				t.Errorf("Remember = %q, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if fills != 1 {
+		t.Errorf("filled %d times, want 1", fills)
+	}
+}
@@ -0,0 +1,44 @@
+package cache
+
+import "sync"
+
+// group collapses concurrent calls for the same key into a single call whose result is shared by all callers.
+type group struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a call in progress.
+type flight struct {
+	done  sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// do calls fn for the key unless a call for the same key is already in progress, in which case it waits for that call
+// and returns its result.
+func (g *group) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	if running, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		running.done.Wait()
+		return running.value, running.err
+	}
+	current := &flight{}
+	current.done.Add(1)
+	g.flights[key] = current
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		current.done.Done()
+	}()
+
+	current.value, current.err = fn()
+	return current.value, current.err
+}
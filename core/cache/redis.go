@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStore is a Store backed by Redis. Keys are prefixed with the APP_NAME so several applications can share a server.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a new RedisStore using the given client.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get returns the value stored under key, and false if there is none.
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, prefixed(key)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for the given duration, or without expiry when ttl is zero.
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, prefixed(key), value, ttl).Err()
+}
+
+// Delete removes the given keys.
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = prefixed(key)
+	}
+	return s.client.Del(ctx, names...).Err()
+}
+
+func prefixed(key string) string {
+	return os.Getenv("APP_NAME") + ":" + key
+}
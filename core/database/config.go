@@ -0,0 +1,192 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of a database connection. Every driver reads the same fields,
+// so switching DATABASE_TYPE never requires renaming the other settings.
+type Config struct {
+	// Driver is the name of a registered driver: mysql, mariadb, postgres, sqlite, sqlserver, or one added with RegisterDriver.
+	Driver string
+	// DSN is a complete data source name in the format of the driver. When it is set, the connection fields below are ignored.
+	DSN  string
+	Host string
+	Port string
+	// Name is the database name, or the path of the database file for SQLite.
+	Name     string
+	User     string
+	Password string
+
+	// SSLMode is one of disable, prefer, require, verify-ca and verify-full. require encrypts the connection without
+	// checking the certificate of the server, verify-ca checks it against SSLRootCert, and verify-full also checks the host name.
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+	// Timezone is the time zone of the session, such as UTC or Europe/Paris. Charset is the character set of the connection.
+	Timezone string
+	Charset  string
+
+	// MaxIdle, MaxOpen and MaxLifetime configure the connection pool; zero leaves the default of database/sql.
+	MaxIdle     int
+	MaxOpen     int
+	MaxLifetime time.Duration
+
+	// ConnectRetries is the number of times a failed first connection is retried, waiting ConnectBackoff
+	// before the first retry and twice as long before every next one, up to 30 seconds.
+	ConnectRetries int
+	ConnectBackoff time.Duration
+
+	// LogQueries prints every query, Replicas holds the DSNs of read replicas, see UseReplicas.
+	LogQueries bool
+	Replicas   []string
+}
+
+// ConfigFromEnv reads the configuration of the application database from the environment:
+// DATABASE_TYPE, DATABASE_DSN, DATABASE_HOST, DATABASE_PORT, DATABASE_NAME, DATABASE_USER, DATABASE_PASS,
+// DATABASE_SSL_MODE, DATABASE_SSL_CA, DATABASE_SSL_CERT, DATABASE_SSL_KEY, DATABASE_TIMEZONE, DATABASE_CHARSET,
+// DATABASE_MAX_IDLE, DATABASE_MAX_CONNECTION, DATABASE_MAX_LIFETIME (seconds), DATABASE_CONNECT_RETRIES (5 by default),
+// DATABASE_CONNECT_BACKOFF (milliseconds, 1000 by default) and DATABASE_REPLICAS.
+// The former DB_HOST, DB_PORT, DB_NAME, DB_USER, DB_PASS and DB_PATH variables are still read when the DATABASE_
+// variables are not set. Queries are logged outside of production.
+func ConfigFromEnv(production bool) (Config, error) {
+	return configFromEnv("DATABASE_", production)
+}
+
+// configFromEnv reads a configuration from the environment variables with the given prefix.
+func configFromEnv(prefix string, production bool) (Config, error) {
+	// The former DB_ variables only stand in for the variables of the application database.
+	env := func(name string, legacy ...string) string {
+		value := os.Getenv(prefix + name)
+		if prefix == "DATABASE_" {
+			for _, fallback := range legacy {
+				if value == "" {
+					value = os.Getenv(fallback)
+				}
+			}
+		}
+		return value
+	}
+	number := func(name string, fallback int) (int, error) {
+		value := env(name)
+		if value == "" {
+			return fallback, nil
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, fmt.Errorf("%s%s must be a positive integer, got %q", prefix, name, value)
+		}
+		return parsed, nil
+	}
+
+	cfg := Config{
+		Driver:      env("TYPE"),
+		DSN:         env("DSN"),
+		Host:        env("HOST", "DB_HOST"),
+		Port:        env("PORT", "DB_PORT"),
+		Name:        env("NAME", "DB_NAME", "DB_PATH"),
+		User:        env("USER", "DB_USER"),
+		Password:    env("PASS", "DB_PASS"),
+		SSLMode:     env("SSL_MODE"),
+		SSLRootCert: env("SSL_CA"),
+		SSLCert:     env("SSL_CERT"),
+		SSLKey:      env("SSL_KEY"),
+		Timezone:    env("TIMEZONE"),
+		Charset:     env("CHARSET"),
+		LogQueries:  !production,
+	}
+	for _, dsn := range strings.Split(env("REPLICAS"), ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			cfg.Replicas = append(cfg.Replicas, dsn)
+		}
+	}
+
+	var err error
+	if cfg.MaxIdle, err = number("MAX_IDLE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxOpen, err = number("MAX_CONNECTION", 0); err != nil {
+		return cfg, err
+	}
+	lifetime, err := number("MAX_LIFETIME", 0)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.MaxLifetime = time.Duration(lifetime) * time.Second
+	if cfg.ConnectRetries, err = number("CONNECT_RETRIES", 5); err != nil {
+		return cfg, err
+	}
+	backoff, err := number("CONNECT_BACKOFF", 1000)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ConnectBackoff = time.Duration(backoff) * time.Millisecond
+	return cfg, nil
+}
+
+// sslMode returns the SSL mode of the configuration, or the given default when none is set.
+func (cfg Config) sslMode(fallback string) string {
+	if cfg.SSLMode == "" {
+		return fallback
+	}
+	return cfg.SSLMode
+}
+
+// TLSConfig returns the TLS configuration matching the SSL settings, or nil when SSL is disabled.
+// Drivers that take a *tls.Config instead of DSN options use it.
+func (cfg Config) TLSConfig() (*tls.Config, error) {
+	mode := cfg.sslMode("disable")
+	if mode == "disable" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+	if cfg.SSLCert != "" || cfg.SSLKey != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.SSLCert, cfg.SSLKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if cfg.SSLRootCert != "" {
+		pem, err := os.ReadFile(cfg.SSLRootCert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.SSLRootCert)
+		}
+	}
+
+	switch mode {
+	case "prefer", "require":
+		tlsConfig.InsecureSkipVerify = true
+	case "verify-ca":
+		// Check the certificate chain against the CA, but not the host name.
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("the database server sent no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, certificate := range state.PeerCertificates[1:] {
+				intermediates.AddCert(certificate)
+			}
+			_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{Roots: tlsConfig.RootCAs, Intermediates: intermediates})
+			return err
+		}
+	case "verify-full":
+	default:
+		return nil, fmt.Errorf("unsupported SSL mode %q, use disable, prefer, require, verify-ca or verify-full", mode)
+	}
+	return tlsConfig, nil
+}
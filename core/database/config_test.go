@@ -0,0 +1,358 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+// databaseEnv lists the environment variables read by ConfigFromEnv.
+var databaseEnv = []string{
+	"DATABASE_TYPE", "DATABASE_DSN", "DATABASE_HOST", "DATABASE_PORT", "DATABASE_NAME", "DATABASE_USER", "DATABASE_PASS",
+	"DATABASE_SSL_MODE", "DATABASE_SSL_CA", "DATABASE_SSL_CERT", "DATABASE_SSL_KEY", "DATABASE_TIMEZONE", "DATABASE_CHARSET",
+	"DATABASE_MAX_IDLE", "DATABASE_MAX_CONNECTION", "DATABASE_MAX_LIFETIME", "DATABASE_CONNECT_RETRIES",
+	"DATABASE_CONNECT_BACKOFF", "DATABASE_REPLICAS",
+	"DB_HOST", "DB_PORT", "DB_NAME", "DB_USER", "DB_PASS", "DB_PATH",
+}
+
+// setDatabaseEnv clears the database variables of the environment, then sets the given ones.
+func setDatabaseEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range databaseEnv {
+		t.Setenv(name, "")
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	defaults := Config{ConnectRetries: 5, ConnectBackoff: time.Second}
+	tests := []struct {
+		name       string
+		env        map[string]string
+		production bool
+		want       func(cfg Config) Config
+		wantErr    string
+	}{
+		{
+			name:       "nothing set",
+			production: true,
+			want:       func(cfg Config) Config { return cfg },
+		},
+		{
+			name: "every setting",
+			env: map[string]string{
+				"DATABASE_TYPE": "postgres", "DATABASE_HOST": "db", "DATABASE_PORT": "5432", "DATABASE_NAME": "app",
+				"DATABASE_USER": "api", "DATABASE_PASS": "secret", "DATABASE_SSL_MODE": "verify-full", "DATABASE_SSL_CA": "ca.pem",
+				"DATABASE_SSL_CERT": "client.pem", "DATABASE_SSL_KEY": "client.key", "DATABASE_TIMEZONE": "UTC",
+				"DATABASE_CHARSET": "utf8", "DATABASE_MAX_IDLE": "2", "DATABASE_MAX_CONNECTION": "10",
+				"DATABASE_MAX_LIFETIME": "60", "DATABASE_CONNECT_RETRIES": "0", "DATABASE_CONNECT_BACKOFF": "250",
+				"DATABASE_REPLICAS": " replica-1, ,replica-2 ",
+			},
+			want: func(cfg Config) Config {
+				return Config{
+					Driver: "postgres", Host: "db", Port: "5432", Name: "app", User: "api", Password: "secret",
+					SSLMode: "verify-full", SSLRootCert: "ca.pem", SSLCert: "client.pem", SSLKey: "client.key",
+					Timezone: "UTC", Charset: "utf8", MaxIdle: 2, MaxOpen: 10, MaxLifetime: time.Minute,
+					ConnectRetries: 0, ConnectBackoff: 250 * time.Millisecond, LogQueries: true,
+					Replicas: []string{"replica-1", "replica-2"},
+				}
+			},
+		},
+		{
+			name:       "former variables",
+			env:        map[string]string{"DB_HOST": "old-db", "DB_PORT": "3306", "DB_NAME": "old", "DB_USER": "u", "DB_PASS": "p"},
+			production: true,
+			want: func(cfg Config) Config {
+				cfg.Host, cfg.Port, cfg.Name, cfg.User, cfg.Password = "old-db", "3306", "old", "u", "p"
+				return cfg
+			},
+		},
+		{
+			name:       "former SQLite path",
+			env:        map[string]string{"DATABASE_TYPE": "sqlite", "DB_PATH": "app.db"},
+			production: true,
+			want: func(cfg Config) Config {
+				cfg.Driver, cfg.Name = "sqlite", "app.db"
+				return cfg
+			},
+		},
+		{
+			name:       "current variables win over the former ones",
+			env:        map[string]string{"DATABASE_HOST": "db", "DB_HOST": "old-db", "DATABASE_NAME": "app", "DB_NAME": "old", "DB_PATH": "old.db"},
+			production: true,
+			want: func(cfg Config) Config {
+				cfg.Host, cfg.Name = "db", "app"
+				return cfg
+			},
+		},
+		{
+			name:    "number that is not one",
+			env:     map[string]string{"DATABASE_MAX_CONNECTION": "ten"},
+			wantErr: `DATABASE_MAX_CONNECTION must be a positive integer, got "ten"`,
+		},
+		{
+			name:    "negative number",
+			env:     map[string]string{"DATABASE_CONNECT_BACKOFF": "-1"},
+			wantErr: `DATABASE_CONNECT_BACKOFF must be a positive integer, got "-1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDatabaseEnv(t, tt.env)
+			cfg, err := ConfigFromEnv(tt.production)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ConfigFromEnv error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.want(defaults); !reflect.DeepEqual(cfg, want) {
+				t.Errorf("ConfigFromEnv =\n%+v\nwant\n%+v", cfg, want)
+			}
+		})
+	}
+}
+
+// dsnOf returns the data source name of a dialector of the built-in drivers.
+func dsnOf(dialector gorm.Dialector) string {
+	switch d := dialector.(type) {
+	case *mysql.Dialector:
+		return d.DSN
+	case *postgres.Dialector:
+		return d.DSN
+	case *sqlserver.Dialector:
+		return d.DSN
+	case *sqlite.Dialector:
+		return d.DSN
+	}
+	return ""
+}
+
+func TestDialectors(t *testing.T) {
+	server := Config{Host: "db", Port: "5432", Name: "app", User: "api", Password: "it's secret"}
+	with := func(change func(cfg *Config)) Config {
+		cfg := server
+		change(&cfg)
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		driver  string
+		cfg     Config
+		wantDSN string
+		wantErr bool
+	}{
+		{"postgres", "postgres", server,
+			`host=db port=5432 dbname=app user=api password='it\'s secret' sslmode=prefer`, false},
+		{"postgres options", "postgresql", with(func(cfg *Config) {
+			cfg.SSLMode, cfg.SSLRootCert, cfg.Timezone, cfg.Charset = "verify-full", "/etc/ca.pem", "Europe/Paris", "UTF8"
+		}), `host=db port=5432 dbname=app user=api password='it\'s secret' sslmode=verify-full sslrootcert=/etc/ca.pem TimeZone=Europe/Paris client_encoding=UTF8`, false},
+		{"postgres DSN", "postgres", with(func(cfg *Config) { cfg.DSN = "host=other" }), "host=other", false},
+		{"mysql", "mysql", server,
+			"api:it's secret@tcp(db:5432)/app?loc=Local&parseTime=true&charset=utf8mb4&net_write_timeout=6000", false},
+		{"mariadb options", "mariadb", with(func(cfg *Config) { cfg.Charset, cfg.Timezone = "latin1", "UTC" }),
+			"api:it's secret@tcp(db:5432)/app?parseTime=true&charset=latin1&net_write_timeout=6000&time_zone=%27%2B00%3A00%27", false},
+		{"mysql unknown time zone", "mysql", with(func(cfg *Config) { cfg.Timezone = "Mars/Olympus" }), "", true},
+		{"mysql unknown SSL mode", "mysql", with(func(cfg *Config) { cfg.SSLMode = "always" }), "", true},
+		{"sqlserver", "mssql", server,
+			"sqlserver://api:it%27s%20secret@db:5432?database=app", false},
+		{"sqlserver encrypted", "sqlserver", with(func(cfg *Config) { cfg.SSLMode = "require" }),
+			"sqlserver://api:it%27s%20secret@db:5432?TrustServerCertificate=true&database=app&encrypt=true", false},
+		{"sqlserver verified", "sqlserver", with(func(cfg *Config) { cfg.SSLMode, cfg.SSLRootCert = "verify-full", "ca.pem" }),
+			"sqlserver://api:it%27s%20secret@db:5432?certificate=ca.pem&database=app&encrypt=true", false},
+		{"sqlserver unencrypted", "sqlserver", with(func(cfg *Config) { cfg.SSLMode = "disable" }),
+			"sqlserver://api:it%27s%20secret@db:5432?database=app&encrypt=disable", false},
+		{"sqlite", "sqlite", Config{Name: "app.db"}, "app.db", false},
+		{"sqlite DSN", "SQLite", Config{Name: "app.db", DSN: "file::memory:"}, "file::memory:", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, err := lookupDriver(tt.driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dialector, err := driver.Dialector(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if err == nil && dsnOf(dialector) != tt.wantDSN {
+				t.Errorf("DSN = %q, want %q", dsnOf(dialector), tt.wantDSN)
+			}
+		})
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name             string
+		cfg              Config
+		wantNil          bool
+		wantSkipVerify   bool
+		wantVerifyChain  bool
+		wantErrSubstring string
+	}{
+		{name: "no mode", cfg: Config{}, wantNil: true},
+		{name: "disable", cfg: Config{SSLMode: "disable"}, wantNil: true},
+		{name: "prefer", cfg: Config{SSLMode: "prefer"}, wantSkipVerify: true},
+		{name: "require", cfg: Config{SSLMode: "require"}, wantSkipVerify: true},
+		{name: "verify-ca", cfg: Config{SSLMode: "verify-ca"}, wantSkipVerify: true, wantVerifyChain: true},
+		{name: "verify-full", cfg: Config{SSLMode: "verify-full", Host: "db"}},
+		{name: "unknown mode", cfg: Config{SSLMode: "always"}, wantErrSubstring: `unsupported SSL mode "always"`},
+		{name: "missing CA", cfg: Config{SSLMode: "verify-full", SSLRootCert: filepath.Join(t.TempDir(), "missing.pem")}, wantErrSubstring: "cannot read the CA certificate"},
+		{name: "invalid CA", cfg: Config{SSLMode: "verify-full", SSLRootCert: notPEM}, wantErrSubstring: "no certificate found"},
+		{name: "missing client certificate", cfg: Config{SSLMode: "require", SSLCert: "missing.pem", SSLKey: "missing.key"}, wantErrSubstring: "cannot load the client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := tt.cfg.TLSConfig()
+			if tt.wantErrSubstring != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstring) {
+					t.Errorf("TLSConfig error = %v, want %q", err, tt.wantErrSubstring)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (tlsConfig == nil) != tt.wantNil {
+				t.Fatalf("TLSConfig = %v, want nil: %v", tlsConfig, tt.wantNil)
+			}
+			if tlsConfig == nil {
+				return
+			}
+			if tlsConfig.InsecureSkipVerify != tt.wantSkipVerify || (tlsConfig.VerifyConnection != nil) != tt.wantVerifyChain {
+				t.Errorf("TLSConfig skips verification: %v, verifies the chain: %v", tlsConfig.InsecureSkipVerify, tlsConfig.VerifyConnection != nil)
+			}
+			if tlsConfig.ServerName != tt.cfg.Host {
+				t.Errorf("server name = %q, want %q", tlsConfig.ServerName, tt.cfg.Host)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	database := filepath.Join(dir, "app.db")
+	unreachable := filepath.Join(dir, "missing", "app.db")
+
+	// The flaky driver fails until its attempts run out, by opening a file in a directory that does not exist.
+	var attempts, setups int
+	failures := 0
+	RegisterDriver(Driver{
+		Dialector: func(cfg Config) (gorm.Dialector, error) {
+			attempts++
+			if attempts <= failures {
+				return sqlite.Open(unreachable), nil
+			}
+			return sqlite.Open(cfg.Name), nil
+		},
+		Setup: func(db *gorm.DB) error {
+			setups++
+			return nil
+		},
+	}, "test-flaky")
+	RegisterDriver(Driver{Dialector: func(cfg Config) (gorm.Dialector, error) {
+		return nil, errors.New("invalid configuration")
+	}}, "test-invalid")
+
+	tests := []struct {
+		name         string
+		cfg          Config
+		failures     int
+		wantAttempts int
+		wantErr      string
+	}{
+		{name: "first attempt", cfg: Config{Driver: "test-flaky", Name: database, ConnectRetries: 2}, wantAttempts: 1},
+		{name: "after retries", cfg: Config{Driver: "TEST-FLAKY", Name: database, ConnectRetries: 2, ConnectBackoff: time.Millisecond}, failures: 2, wantAttempts: 3},
+		{name: "retries run out", cfg: Config{Driver: "test-flaky", Name: database, ConnectRetries: 2, ConnectBackoff: time.Millisecond}, failures: 3, wantAttempts: 3,
+			wantErr: "cannot connect to the database after 3 attempt(s)"},
+		{name: "without retries", cfg: Config{Driver: "test-flaky", Name: database}, failures: 1, wantAttempts: 1,
+			wantErr: "cannot connect to the database after 1 attempt(s)"},
+		{name: "invalid configuration is not retried", cfg: Config{Driver: "test-invalid", ConnectRetries: 2}, wantErr: "invalid configuration"},
+		{name: "unknown driver", cfg: Config{Driver: "clickhouse"}, wantErr: `database driver "clickhouse" is not supported, use one of `},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, setups, failures = 0, 0, tt.failures
+			var db *gorm.DB
+			var err error
+			captureStdout(t, func() { db, err = Open(tt.cfg) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Open error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if wantSetups := map[bool]int{true: 1}[db != nil]; setups != wantSetups {
+				t.Errorf("%d setups, want %d", setups, wantSetups)
+			}
+		})
+	}
+}
+
+func TestOpenPool(t *testing.T) {
+	var db *gorm.DB
+	var err error
+	captureStdout(t, func() {
+		db, err = Open(Config{Driver: "sqlite", Name: filepath.Join(t.TempDir(), "app.db"), MaxIdle: 1, MaxOpen: 3, MaxLifetime: time.Minute})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open := pool.Stats().MaxOpenConnections; open != 3 {
+		t.Errorf("max open connections = %d, want 3", open)
+	}
+	// Every connection is instrumented.
+	if _, ok := db.Config.Plugins["goapify:query_logger"]; !ok {
+		t.Error("the query logger is not registered")
+	}
+}
+
+func TestInitFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	setDatabaseEnv(t, map[string]string{"DB_PATH": path})
+	var err error
+	captureStdout(t, func() { _, err = InitSQLite(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("InitSQLite did not open the database of DB_PATH: %v", err)
+	}
+
+	setDatabaseEnv(t, map[string]string{"DATABASE_TYPE": "sqlite", "DATABASE_MAX_IDLE": "many"})
+	if _, err := Init(true); err == nil || !strings.Contains(err.Error(), "DATABASE_MAX_IDLE") {
+		t.Errorf("Init with an invalid setting = %v, want an error", err)
+	}
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// DefaultConnection is the name of the application database, configured with the DATABASE_ variables.
+const DefaultConnection = "main"
+
+// ErrUnknownConnection is returned when a connection is requested by a name that is not configured.
+var ErrUnknownConnection = errors.New("unknown database connection")
+
+// Connections holds the named database connections of the application, such as a legacy database read next to
+// the main one. The DefaultConnection is always present. It is safe for concurrent use.
+type Connections struct {
+	mu          sync.RWMutex
+	connections map[string]*gorm.DB
+}
+
+// NewConnections creates a set of connections holding the given connection as the DefaultConnection.
+func NewConnections(main *gorm.DB) *Connections {
+	return &Connections{connections: map[string]*gorm.DB{DefaultConnection: main}}
+}
+
+// Add registers a connection under the given name, replacing the connection registered under it, if any.
+func (c *Connections) Add(name string, db *gorm.DB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connections[strings.ToLower(name)] = db
+}
+
+// Get returns the connection registered under the given name, or ErrUnknownConnection.
+func (c *Connections) Get(name string) (*gorm.DB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	db, ok := c.connections[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, add it to DB_CONNECTIONS", ErrUnknownConnection, name)
+	}
+	return db, nil
+}
+
+// Default returns the DefaultConnection.
+func (c *Connections) Default() *gorm.DB {
+	db, _ := c.Get(DefaultConnection)
+	return db
+}
+
+// Names returns the names of the registered connections, the DefaultConnection first.
+func (c *Connections) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.connections))
+	for name := range c.connections {
+		if name != DefaultConnection {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultConnection}, names...)
+}
+
+// ConnectionNames returns the names of the connections declared in DB_CONNECTIONS, such as "main,legacy",
+// the DefaultConnection first. The DefaultConnection is always included, also when DB_CONNECTIONS is not set.
+func ConnectionNames() []string {
+	names := []string{DefaultConnection}
+	for _, name := range strings.Split(os.Getenv("DB_CONNECTIONS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ConnectionConfigFromEnv reads the configuration of a named connection from the environment.
+// The DefaultConnection is configured with the DATABASE_ variables, see ConfigFromEnv, and every other connection
+// with the same variables prefixed by DB_ and its upper case name instead, e.g. DB_LEGACY_TYPE, DB_LEGACY_HOST
+// and DB_LEGACY_NAME for the "legacy" connection.
+func ConnectionConfigFromEnv(name string, production bool) (Config, error) {
+	if strings.EqualFold(name, DefaultConnection) {
+		return ConfigFromEnv(production)
+	}
+	prefix := "DB_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	cfg, err := configFromEnv(prefix, production)
+	if err == nil && cfg.Driver == "" && cfg.DSN == "" {
+		err = fmt.Errorf("%sTYPE must be set for the %s database connection", prefix, name)
+	}
+	return cfg, err
+}
+
+// InitConnection opens the named connection configured in the environment, see ConnectionConfigFromEnv and Open.
+func InitConnection(name string, production bool) (*gorm.DB, error) {
+	cfg, err := ConnectionConfigFromEnv(name, production)
+	if err != nil {
+		return nil, err
+	}
+	return Open(cfg)
+}
+
+// InitConnections opens every connection declared in DB_CONNECTIONS, see ConnectionNames and InitConnection.
+// All of them are opened at once, so a misconfigured connection stops the application at startup instead of
+// failing its first query. When one of them cannot be opened, the connections opened before it are closed.
+//
+// Example usage:
+//
+//	connections, err := database.InitConnections(production)
+//	legacy, err := connections.Get("legacy")
+func InitConnections(production bool) (*Connections, error) {
+	var connections *Connections
+	for _, name := range ConnectionNames() {
+		db, err := InitConnection(name, production)
+		if err != nil {
+			if connections != nil {
+				connections.close()
+			}
+			return nil, fmt.Errorf("database connection %s: %w", name, err)
+		}
+		if connections == nil {
+			connections = NewConnections(db)
+		} else {
+			connections.Add(name, db)
+		}
+	}
+	return connections, nil
+}
+
+// close closes the connection pools of every registered connection.
+func (c *Connections) close() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, db := range c.connections {
+		if pool, err := db.DB(); err == nil {
+			pool.Close()
+		}
+	}
+}
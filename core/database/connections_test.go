@@ -0,0 +1,181 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConnections(t *testing.T) {
+	dir := t.TempDir()
+	main, legacy := openOrigin(t, filepath.Join(dir, "main.db"), "main"), openOrigin(t, filepath.Join(dir, "legacy.db"), "legacy")
+	connections := NewConnections(main)
+	connections.Add("Legacy", legacy)
+	connections.Add("archive", main)
+
+	tests := []struct {
+		name    string
+		wantDB  string
+		wantErr error
+	}{
+		{"main", "main", nil},
+		{"legacy", "legacy", nil},
+		{"LEGACY", "legacy", nil},
+		{"archive", "main", nil},
+		{"crm", "", ErrUnknownConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := connections.Get(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var row origin
+			if err := db.First(&row).Error; err != nil || row.Name != tt.wantDB {
+				t.Errorf("Get(%q) reads the %q database (%v), want %q", tt.name, row.Name, err, tt.wantDB)
+			}
+		})
+	}
+
+	if got, want := connections.Names(), []string{"main", "archive", "legacy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+	if connections.Default() != main {
+		t.Error("Default is not the main connection")
+	}
+}
+
+func TestConnectionNames(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{"", []string{"main"}},
+		{"main", []string{"main"}},
+		{"main,legacy", []string{"main", "legacy"}},
+		{" Legacy , ,archive,legacy,MAIN", []string{"main", "legacy", "archive"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("DB_CONNECTIONS", tt.env)
+		if got := ConnectionNames(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ConnectionNames with DB_CONNECTIONS=%q = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection string
+		env        map[string]string
+		wantDriver string
+		wantHost   string
+		wantName   string
+		wantErr    string
+	}{
+		{
+			name:       "main connection",
+			connection: "MAIN",
+			env:        map[string]string{"DATABASE_TYPE": "mysql", "DATABASE_HOST": "db", "DB_MAIN_TYPE": "postgres"},
+			wantDriver: "mysql",
+			wantHost:   "db",
+		},
+		{
+			name:       "named connection",
+			connection: "legacy",
+			env:        map[string]string{"DATABASE_TYPE": "mysql", "DB_LEGACY_TYPE": "sqlserver", "DB_LEGACY_HOST": "old-db", "DB_LEGACY_NAME": "crm"},
+			wantDriver: "sqlserver",
+			wantHost:   "old-db",
+			wantName:   "crm",
+		},
+		{
+			name:       "the former variables only configure the main connection",
+			connection: "legacy",
+			env:        map[string]string{"DB_LEGACY_TYPE": "sqlite", "DB_HOST": "old-db", "DB_PATH": "app.db"},
+			wantDriver: "sqlite",
+		},
+		{
+			name:       "name with a dash",
+			connection: "legacy-crm",
+			env:        map[string]string{"DB_LEGACY_CRM_TYPE": "postgres", "DB_LEGACY_CRM_HOST": "crm"},
+			wantDriver: "postgres",
+			wantHost:   "crm",
+		},
+		{
+			name:       "DSN without a type",
+			connection: "legacy",
+			env:        map[string]string{"DB_LEGACY_DSN": "file:legacy.db"},
+		},
+		{
+			name:       "missing type",
+			connection: "legacy",
+			env:        map[string]string{"DB_LEGACY_HOST": "old-db"},
+			wantErr:    "DB_LEGACY_TYPE must be set for the legacy database connection",
+		},
+		{
+			name:       "invalid number",
+			connection: "legacy",
+			env:        map[string]string{"DB_LEGACY_TYPE": "sqlite", "DB_LEGACY_MAX_IDLE": "-2"},
+			wantErr:    `DB_LEGACY_MAX_IDLE must be a positive integer, got "-2"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDatabaseEnv(t, tt.env)
+			cfg, err := ConnectionConfigFromEnv(tt.connection, true)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Driver != tt.wantDriver || cfg.Host != tt.wantHost || cfg.Name != tt.wantName {
+				t.Errorf("config = %s on %q named %q, want %s on %q named %q", cfg.Driver, cfg.Host, cfg.Name, tt.wantDriver, tt.wantHost, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestInitConnections(t *testing.T) {
+	dir := t.TempDir()
+	setDatabaseEnv(t, map[string]string{
+		"DATABASE_TYPE":  "sqlite",
+		"DATABASE_NAME":  filepath.Join(dir, "main.db"),
+		"DB_LEGACY_TYPE": "sqlite",
+		"DB_LEGACY_NAME": filepath.Join(dir, "legacy.db"),
+	})
+
+	t.Setenv("DB_CONNECTIONS", "main,legacy")
+	var connections *Connections
+	var err error
+	captureStdout(t, func() { connections, err = InitConnections(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := connections.Names(); !reflect.DeepEqual(got, []string{"main", "legacy"}) {
+		t.Errorf("Names = %v, want main and legacy", got)
+	}
+	legacy, err := connections.Get("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file string
+	if err := legacy.Raw("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file).Error; err != nil || filepath.Base(file) != "legacy.db" {
+		t.Errorf("legacy connection opened %q (%v), want legacy.db", file, err)
+	}
+
+	t.Setenv("DB_CONNECTIONS", "main,legacy,archive")
+	captureStdout(t, func() { connections, err = InitConnections(true) })
+	if connections != nil || err == nil || !strings.HasPrefix(err.Error(), "database connection archive: DB_ARCHIVE_TYPE must be set") {
+		t.Errorf("InitConnections with a misconfigured connection = %v, %v, want an error", connections, err)
+	}
+}
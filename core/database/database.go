@@ -0,0 +1,30 @@
+// Package database connects to the application databases through a registry of drivers sharing one configuration,
+// and provides the read replica, query log and upsert support of those connections.
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// Init opens a connection to the database configured in the environment, see ConfigFromEnv and Open.
+// It is shared by the web server and the apify command line so both connect the same way.
+// When DATABASE_REPLICAS is set, read queries are spread over the replicas, see UseReplicas.
+// Queries are recorded in the QueryLog of their context and slow queries are reported, see UseQueryLogger.
+// It returns a *gorm.DB instance on success, or an error if the driver is not supported or the connection fails.
+func Init(production bool) (*gorm.DB, error) {
+	cfg, err := ConfigFromEnv(production)
+	if err != nil {
+		return nil, err
+	}
+	return Open(cfg)
+}
+
+// openFromEnv opens a connection with the given driver and the rest of the configuration from the environment.
+func openFromEnv(driver string, production bool) (*gorm.DB, error) {
+	cfg, err := ConfigFromEnv(production)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Driver = driver
+	return Open(cfg)
+}
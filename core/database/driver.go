@@ -0,0 +1,139 @@
+package database
+
+import (
+	"GoAPIfy/core/helper"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Driver opens connections to one type of database.
+type Driver struct {
+	// Dialector returns the GORM dialector connecting with the given configuration.
+	Dialector func(cfg Config) (gorm.Dialector, error)
+	// SQLDriver is the name of the database/sql driver used by the dialector. It is needed to open read replicas;
+	// leave it empty when the driver does not support them.
+	SQLDriver string
+	// Setup, when set, runs on every new connection, for instance to register callbacks.
+	Setup func(db *gorm.DB) error
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// RegisterDriver makes a driver available to Open under the given names, such as the DATABASE_TYPE values
+// selecting it. Registering a name again replaces the driver, so the built-in drivers can be overridden.
+// Call it from an init function or before connecting to the database.
+//
+// Example usage:
+//
+//	database.RegisterDriver(database.Driver{
+//		Dialector: func(cfg database.Config) (gorm.Dialector, error) {
+//			return clickhouse.Open(cfg.DSN), nil
+//		},
+//	}, "clickhouse")
+func RegisterDriver(driver Driver, names ...string) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	for _, name := range names {
+		drivers[strings.ToLower(name)] = driver
+	}
+}
+
+// lookupDriver returns the driver registered under the given name.
+func lookupDriver(name string) (Driver, error) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	driver, ok := drivers[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(drivers))
+		for registered := range drivers {
+			names = append(names, registered)
+		}
+		sort.Strings(names)
+		return Driver{}, fmt.Errorf("database driver %q is not supported, use one of %s", name, strings.Join(names, ", "))
+	}
+	return driver, nil
+}
+
+// Open connects to the database described by cfg with its registered driver and returns the GORM connection.
+// A failing first connection is retried cfg.ConnectRetries times with an exponential backoff, so the application
+// can start before its database is ready. The pool settings are applied, read replicas are registered
+// when configured (see UseReplicas) and the queries are instrumented (see UseQueryLogger).
+//
+// Example usage:
+//
+//	db, err := database.Open(database.Config{Driver: "postgres", Host: "db", Port: "5432", Name: "app", SSLMode: "require"})
+func Open(cfg Config) (*gorm.DB, error) {
+	driver, err := lookupDriver(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	gormConfig := &gorm.Config{}
+	if cfg.LogQueries {
+		gormConfig.Logger = logger.Default.LogMode(logger.Info)
+	}
+
+	fmt.Println(helper.ColorizeCmd(helper.Blue, fmt.Sprintf("Connecting to %s", cfg.Driver)))
+	var db *gorm.DB
+	backoff := cfg.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		// Every attempt gets a new dialector, since a dialector keeps the connection of its last attempt.
+		dialector, err := driver.Dialector(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if db, err = gorm.Open(dialector, gormConfig); err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetries {
+			return nil, fmt.Errorf("cannot connect to the database after %d attempt(s): %w", attempt+1, err)
+		}
+		fmt.Println(helper.ColorizeCmd(helper.Yellow, fmt.Sprintf("Cannot connect to the database, retrying in %s: %s", backoff, err)))
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+
+	if driver.Setup != nil {
+		if err := driver.Setup(db); err != nil {
+			return nil, err
+		}
+	}
+	pool, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(pool, cfg)
+
+	if err := UseReplicas(db, cfg); err != nil {
+		return nil, err
+	}
+	if err := UseQueryLogger(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// configurePool applies the pool settings of the configuration to a connection pool.
+func configurePool(pool *sql.DB, cfg Config) {
+	if cfg.MaxIdle > 0 {
+		pool.SetMaxIdleConns(cfg.MaxIdle)
+	}
+	if cfg.MaxOpen > 0 {
+		pool.SetMaxOpenConns(cfg.MaxOpen)
+	}
+	if cfg.MaxLifetime > 0 {
+		pool.SetConnMaxLifetime(cfg.MaxLifetime)
+	}
+}
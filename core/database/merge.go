@@ -0,0 +1,106 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+// RegisterMerge makes upserts on SQL Server match existing records on the conflict columns of the ON CONFLICT clause.
+// The SQL Server driver turns an upsert into a MERGE that always matches on the primary key and falls back to a plain
+// INSERT when the primary key is not set, so an upsert on a unique column such as email would insert duplicates.
+func RegisterMerge(db *gorm.DB) error {
+	return db.Callback().Create().Before("gorm:create").Register("goapify:merge", mergeOnConflictColumns)
+}
+
+// mergeOnConflictColumns builds the MERGE statement of an upsert with conflict columns.
+// The driver executes a statement that has already been built as is, including scanning the OUTPUT of generated fields.
+func mergeOnConflictColumns(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	if onConflict, ok := db.Statement.Clauses["ON CONFLICT"].Expression.(clause.OnConflict); !ok || len(onConflict.Columns) == 0 {
+		return
+	}
+
+	// Converting the values also turns UpdateAll into the list of columns to update.
+	values := callbacks.ConvertToCreateValues(db.Statement)
+	onConflict := db.Statement.Clauses["ON CONFLICT"].Expression.(clause.OnConflict)
+	stmt := db.Statement
+	primary := stmt.Schema.PrioritizedPrimaryField
+
+	stmt.WriteString("MERGE INTO ")
+	stmt.WriteQuoted(stmt.Table)
+	stmt.WriteString(" USING (VALUES")
+	for i, value := range values.Values {
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteByte('(')
+		stmt.AddVar(stmt, value...)
+		stmt.WriteByte(')')
+	}
+	stmt.WriteString(") AS excluded (")
+	for i, column := range values.Columns {
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteQuoted(column.Name)
+	}
+	stmt.WriteString(") ON ")
+
+	var on clause.Where
+	for _, column := range onConflict.Columns {
+		on.Exprs = append(on.Exprs, clause.Eq{
+			Column: clause.Column{Table: stmt.Table, Name: column.Name},
+			Value:  clause.Column{Table: "excluded", Name: column.Name},
+		})
+	}
+	on.Build(stmt)
+
+	if len(onConflict.DoUpdates) > 0 {
+		stmt.WriteString(" WHEN MATCHED")
+		// The WHERE of the ON CONFLICT clause limits the matched records that are updated, the others are left untouched.
+		if len(onConflict.Where.Exprs) > 0 {
+			stmt.WriteString(" AND ")
+			clause.AndConditions{Exprs: onConflict.Where.Exprs}.Build(stmt)
+		}
+		stmt.WriteString(" THEN UPDATE SET ")
+		onConflict.DoUpdates.Build(stmt)
+	}
+
+	// Generated primary keys are left to the database.
+	var inserted []clause.Column
+	for _, column := range values.Columns {
+		if primary == nil || !primary.AutoIncrement || primary.DBName != column.Name {
+			inserted = append(inserted, column)
+		}
+	}
+	stmt.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	for i, column := range inserted {
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteQuoted(column.Name)
+	}
+	stmt.WriteString(") VALUES (")
+	for i, column := range inserted {
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteQuoted(clause.Column{Table: "excluded", Name: column.Name})
+	}
+	stmt.WriteString(")")
+
+	if fields := stmt.Schema.FieldsWithDefaultDBValue; len(fields) > 0 {
+		stmt.WriteString(" OUTPUT")
+		for i, field := range fields {
+			if i > 0 {
+				stmt.WriteString(",")
+			}
+			stmt.WriteString(" INSERTED.")
+			stmt.AddVar(stmt, clause.Column{Name: field.DBName})
+		}
+	}
+	stmt.WriteString(";")
+}
@@ -1,78 +1,54 @@
-// Package database provides functions for initializing and interacting with different database management systems.
 package database
 
 import (
-	"fmt"
-	"log"
-	"os"
-	"strconv"
-	"time"
+	"net"
+	"net/url"
 
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// InitMSSQL initializes a connection to a Microsoft SQL Server database.
-// It reads the database connection details from environment variables and returns a *gorm.DB instance on success.
-// If production is false, logging is enabled.
-func InitMSSQL(production bool) (*gorm.DB, error) {
-	// Get the database configuration from the environment variables
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-	dbUser := os.Getenv("DB_USER")
-	dbPass := os.Getenv("DB_PASS")
-
-	// Create the database URL string
-	dbUrl := fmt.Sprintf("sqlserver://%s:%s@%s:%s?database=%s", dbUser, dbPass, dbHost, dbPort, dbName)
+func init() {
+	RegisterDriver(Driver{Dialector: mssqlDialector, SQLDriver: "sqlserver", Setup: RegisterMerge}, "sqlserver", "mssql")
+}
 
-	// Open a connection to the database
-	var loggerOption *gorm.Config
-	if !production {
-		loggerOption = &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
+// mssqlDialector returns the dialector of a Microsoft SQL Server connection.
+// Without an SSL mode the driver default applies, which encrypts the login only. SQL Server has no session time zone,
+// so the timezone setting is ignored. Upserts are set up to match existing records on their conflict columns, see RegisterMerge.
+func mssqlDialector(cfg Config) (gorm.Dialector, error) {
+	if cfg.DSN != "" {
+		return sqlserver.Open(cfg.DSN), nil
+	}
+
+	query := url.Values{}
+	query.Set("database", cfg.Name)
+	switch cfg.SSLMode {
+	case "disable":
+		query.Set("encrypt", "disable")
+	case "prefer", "require":
+		query.Set("encrypt", "true")
+		query.Set("TrustServerCertificate", "true")
+	case "verify-ca", "verify-full":
+		query.Set("encrypt", "true")
+		if cfg.SSLRootCert != "" {
+			query.Set("certificate", cfg.SSLRootCert)
 		}
 	}
-	db, err := gorm.Open(sqlserver.Open(dbUrl), &gorm.Config{}, loggerOption)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set up database connection pool settings
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, err
-	}
-
-	maxIdleStr := os.Getenv("DATABASE_MAX_IDLE")
-	maxIdle, err := strconv.Atoi(maxIdleStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_IDLE to integer.")
-	}
 
-	maxConnectionStr := os.Getenv("DATABASE_MAX_CONNECTION")
-	maxConnection, err := strconv.Atoi(maxConnectionStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_CONNECTION to integer.")
-	}
-
-	maxLifetimeStr := os.Getenv("DATABASE_MAX_LIFETIME")
-	maxLifetime, err := strconv.Atoi(maxLifetimeStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_LIFETIME to integer.")
-	}
-
-	if maxIdle > 0 {
-		sqlDB.SetMaxIdleConns(maxIdle)
-	}
-	if maxConnection > 0 {
-		sqlDB.SetMaxOpenConns(maxConnection)
-	}
-	if maxLifetime > 0 {
-		sqlDB.SetConnMaxLifetime(time.Duration(maxLifetime) * time.Second)
+	dsn := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		RawQuery: query.Encode(),
 	}
+	return sqlserver.Open(dsn.String()), nil
+}
 
-	// Return the database instance
-	return db, nil
+// InitMSSQL initializes a connection to a Microsoft SQL Server database.
+// It reads the database connection details from environment variables and returns a *gorm.DB instance on success.
+// If production is false, logging is enabled.
+//
+// Deprecated: use Open with ConfigFromEnv, which reads the same settings for every database type.
+func InitMSSQL(production bool) (*gorm.DB, error) {
+	return openFromEnv("sqlserver", production)
 }
@@ -1,82 +1,70 @@
 package database
 
 import (
-	"GoAPIfy/core/helper"
-	"fmt"
-	"log"
-	"os"
-	"strconv"
+	"net"
 	"time"
 
+	gomysql "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// InitMysql initializes a new MySQL or MariaDB database connection and returns a GORM DB object, or an error if the connection fails.
-// If the production flag is set to true, GORM will not output SQL logs.
-func InitMysql(production bool) (*gorm.DB, error) {
-	// Print a message to indicate that the MySQL / MariaDB connection is being initialized
-	fmt.Println(helper.ColorizeCmd(helper.Blue, "Initializing MySQL / MariaDB"))
-
-	// Get the database configuration from the environment variables
-	dbName := os.Getenv("DATABASE_NAME")
-	dbHost := os.Getenv("DATABASE_HOST")
-	dbPort := os.Getenv("DATABASE_PORT")
-	dbUser := os.Getenv("DATABASE_USER")
-	dbPass := os.Getenv("DATABASE_PASS")
-
-	// Create a Data Source Name (DSN) for the database connection
-	dsn := dbUser + ":" + dbPass + "@tcp(" + dbHost + ":" + dbPort + ")/" + dbName + "?charset=utf8mb4&parseTime=True&loc=Local&net_write_timeout=6000"
-
-	// Set the logger configuration based on the production flag
-	var loggerOption = &gorm.Config{}
-	if !production {
-		loggerOption = &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		}
-	}
-
-	// Open a connection to the database using the provided DSN and logger settings
-	db, err := gorm.Open(mysql.Open(dsn), loggerOption)
-	if err != nil {
-		return nil, err
-	}
+func init() {
+	RegisterDriver(Driver{Dialector: mysqlDialector, SQLDriver: "mysql"}, "mysql", "mariadb")
+}
 
-	// Set up database connection pool settings
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, err
+// mysqlDialector returns the dialector of a MySQL or MariaDB connection.
+// The charset defaults to utf8mb4 and the time zone to the local one. SSL is disabled by default; prefer and require
+// encrypt the connection without checking the certificate, the verify modes check it against the CA.
+func mysqlDialector(cfg Config) (gorm.Dialector, error) {
+	if cfg.DSN != "" {
+		return mysql.Open(cfg.DSN), nil
 	}
 
-	maxIdleStr := os.Getenv("DATABASE_MAX_IDLE")
-	maxIdle, err := strconv.Atoi(maxIdleStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_IDLE to integer.")
+	dsn := gomysql.NewConfig()
+	dsn.User = cfg.User
+	dsn.Passwd = cfg.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	dsn.DBName = cfg.Name
+	dsn.ParseTime = true
+	dsn.Params = map[string]string{"charset": "utf8mb4", "net_write_timeout": "6000"}
+	if cfg.Charset != "" {
+		dsn.Params["charset"] = cfg.Charset
 	}
 
-	maxConnectionStr := os.Getenv("DATABASE_MAX_CONNECTION")
-	maxConnection, err := strconv.Atoi(maxConnectionStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_CONNECTION to integer.")
+	dsn.Loc = time.Local
+	if cfg.Timezone != "" {
+		location, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		// Sessions use the same zone, so NOW() and the values read agree with the parsed times.
+		// Named zones other than UTC need the time zone tables of the server to be loaded.
+		dsn.Loc = location
+		dsn.Params["time_zone"] = "'" + location.String() + "'"
+		if location == time.UTC {
+			dsn.Params["time_zone"] = "'+00:00'"
+		}
 	}
 
-	maxLifetimeStr := os.Getenv("DATABASE_MAX_LIFETIME")
-	maxLifetime, err := strconv.Atoi(maxLifetimeStr)
+	tlsConfig, err := cfg.TLSConfig()
 	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_LIFETIME to integer.")
-	}
-
-	if maxIdle > 0 {
-		sqlDB.SetMaxIdleConns(maxIdle)
-	}
-	if maxConnection > 0 {
-		sqlDB.SetMaxOpenConns(maxConnection)
+		return nil, err
 	}
-	if maxLifetime > 0 {
-		sqlDB.SetConnMaxLifetime(time.Duration(maxLifetime) * time.Second)
+	if tlsConfig != nil {
+		if err := gomysql.RegisterTLSConfig("goapify", tlsConfig); err != nil {
+			return nil, err
+		}
+		dsn.TLSConfig = "goapify"
 	}
+	return mysql.Open(dsn.FormatDSN()), nil
+}
 
-	// Return the database instance
-	return db, err
+// InitMysql initializes a new MySQL or MariaDB database connection and returns a GORM DB object, or an error if the connection fails.
+// If the production flag is set to true, GORM will not output SQL logs.
+//
+// Deprecated: use Open with ConfigFromEnv, which reads the same settings for every database type.
+func InitMysql(production bool) (*gorm.DB, error) {
+	return openFromEnv("mysql", production)
 }
@@ -1,81 +1,57 @@
-// Package database provides functions for initializing and connecting to a PostgreSQL database using the GORM ORM library.
 package database
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"strconv"
-	"time"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// InitPostgres initializes a connection to a PostgreSQL database and returns a GORM DB instance. The function takes a boolean argument production which determines whether to use a logger in production environment or not.
-// It reads database configuration from environment variables: DB_HOST, DB_PORT, DB_NAME, DB_USER, DB_PASS, DATABASE_MAX_IDLE, DATABASE_MAX_CONNECTION, and DATABASE_MAX_LIFETIME.
-// It creates the database URL string using the configuration and opens a connection to the database. Then, it sets up the database connection pool settings.
-// The function returns a GORM DB instance and an error if any.
-func InitPostgres(production bool) (*gorm.DB, error) {
-
-	// Get the database configuration from the environment variables
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-	dbUser := os.Getenv("DB_USER")
-	dbPass := os.Getenv("DB_PASS")
-
-	// Create the database URL string
-	dbUrl := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=disable", dbHost, dbPort, dbName, dbUser, dbPass)
+func init() {
+	RegisterDriver(Driver{Dialector: postgresDialector, SQLDriver: "pgx"}, "postgres", "postgresql")
+}
 
-	// Open a connection to the database
-	var loggerOption *gorm.Config
-	if !production {
-		loggerOption = &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
+// postgresDialector returns the dialector of a PostgreSQL connection.
+// The SSL mode defaults to prefer, which encrypts the connection when the server supports it.
+func postgresDialector(cfg Config) (gorm.Dialector, error) {
+	if cfg.DSN != "" {
+		return postgres.Open(cfg.DSN), nil
+	}
+
+	settings := [][2]string{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"dbname", cfg.Name},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"sslmode", cfg.sslMode("prefer")},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+		{"TimeZone", cfg.Timezone},
+		{"client_encoding", cfg.Charset},
+	}
+	var dsn []string
+	for _, setting := range settings {
+		if setting[1] != "" {
+			dsn = append(dsn, fmt.Sprintf("%s=%s", setting[0], quoteSetting(setting[1])))
 		}
 	}
-	db, err := gorm.Open(postgres.Open(dbUrl), loggerOption)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set up database connection pool settings
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, err
-	}
-
-	maxIdleStr := os.Getenv("DATABASE_MAX_IDLE")
-	maxIdle, err := strconv.Atoi(maxIdleStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_IDLE to integer.")
-	}
-
-	maxConnectionStr := os.Getenv("DATABASE_MAX_CONNECTION")
-	maxConnection, err := strconv.Atoi(maxConnectionStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_CONNECTION to integer.")
-	}
-
-	maxLifetimeStr := os.Getenv("DATABASE_MAX_LIFETIME")
-	maxLifetime, err := strconv.Atoi(maxLifetimeStr)
-	if err != nil {
-		log.Fatal("Error converting DATABASE_MAX_LIFETIME to integer.")
-	}
+	return postgres.Open(strings.Join(dsn, " ")), nil
+}
 
-	// Configure database connection pool
-	if maxIdle > 0 {
-		sqlDB.SetMaxIdleConns(maxIdle)
-	}
-	if maxConnection > 0 {
-		sqlDB.SetMaxOpenConns(maxConnection)
-	}
-	if maxLifetime > 0 {
-		sqlDB.SetConnMaxLifetime(time.Duration(maxLifetime) * time.Second)
+// quoteSetting quotes a value of a key=value connection string when it is empty or holds spaces or quotes.
+func quoteSetting(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
 	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
 
-	// Return the database instance
-	return db, nil
+// InitPostgres initializes a connection to a PostgreSQL database and returns a GORM DB instance. The function takes a boolean argument production which determines whether to use a logger in production environment or not.
+//
+// Deprecated: use Open with ConfigFromEnv, which reads the same settings for every database type.
+func InitPostgres(production bool) (*gorm.DB, error) {
+	return openFromEnv("postgres", production)
 }
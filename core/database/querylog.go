@@ -0,0 +1,279 @@
+package database
+
+import (
+	"GoAPIfy/core/helper"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoggedQuery is a query recorded by a QueryLog.
+type LoggedQuery struct {
+	SQL          string
+	Vars         []interface{}
+	Duration     time.Duration
+	RowsAffected int64
+	// Caller is the function and line of the application code that ran the query.
+	Caller string
+	Error  error
+}
+
+// RepeatedQuery is a query shape that ran several times in the same request, a suspected N+1 query.
+type RepeatedQuery struct {
+	// Shape is the SQL of the query with the lists of bound values collapsed, so queries that only differ
+	// by their values or by the number of values in an IN list share a shape.
+	Shape   string
+	Count   int
+	Callers []string
+}
+
+// QueryLog records the queries run with a context holding it, typically the queries of a single request.
+// It is safe for concurrent use.
+type QueryLog struct {
+	mu      sync.Mutex
+	queries []LoggedQuery
+}
+
+// queryLogContextKey is the context key of the QueryLog of a request.
+type queryLogContextKey struct{}
+
+// NewQueryLog creates an empty query log.
+func NewQueryLog() *QueryLog {
+	return &QueryLog{}
+}
+
+// WithQueryLog returns a copy of the context that records the queries run with it in the given log.
+//
+// Example usage:
+//
+//	queries := database.NewQueryLog()
+//	err := s.Model.WithContext(database.WithQueryLog(ctx, queries)).Load(&users).Get()
+func WithQueryLog(ctx context.Context, log *QueryLog) context.Context {
+	return context.WithValue(ctx, queryLogContextKey{}, log)
+}
+
+// QueryLogFromContext returns the query log of the given context, if any.
+func QueryLogFromContext(ctx context.Context) (*QueryLog, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	log, ok := ctx.Value(queryLogContextKey{}).(*QueryLog)
+	return log, ok
+}
+
+// Queries returns a copy of the recorded queries, in the order they finished.
+func (l *QueryLog) Queries() []LoggedQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LoggedQuery(nil), l.queries...)
+}
+
+// Count returns the number of recorded queries.
+func (l *QueryLog) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queries)
+}
+
+// Duration returns the total time spent in the recorded queries.
+func (l *QueryLog) Duration() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var total time.Duration
+	for _, query := range l.queries {
+		total += query.Duration
+	}
+	return total
+}
+
+// Repeated returns the SELECT shapes that ran at least threshold times, the usual sign of an N+1 query:
+// a query run once per record of a previous result instead of once for all of them, see model.With.
+func (l *QueryLog) Repeated(threshold int) []RepeatedQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var repeated []*RepeatedQuery
+	shapes := map[string]*RepeatedQuery{}
+	for _, query := range l.queries {
+		if !isSelect(query.SQL) {
+			continue
+		}
+		shape := queryShape(query.SQL)
+		r, ok := shapes[shape]
+		if !ok {
+			r = &RepeatedQuery{Shape: shape}
+			shapes[shape] = r
+			repeated = append(repeated, r)
+		}
+		r.Count++
+		if !containsString(r.Callers, query.Caller) {
+			r.Callers = append(r.Callers, query.Caller)
+		}
+	}
+
+	var found []RepeatedQuery
+	for _, r := range repeated {
+		if threshold > 0 && r.Count >= threshold {
+			found = append(found, *r)
+		}
+	}
+	return found
+}
+
+// record adds a query to the log.
+func (l *QueryLog) record(query LoggedQuery) {
+	l.mu.Lock()
+	l.queries = append(l.queries, query)
+	l.mu.Unlock()
+}
+
+// QueryLogger is a GORM plugin that records the queries run with a context holding a QueryLog,
+// and prints the queries slower than the threshold of DATABASE_SLOW_QUERY_THRESHOLD, in milliseconds, whatever their context.
+type QueryLogger struct {
+	slowThreshold time.Duration
+}
+
+// queryStartKey is the statement setting holding the time the statement started.
+const queryStartKey = "goapify:query_start"
+
+// UseQueryLogger registers a QueryLogger on the given connection. DATABASE_SLOW_QUERY_THRESHOLD holds the number of
+// milliseconds above which a query is reported as slow, 200 by default; 0 disables the report.
+func UseQueryLogger(db *gorm.DB) error {
+	slowThreshold := 200 * time.Millisecond
+	if milliseconds, err := strconv.Atoi(os.Getenv("DATABASE_SLOW_QUERY_THRESHOLD")); err == nil && milliseconds >= 0 {
+		slowThreshold = time.Duration(milliseconds) * time.Millisecond
+	}
+	if err := db.Use(&QueryLogger{slowThreshold: slowThreshold}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return err
+	}
+	return nil
+}
+
+// Name returns the name of the plugin.
+func (q *QueryLogger) Name() string {
+	return "goapify:query_logger"
+}
+
+// Initialize times the statements around the callback of every processor that runs SQL.
+func (q *QueryLogger) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("gorm:create").Register("goapify:query_start", q.start),
+		callbacks.Create().After("gorm:create").Register("goapify:query_record", q.record),
+		callbacks.Query().Before("gorm:query").Register("goapify:query_start", q.start),
+		callbacks.Query().After("gorm:query").Register("goapify:query_record", q.record),
+		callbacks.Update().Before("gorm:update").Register("goapify:query_start", q.start),
+		callbacks.Update().After("gorm:update").Register("goapify:query_record", q.record),
+		callbacks.Delete().Before("gorm:delete").Register("goapify:query_start", q.start),
+		callbacks.Delete().After("gorm:delete").Register("goapify:query_record", q.record),
+		callbacks.Row().Before("gorm:row").Register("goapify:query_start", q.start),
+		callbacks.Row().After("gorm:row").Register("goapify:query_record", q.record),
+		callbacks.Raw().Before("gorm:raw").Register("goapify:query_start", q.start),
+		callbacks.Raw().After("gorm:raw").Register("goapify:query_record", q.record),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start stores the time the statement starts.
+func (q *QueryLogger) start(db *gorm.DB) {
+	db.Statement.Settings.Store(queryStartKey, time.Now())
+}
+
+// record records the statement in the query log of its context and reports it when it is slow.
+func (q *QueryLogger) record(db *gorm.DB) {
+	started, ok := db.Statement.Settings.Load(queryStartKey)
+	if !ok || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	duration := time.Since(started.(time.Time))
+	log, logged := QueryLogFromContext(db.Statement.Context)
+	slow := q.slowThreshold > 0 && duration >= q.slowThreshold
+	if !logged && !slow {
+		return
+	}
+
+	query := LoggedQuery{
+		SQL:          db.Statement.SQL.String(),
+		Vars:         append([]interface{}(nil), db.Statement.Vars...),
+		Duration:     duration,
+		RowsAffected: db.RowsAffected,
+		Caller:       caller(),
+		Error:        db.Error,
+	}
+	if logged {
+		log.record(query)
+	}
+	if slow {
+		sql := db.Dialector.Explain(query.SQL, query.Vars...)
+		fmt.Println(helper.ColorizeCmd(helper.Yellow, fmt.Sprintf("Slow query (%s) at %s: %s", duration.Round(time.Microsecond), query.Caller, sql)))
+	}
+}
+
+// caller returns the first function on the call stack outside of GORM, the SQL drivers, the model wrapper and this package.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !isInternalFrame(frame.Function) {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// internalPackages are the packages whose frames caller skips.
+var internalPackages = []string{"gorm.io/", "database/sql.", "runtime.", "GoAPIfy/model.", "GoAPIfy/core/database."}
+
+// isInternalFrame reports whether the given function belongs to one of the internalPackages.
+func isInternalFrame(function string) bool {
+	for _, prefix := range internalPackages {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderList matches a parenthesized list of bind placeholders in the syntax of any supported dialect.
+var placeholderList = regexp.MustCompile(`\(\s*(\?|\$\d+|@p\d+)(\s*,\s*(\?|\$\d+|@p\d+))*\s*\)`)
+
+// placeholder matches a single numbered bind placeholder of Postgres or SQL Server.
+var placeholder = regexp.MustCompile(`\$\d+|@p\d+`)
+
+// queryShape returns the SQL of a query with its placeholders unified and its lists of values collapsed.
+func queryShape(sql string) string {
+	sql = placeholderList.ReplaceAllString(sql, "(?)")
+	return placeholder.ReplaceAllString(sql, "?")
+}
+
+// isSelect reports whether the SQL is a read query.
+func isSelect(sql string) bool {
+	sql = strings.ToUpper(strings.TrimSpace(sql))
+	return strings.HasPrefix(sql, "SELECT") || strings.HasPrefix(sql, "WITH")
+}
+
+// containsString reports whether the slice contains the value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
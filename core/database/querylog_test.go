@@ -0,0 +1,221 @@
+package database
+
+import (
+	"GoAPIfy/internal/testdb"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// captureStdout returns what fn prints on the standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		var buffer bytes.Buffer
+		_, _ = io.Copy(&buffer, reader)
+		printed <- buffer.String()
+	}()
+	fn()
+	writer.Close()
+	return <-printed
+}
+
+// openLoggedDB opens an SQLite database with an origin table and the query logger registered.
+func openLoggedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := testdb.Open(t, &origin{})
+	if err := UseQueryLogger(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestQueryShape(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM `users` WHERE `id` = ?", "SELECT * FROM `users` WHERE `id` = ?"},
+		{"SELECT * FROM `posts` WHERE `user_id` IN (?,?,?)", "SELECT * FROM `posts` WHERE `user_id` IN (?)"},
+		{`SELECT * FROM "posts" WHERE "user_id" IN ($1, $2) AND "id" > $3`, `SELECT * FROM "posts" WHERE "user_id" IN (?) AND "id" > ?`},
+		{"SELECT * FROM [posts] WHERE [user_id] IN (@p1,@p2) AND [id] = @p3", "SELECT * FROM [posts] WHERE [user_id] IN (?) AND [id] = ?"},
+		{"SELECT count(*) FROM `users` WHERE (`name` = ?)", "SELECT count(*) FROM `users` WHERE (`name` = ?)"},
+		{"SELECT * FROM `users` WHERE `id` IN (?)", "SELECT * FROM `users` WHERE `id` IN (?)"},
+		{"INSERT INTO `users` (`name`,`age`) VALUES (?,?),(?,?)", "INSERT INTO `users` (`name`,`age`) VALUES (?),(?)"},
+	}
+	for _, tt := range tests {
+		if got := queryShape(tt.sql); got != tt.want {
+			t.Errorf("queryShape(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestQueryLogRepeated(t *testing.T) {
+	log := NewQueryLog()
+	for _, query := range []LoggedQuery{
+		{SQL: "SELECT * FROM `posts` WHERE `user_id` = ?", Caller: "a"},
+		{SQL: "SELECT * FROM `posts` WHERE `user_id` = ?", Caller: "a"},
+		{SQL: "SELECT * FROM `posts` WHERE `user_id` = ?", Caller: "b"},
+		{SQL: "SELECT * FROM `tags` WHERE `id` IN (?,?)", Caller: "a"},
+		{SQL: "SELECT * FROM `tags` WHERE `id` IN (?)", Caller: "a"},
+		{SQL: "UPDATE `posts` SET `views` = ? WHERE `id` = ?", Caller: "a"},
+		{SQL: "UPDATE `posts` SET `views` = ? WHERE `id` = ?", Caller: "a"},
+		{SQL: "UPDATE `posts` SET `views` = ? WHERE `id` = ?", Caller: "a"},
+		{SQL: "with recent AS (SELECT 1) SELECT * FROM recent", Caller: "c"},
+	} {
+		log.record(query)
+	}
+
+	tests := []struct {
+		threshold int
+		want      string
+	}{
+		{0, ""},
+		{4, ""},
+		{3, "3 SELECT * FROM `posts` WHERE `user_id` = ? [a b]"},
+		{2, "3 SELECT * FROM `posts` WHERE `user_id` = ? [a b] | 2 SELECT * FROM `tags` WHERE `id` IN (?) [a]"},
+		{1, "3 SELECT * FROM `posts` WHERE `user_id` = ? [a b] | 2 SELECT * FROM `tags` WHERE `id` IN (?) [a] | 1 with recent AS (SELECT 1) SELECT * FROM recent [c]"},
+	}
+	for _, tt := range tests {
+		var found []string
+		for _, r := range log.Repeated(tt.threshold) {
+			found = append(found, fmt.Sprintf("%d %s %v", r.Count, r.Shape, r.Callers))
+		}
+		if got := strings.Join(found, " | "); got != tt.want {
+			t.Errorf("Repeated(%d) = %q, want %q", tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestQueryLogger(t *testing.T) {
+	t.Setenv("DATABASE_SLOW_QUERY_THRESHOLD", "0")
+	db := openLoggedDB(t)
+	queries := NewQueryLog()
+	logged := db.WithContext(WithQueryLog(context.Background(), queries))
+
+	tests := []struct {
+		name      string
+		run       func() error
+		wantSQL   string
+		wantVars  string
+		wantRows  int64
+		wantError bool
+	}{
+		{"create", func() error { return logged.Create(&origin{ID: 1, Name: "a"}).Error }, "INSERT INTO `origins`", "[a 1]", 1, false},
+		{"query", func() error { return logged.Where("name = ?", "a").Find(&[]origin{}).Error }, "SELECT * FROM `origins` WHERE name = ?", "[a]", 1, false},
+		{"update", func() error { return logged.Model(&origin{ID: 1}).Update("name", "b").Error }, "UPDATE `origins` SET `name`=?", "[b 1]", 1, false},
+		// Row does not know the number of rows it reads.
+		{"row", func() error { return logged.Model(&origin{}).Select("count(*)").Row().Err() }, "SELECT count(*) FROM `origins`", "[]", -1, false},
+		{"raw", func() error { return logged.Exec("UPDATE origins SET name = ?", "c").Error }, "UPDATE origins SET name = ?", "[c]", 1, false},
+		{"delete", func() error { return logged.Delete(&origin{ID: 1}).Error }, "DELETE FROM `origins`", "[1]", 1, false},
+		{"failed query", func() error { return logged.Exec("UPDATE missing SET name = ?", "x").Error }, "UPDATE missing", "[x]", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := queries.Count()
+			if err := tt.run(); (err != nil) != tt.wantError {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantError)
+			}
+			all := queries.Queries()
+			if len(all) != before+1 {
+				t.Fatalf("%d queries recorded, want 1", len(all)-before)
+			}
+			query := all[before]
+			if !strings.HasPrefix(query.SQL, tt.wantSQL) || fmt.Sprint(query.Vars) != tt.wantVars || query.RowsAffected != tt.wantRows {
+				t.Errorf("recorded %q %v with %d rows, want %q %s with %d rows", query.SQL, query.Vars, query.RowsAffected, tt.wantSQL, tt.wantVars, tt.wantRows)
+			}
+			if (query.Error != nil) != tt.wantError || query.Duration <= 0 || query.Caller == "" {
+				t.Errorf("recorded error %v, duration %s and caller %q", query.Error, query.Duration, query.Caller)
+			}
+		})
+	}
+
+	// Queries without a log in their context are not recorded.
+	before := queries.Count()
+	if err := db.Find(&[]origin{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if queries.Count() != before {
+		t.Error("a query without a query log in its context was recorded")
+	}
+	var total time.Duration
+	for _, query := range queries.Queries() {
+		total += query.Duration
+	}
+	if queries.Duration() != total {
+		t.Errorf("Duration = %s, want the sum %s", queries.Duration(), total)
+	}
+}
+
+func TestSlowQueries(t *testing.T) {
+	tests := []struct {
+		threshold     string
+		wantThreshold time.Duration
+		wantReport    bool
+	}{
+		{"", 200 * time.Millisecond, false},
+		{"invalid", 200 * time.Millisecond, false},
+		{"-1", 200 * time.Millisecond, false},
+		{"0", 0, false},
+		{"1", time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			t.Setenv("DATABASE_SLOW_QUERY_THRESHOLD", tt.threshold)
+			db := openLoggedDB(t)
+			plugin, ok := db.Config.Plugins["goapify:query_logger"].(*QueryLogger)
+			if !ok || plugin.slowThreshold != tt.wantThreshold {
+				t.Fatalf("slow threshold = %v, want %s", plugin, tt.wantThreshold)
+			}
+			if err := UseQueryLogger(db); err != nil {
+				t.Errorf("registering the query logger twice = %v", err)
+			}
+
+			// The recursive query counts to a million, which takes more than a millisecond. It runs with Exec,
+			// as the rows of a query are only computed while they are read, after the query is recorded.
+			printed := captureStdout(t, func() {
+				db.Exec("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?) SELECT count(*) FROM n", 1000000)
+			})
+			if reported := strings.Contains(printed, "Slow query") && strings.Contains(printed, "1000000"); reported != tt.wantReport {
+				t.Errorf("printed %q, want a slow query report: %v", printed, tt.wantReport)
+			}
+		})
+	}
+}
+
+func TestQueryLogFromContext(t *testing.T) {
+	queries := NewQueryLog()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want *QueryLog
+	}{
+		{"nil context", nil, nil},
+		{"without a log", context.Background(), nil},
+		{"with a log", WithQueryLog(context.Background(), queries), queries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := QueryLogFromContext(tt.ctx); got != tt.want || ok != (tt.want != nil) {
+				t.Errorf("QueryLogFromContext = %p, %v, want %p", got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"GoAPIfy/core/helper"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// primaryContextKey marks a context whose queries must run on the primary database.
+type primaryContextKey struct{}
+
+// OnPrimary returns a session of the given connection whose queries always run on the primary database,
+// even when read replicas are configured. Use it to read data right after writing it, since replicas may lag behind.
+// The setting is carried in the context of the session, so it survives Session, Load and repository chains.
+func OnPrimary(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, primaryContextKey{}, true))
+}
+
+// usesPrimary reports whether the given context was marked by OnPrimary.
+func usesPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(primaryContextKey{}).(bool)
+	return primary
+}
+
+// replica is a single read replica together with its last known health: 1 healthy, 0 unhealthy, -1 not checked yet.
+type replica struct {
+	pool    *sql.DB
+	healthy int32
+}
+
+// Resolver is a GORM plugin that sends read queries to healthy read replicas and everything else to the primary.
+// Queries (Find, First, Count, Scan, ...) are spread over the replicas round robin, while writes, transactions,
+// locking reads (SELECT ... FOR UPDATE) and sessions created with OnPrimary use the primary connection.
+// When every replica is unhealthy, reads fall back to the primary.
+type Resolver struct {
+	primary        gorm.ConnPool
+	replicas       []*replica
+	next           uint32
+	healthInterval time.Duration
+}
+
+// UseReplicas registers a Resolver on the given primary connection for the read replicas of the configuration.
+// cfg.Replicas holds DSNs in the format of the driver of the primary connection, see ConfigFromEnv,
+// and DATABASE_REPLICA_HEALTH_INTERVAL the number of seconds between replica health checks (10 by default).
+// The replicas use the pool settings of the primary connection. It does nothing when no replica is configured.
+func UseReplicas(db *gorm.DB, cfg Config) error {
+	if len(cfg.Replicas) == 0 {
+		return nil
+	}
+	driver, err := lookupDriver(cfg.Driver)
+	if err != nil {
+		return err
+	}
+	if driver.SQLDriver == "" {
+		return fmt.Errorf("database driver %q does not support read replicas", cfg.Driver)
+	}
+
+	healthInterval := 10 * time.Second
+	if seconds, err := strconv.Atoi(os.Getenv("DATABASE_REPLICA_HEALTH_INTERVAL")); err == nil && seconds > 0 {
+		healthInterval = time.Duration(seconds) * time.Second
+	}
+
+	resolver := &Resolver{healthInterval: healthInterval}
+	for _, dsn := range cfg.Replicas {
+		// sql.Open does not connect, an unreachable replica is only marked unhealthy by the health check.
+		pool, err := sql.Open(driver.SQLDriver, dsn)
+		if err != nil {
+			return err
+		}
+		configurePool(pool, cfg)
+		resolver.replicas = append(resolver.replicas, &replica{pool: pool, healthy: -1})
+	}
+
+	fmt.Println(helper.ColorizeCmd(helper.Blue, fmt.Sprintf("Using %d read replica(s)", len(resolver.replicas))))
+	return db.Use(resolver)
+}
+
+// Name returns the name of the plugin.
+func (r *Resolver) Name() string {
+	return "goapify:replicas"
+}
+
+// Initialize installs the routing callbacks, checks the replicas once and starts the periodic health checks.
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.Config.ConnPool
+
+	if err := db.Callback().Query().Before("gorm:query").Register("goapify:replicas", r.route); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("goapify:replicas", r.route); err != nil {
+		return err
+	}
+
+	r.checkHealth()
+	go func() {
+		ticker := time.NewTicker(r.healthInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			r.checkHealth()
+		}
+	}()
+	return nil
+}
+
+// route switches the connection of a read query to a replica when the query is allowed to run on one.
+func (r *Resolver) route(db *gorm.DB) {
+	// Statements inside a transaction hold the transaction as their connection and must stay on it.
+	if db.Statement.ConnPool != r.primary {
+		return
+	}
+	if usesPrimary(db.Statement.Context) {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	if pool := r.pick(); pool != nil {
+		db.Statement.ConnPool = pool
+	}
+}
+
+// pick returns the next healthy replica, or nil if there is none.
+func (r *Resolver) pick() *sql.DB {
+	count := uint32(len(r.replicas))
+	start := atomic.AddUint32(&r.next, 1)
+	for i := uint32(0); i < count; i++ {
+		candidate := r.replicas[(start+i)%count]
+		if atomic.LoadInt32(&candidate.healthy) == 1 {
+			return candidate.pool
+		}
+	}
+	return nil
+}
+
+// checkHealth pings every replica and records whether it is reachable, logging every change.
+func (r *Resolver) checkHealth() {
+	for i, replica := range r.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := replica.pool.PingContext(ctx)
+		cancel()
+
+		healthy := int32(0)
+		if err == nil {
+			healthy = 1
+		}
+		if atomic.SwapInt32(&replica.healthy, healthy) == healthy {
+			continue
+		}
+		if err != nil {
+			fmt.Println(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Read replica #%d is unavailable, reading from the primary instead: %s", i+1, err)))
+		} else {
+			fmt.Println(helper.ColorizeCmd(helper.Green, fmt.Sprintf("Read replica #%d is available.", i+1)))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// origin is the table of the replica tests, every database holds a single row naming itself.
+type origin struct {
+	ID   uint
+	Name string
+}
+
+// openOrigin creates the SQLite database at the given path with a single origin row of the given name.
+func openOrigin(t *testing.T, path string, name string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&origin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&origin{ID: 1, Name: name}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestReplicaRouting(t *testing.T) {
+	dir := t.TempDir()
+	replicaPath := filepath.Join(dir, "replica.db")
+	openOrigin(t, replicaPath, "replica")
+	db := openOrigin(t, filepath.Join(dir, "primary.db"), "primary")
+	if err := UseReplicas(db, Config{Driver: "sqlite", Replicas: []string{replicaPath}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		read func(db *gorm.DB) (string, error)
+		want string
+	}{
+		{"find", func(db *gorm.DB) (string, error) {
+			var o origin
+			err := db.First(&o, 1).Error
+			return o.Name, err
+		}, "replica"},
+		{"raw scan", func(db *gorm.DB) (string, error) {
+			var name string
+			err := db.Raw("SELECT name FROM origins WHERE id = 1").Scan(&name).Error
+			return name, err
+		}, "replica"},
+		{"on primary", func(db *gorm.DB) (string, error) {
+			var o origin
+			err := OnPrimary(db).Session(&gorm.Session{}).First(&o, 1).Error
+			return o.Name, err
+		}, "primary"},
+		{"locking read", func(db *gorm.DB) (string, error) {
+			var o origin
+			err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&o, 1).Error
+			return o.Name, err
+		}, "primary"},
+		{"inside a transaction", func(db *gorm.DB) (string, error) {
+			var o origin
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return tx.First(&o, 1).Error
+			})
+			return o.Name, err
+		}, "primary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("read from the %s database, want the %s one", got, tt.want)
+			}
+		})
+	}
+
+	// Writes always go to the primary.
+	if err := db.Create(&origin{ID: 2, Name: "written"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := OnPrimary(db).Model(&origin{}).Count(&count).Error; err != nil || count != 2 {
+		t.Errorf("%d rows on the primary after the write, want 2 (%v)", count, err)
+	}
+}
+
+func TestReplicaFallback(t *testing.T) {
+	dir := t.TempDir()
+	db := openOrigin(t, filepath.Join(dir, "primary.db"), "primary")
+	unreachable := "file:" + filepath.Join(dir, "missing", "replica.db") + "?mode=ro"
+	if err := UseReplicas(db, Config{Driver: "sqlite", Replicas: []string{unreachable}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var o origin
+	if err := db.First(&o, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "primary" {
+		t.Errorf("read from the %s database while the replica is down, want the primary", o.Name)
+	}
+}
+
+func TestUseReplicasErrors(t *testing.T) {
+	RegisterDriver(Driver{Dialector: sqliteDialector}, "test-without-sql-driver")
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"unknown driver", Config{Driver: "oracle", Replicas: []string{"x"}}},
+		{"driver without database/sql driver", Config{Driver: "test-without-sql-driver", Replicas: []string{"x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openOrigin(t, filepath.Join(t.TempDir(), "primary.db"), "primary")
+			if err := UseReplicas(db, tt.cfg); err == nil {
+				t.Error("UseReplicas succeeded")
+			}
+		})
+	}
+
+	db := openOrigin(t, filepath.Join(t.TempDir(), "primary.db"), "primary")
+	if err := UseReplicas(db, Config{Driver: "sqlite"}); err != nil {
+		t.Errorf("UseReplicas without replicas = %v, want nil", err)
+	}
+}
@@ -1,17 +1,84 @@
-// Package math provides encryption and decryption functions using the AES block cipher in CFB mode.
+// Package math provides encryption and decryption functions using the AES block cipher in CFB mode,
+// and authenticated encryption with AES-256-GCM under keys derived from APP_KEY.
 package math
 
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 )
 
+// ErrMissingAppKey is returned when a key derived from APP_KEY is needed while APP_KEY is not set.
+var ErrMissingAppKey = errors.New("APP_KEY is not set, run `apify key` to generate it")
+
+// deriveKey returns a 32 byte key for the given purpose derived from APP_KEY,
+// so every use of the application key works with an independent key.
+func deriveKey(purpose string) ([]byte, error) {
+	secret := os.Getenv("APP_KEY")
+	if secret == "" {
+		return nil, ErrMissingAppKey
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil), nil
+}
+
+// Seal encrypts and authenticates the plaintext with AES-256-GCM and returns the random nonce followed by
+// the ciphertext as a base64-encoded string. The key is derived from APP_KEY, which can be of any length.
+// Unlike Encrypt, a sealed value that has been tampered with is rejected by Unseal.
+func Seal(plaintext []byte) (string, error) {
+	aead, err := sealCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Unseal decrypts a value created by Seal. It returns an error when the value is not a sealed value,
+// has been tampered with, or was sealed with another APP_KEY.
+func Unseal(sealed string) ([]byte, error) {
+	aead, err := sealCipher()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed value: %w", err)
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("invalid sealed value: too short")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("invalid sealed value: authentication failed")
+	}
+	return plaintext, nil
+}
+
+// sealCipher returns the AES-256-GCM cipher used by Seal and Unseal.
+func sealCipher() (cipher.AEAD, error) {
+	key, err := deriveKey("goapify:seal")
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 // Encrypt takes a plaintext string and returns the encrypted ciphertext as a base64-encoded string.
 // It uses the AES block cipher in CFB mode with a random initialization vector and the APP_KEY environment variable as the encryption key.
 func Encrypt(text string) (string, error) {
@@ -0,0 +1,69 @@
+package math
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	t.Setenv("APP_KEY", "key")
+	sealed, err := Seal([]byte("123-45-6789"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Seal([]byte("123-45-6789"))
+	if sealed == again {
+		t.Error("Seal returned the same value twice, want a random nonce")
+	}
+	tampered := []byte(sealed)
+	tampered[len(tampered)-3] ^= 1
+
+	tests := []struct {
+		name    string
+		key     string
+		sealed  string
+		want    string
+		wantErr string
+	}{
+		{"same key", "key", sealed, "123-45-6789", ""},
+		{"second seal", "key", again, "123-45-6789", ""},
+		{"empty plaintext", "key", mustSeal(t, ""), "", ""},
+		{"other key", "other", sealed, "", "authentication failed"},
+		{"tampered", "key", string(tampered), "", "authentication failed"},
+		{"not base64", "key", "not base64!", "", "invalid sealed value"},
+		{"too short", "key", base64.StdEncoding.EncodeToString([]byte("short")), "", "too short"},
+		{"missing key", "", sealed, "", ErrMissingAppKey.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_KEY", tt.key)
+			plaintext, err := Unseal(tt.sealed)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Unseal error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(plaintext) != tt.want {
+				t.Errorf("Unseal = %q, %v, want %q", plaintext, err, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("APP_KEY", "")
+	if _, err := Seal([]byte("x")); !errors.Is(err, ErrMissingAppKey) {
+		t.Errorf("Seal without APP_KEY = %v, want ErrMissingAppKey", err)
+	}
+}
+
+// mustSeal seals the given text under the current APP_KEY.
+func mustSeal(t *testing.T, text string) string {
+	t.Helper()
+	sealed, err := Seal([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
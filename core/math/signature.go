@@ -0,0 +1,40 @@
+package math
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Sign returns an HMAC-SHA256 signature of the message with a key derived from APP_KEY.
+// It is used to make values handed out to clients, such as pagination cursors, tamper-evident.
+// It returns ErrMissingAppKey when APP_KEY is not set, since anyone could forge a signature without a key.
+func Sign(message []byte) ([]byte, error) {
+	key, err := deriveKey("goapify:signature")
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// VerifySignature reports whether the signature is a valid signature of the message created by Sign.
+// The comparison is done in constant time. No signature is valid when APP_KEY is not set.
+func VerifySignature(message []byte, signature []byte) bool {
+	expected, err := Sign(message)
+	return err == nil && hmac.Equal(expected, signature)
+}
+
+// BlindIndex returns a keyed HMAC-SHA256 hash of the value as 64 hexadecimal characters, for equality lookups on
+// an encrypted column without decrypting it. The key is derived from APP_KEY and from scope, usually the name of
+// the column, so equal values in different columns get different indexes.
+func BlindIndex(scope string, value []byte) (string, error) {
+	key, err := deriveKey("goapify:blind_index:" + scope)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,55 @@
+package math
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name     string
+		signKey  string
+		checkKey string
+		message  string
+		checked  string
+		wantErr  error
+		wantOK   bool
+	}{
+		{"same key and message", "key", "key", "cursor", "cursor", nil, true},
+		{"other message", "key", "key", "cursor", "cursor2", nil, false},
+		{"other key", "key", "other", "cursor", "cursor", nil, false},
+		{"missing key", "", "", "cursor", "cursor", ErrMissingAppKey, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_KEY", tt.signKey)
+			signature, err := Sign([]byte(tt.message))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sign error = %v, want %v", err, tt.wantErr)
+			}
+			t.Setenv("APP_KEY", tt.checkKey)
+			if got := VerifySignature([]byte(tt.checked), signature); got != tt.wantOK {
+				t.Errorf("VerifySignature = %v, want %v", got, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	t.Setenv("APP_KEY", "key")
+	email, _ := BlindIndex("email", []byte("alice@example.com"))
+	again, _ := BlindIndex("email", []byte("alice@example.com"))
+	phone, _ := BlindIndex("phone", []byte("alice@example.com"))
+	if email != again || len(email) != 64 {
+		t.Errorf("BlindIndex is not a stable 64 character hash: %q, %q", email, again)
+	}
+	if email == phone {
+		t.Error("BlindIndex gives the same index in different scopes")
+	}
+
+	t.Setenv("APP_KEY", "")
+	if _, err := BlindIndex("email", []byte("alice@example.com")); !errors.Is(err, ErrMissingAppKey) {
+		t.Errorf("BlindIndex without APP_KEY = %v, want ErrMissingAppKey", err)
+	}
+}
@@ -0,0 +1,75 @@
+package math
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet used by ULIDs, without I, L, O and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator remembers the last ULID generated, to keep the ULIDs of the same millisecond increasing.
+var ulidGenerator struct {
+	mu      sync.Mutex
+	time    uint64
+	entropy [10]byte
+}
+
+// NewULID returns a new ULID: a 26 character, lexicographically sortable identifier made of a 48 bit millisecond
+// timestamp followed by 80 random bits, see https://github.com/ulid/spec. ULIDs generated in the same millisecond
+// by this process increment the random part of the previous one, so they always sort in the order they were generated.
+func NewULID() string {
+	g := &ulidGenerator
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// A clock that goes back is ignored, the ULIDs keep counting from the last millisecond.
+	if now := uint64(time.Now().UnixMilli()); now > g.time {
+		g.time = now
+		readEntropy(&g.entropy)
+	} else if !incrementEntropy(&g.entropy) {
+		// The random part of the millisecond is exhausted, move on to the next one.
+		g.time++
+		readEntropy(&g.entropy)
+	}
+
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:2], uint16(g.time>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(g.time))
+	copy(id[6:], g.entropy[:])
+	return encodeULID(id)
+}
+
+// readEntropy fills the random part of a ULID.
+func readEntropy(entropy *[10]byte) {
+	if _, err := rand.Read(entropy[:]); err != nil {
+		panic("math: cannot read random bytes for a ULID: " + err.Error())
+	}
+}
+
+// incrementEntropy adds one to the random part of a ULID and reports false when it overflows.
+func incrementEntropy(entropy *[10]byte) bool {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID encodes the 128 bits of a ULID as 26 Crockford base32 characters, 5 bits per character
+// starting from the most significant bits, the first character holding only 3 bits.
+func encodeULID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
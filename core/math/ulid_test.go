@@ -0,0 +1,89 @@
+package math
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewULID(t *testing.T) {
+	before := uint64(time.Now().UnixMilli())
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = NewULID()
+	}
+	after := uint64(time.Now().UnixMilli())
+
+	seen := map[string]bool{}
+	for i, id := range ids {
+		if len(id) != 26 || strings.Trim(id, crockford) != "" {
+			t.Fatalf("NewULID() = %q, want 26 Crockford base32 characters", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewULID() returned %q twice", id)
+		}
+		seen[id] = true
+		if i > 0 && id <= ids[i-1] {
+			t.Errorf("ULID %q sorts before the previous one %q", id, ids[i-1])
+		}
+		// The first 10 characters hold the 48 bit timestamp, which is at most one millisecond ahead of the clock
+		// when the random part of a millisecond overflows.
+		if ms := decodeTime(id); ms < before || ms > after+1 {
+			t.Errorf("timestamp of %q = %d, want between %d and %d", id, ms, before, after)
+		}
+	}
+}
+
+// decodeTime returns the millisecond timestamp of a ULID.
+func decodeTime(id string) uint64 {
+	var ms uint64
+	for _, c := range id[:10] {
+		ms = ms<<5 | uint64(strings.IndexRune(crockford, c))
+	}
+	return ms
+}
+
+func TestEncodeULID(t *testing.T) {
+	tests := []struct {
+		time    uint64
+		entropy [10]byte
+		want    string
+	}{
+		{0, [10]byte{}, "00000000000000000000000000"},
+		{1, [10]byte{}, "00000000010000000000000000"},
+		{0, [10]byte{9: 1}, "00000000000000000000000001"},
+		{0, [10]byte{9: 32}, "00000000000000000000000010"},
+		{1<<48 - 1, [10]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255}, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		// The example of the specification, 2016-07-30 23:54:10.259 UTC.
+		{1469922850259, [10]byte{}, "01ARZ3NDEK0000000000000000"},
+	}
+	for _, tt := range tests {
+		var id [16]byte
+		binary.BigEndian.PutUint16(id[0:2], uint16(tt.time>>32))
+		binary.BigEndian.PutUint32(id[2:6], uint32(tt.time))
+		copy(id[6:], tt.entropy[:])
+		if got := encodeULID(id); got != tt.want {
+			t.Errorf("encodeULID(%d, %v) = %q, want %q", tt.time, tt.entropy, got, tt.want)
+		}
+	}
+}
+
+func TestIncrementEntropy(t *testing.T) {
+	tests := []struct {
+		entropy [10]byte
+		want    [10]byte
+		wantOk  bool
+	}{
+		{[10]byte{}, [10]byte{9: 1}, true},
+		{[10]byte{9: 255}, [10]byte{8: 1}, true},
+		{[10]byte{0, 255, 255, 255, 255, 255, 255, 255, 255, 255}, [10]byte{1}, true},
+		{[10]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255}, [10]byte{}, false},
+	}
+	for _, tt := range tests {
+		entropy := tt.entropy
+		if ok := incrementEntropy(&entropy); entropy != tt.want || ok != tt.wantOk {
+			t.Errorf("incrementEntropy(%v) = %v, %v, want %v, %v", tt.entropy, entropy, ok, tt.want, tt.wantOk)
+		}
+	}
+}
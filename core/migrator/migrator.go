@@ -0,0 +1,338 @@
+// Package migrator provides versioned, reversible database migrations on top of GORM.
+// Each migration has a timestamped ID and a pair of Up/Down functions. Applied migrations
+// are recorded in the schema_migrations table together with the batch they ran in, so they
+// can be rolled back in the reverse order they were applied.
+package migrator
+
+import (
+	"GoAPIfy/core/database"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration describes a single schema or data change.
+// ID must be unique and sortable, the convention is a timestamp followed by a short name,
+// e.g. "20230301000000_create_users_table". Up applies the change and Down reverts it.
+// Connection is the name of the database connection the migration runs on, database.DefaultConnection when empty.
+type Migration struct {
+	ID         string
+	Connection string
+	Up         func(tx *gorm.DB) error
+	Down       func(tx *gorm.DB) error
+}
+
+// SchemaMigration is the record stored in the schema_migrations table for every applied migration.
+type SchemaMigration struct {
+	ID        uint      `gorm:"primaryKey"`
+	Migration string    `gorm:"size:255;uniqueIndex"`
+	Batch     int       `gorm:"index"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName returns the name of the table used to record applied migrations.
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes whether a known migration has been applied, and in which batch.
+type Status struct {
+	ID        string
+	Applied   bool
+	Batch     int
+	AppliedAt *time.Time
+}
+
+// Migrator runs a set of migrations against a database connection.
+type Migrator struct {
+	db         *gorm.DB
+	connection string
+	migrations []*Migration
+}
+
+// New creates a new Migrator for the default database connection and the given migrations.
+// It is a shortcut for NewForConnection with database.DefaultConnection.
+func New(db *gorm.DB, migrations []*Migration) *Migrator {
+	return NewForConnection(database.DefaultConnection, db, migrations)
+}
+
+// NewForConnection creates a new Migrator running, on the given database connection, the migrations
+// whose Connection is the given name. Every connection records its applied migrations in its own schema_migrations table.
+// The migrations are sorted by ID, so the order they are passed in does not matter.
+// Migrations always run on the primary connection, also when read replicas are configured.
+// It panics if two migrations share the same ID, since that is always a programming error.
+//
+// Example usage:
+//
+//	legacy, err := connections.Get("legacy")
+//	ran, err := migrator.NewForConnection("legacy", legacy, migration.All()).Migrate()
+func NewForConnection(name string, db *gorm.DB, migrations []*Migration) *Migrator {
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].ID == sorted[i-1].ID {
+			panic(fmt.Sprintf("migrator: duplicate migration ID %q", sorted[i].ID))
+		}
+	}
+
+	var targeted []*Migration
+	for _, migration := range sorted {
+		if strings.EqualFold(connectionOf(migration), name) {
+			targeted = append(targeted, migration)
+		}
+	}
+
+	return &Migrator{db: database.OnPrimary(db), connection: name, migrations: targeted}
+}
+
+// Connections returns the names of the connections targeted by the given migrations, the default connection first.
+// The default connection is always included, so its schema is checked even when no migration targets it yet.
+func Connections(migrations []*Migration) []string {
+	names := []string{database.DefaultConnection}
+	seen := map[string]bool{database.DefaultConnection: true}
+	for _, migration := range migrations {
+		name := strings.ToLower(connectionOf(migration))
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Connection returns the name of the connection the migrator runs on.
+func (m *Migrator) Connection() string {
+	return m.connection
+}
+
+// connectionOf returns the name of the connection the migration runs on.
+func connectionOf(migration *Migration) string {
+	if migration.Connection == "" {
+		return database.DefaultConnection
+	}
+	return migration.Connection
+}
+
+// prepare makes sure the schema_migrations table exists.
+func (m *Migrator) prepare() error {
+	if m.db.Migrator().HasTable(&SchemaMigration{}) {
+		return nil
+	}
+	return m.db.Migrator().CreateTable(&SchemaMigration{})
+}
+
+// applied returns the applied migration records ordered by the order they were applied in.
+func (m *Migrator) applied() ([]SchemaMigration, error) {
+	if err := m.prepare(); err != nil {
+		return nil, err
+	}
+	var records []SchemaMigration
+	err := m.db.Order("batch ASC").Order("id ASC").Find(&records).Error
+	return records, err
+}
+
+// Pending returns the migrations that have not been applied yet, in the order they would run.
+func (m *Migrator) Pending() ([]*Migration, error) {
+	records, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	ran := make(map[string]bool, len(records))
+	for _, record := range records {
+		ran[record.Migration] = true
+	}
+
+	var pending []*Migration
+	for _, migration := range m.migrations {
+		if !ran[migration.ID] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration in a single new batch.
+// Each migration runs inside its own transaction together with its schema_migrations record,
+// so a failing migration leaves no record behind and stops the run.
+// It returns the IDs of the migrations that were applied.
+func (m *Migrator) Migrate() ([]string, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	batch, err := m.lastBatch()
+	if err != nil {
+		return nil, err
+	}
+	batch++
+
+	var ran []string
+	for _, migration := range pending {
+		if migration.Up == nil {
+			return ran, fmt.Errorf("migration %s has no Up function", migration.ID)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Migration: migration.ID, Batch: batch, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", migration.ID, err)
+		}
+		ran = append(ran, migration.ID)
+	}
+	return ran, nil
+}
+
+// Rollback reverts applied migrations in the reverse order they were applied.
+// If step is greater than zero, that many migrations are reverted; otherwise the whole last batch is reverted.
+// It returns the IDs of the migrations that were rolled back.
+func (m *Migrator) Rollback(step int) ([]string, error) {
+	records, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var targets []SchemaMigration
+	if step > 0 {
+		if step > len(records) {
+			step = len(records)
+		}
+		targets = records[len(records)-step:]
+	} else {
+		lastBatch := records[len(records)-1].Batch
+		for _, record := range records {
+			if record.Batch == lastBatch {
+				targets = append(targets, record)
+			}
+		}
+	}
+
+	known := make(map[string]*Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.ID] = migration
+	}
+
+	var reverted []string
+	for i := len(targets) - 1; i >= 0; i-- {
+		record := targets[i]
+		migration, ok := known[record.Migration]
+		if !ok {
+			return reverted, fmt.Errorf("migration %s has been applied but is not registered", record.Migration)
+		}
+		if migration.Down == nil {
+			return reverted, fmt.Errorf("migration %s has no Down function and cannot be rolled back", migration.ID)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, record.ID).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("rollback of %s failed: %w", migration.ID, err)
+		}
+		reverted = append(reverted, migration.ID)
+	}
+	return reverted, nil
+}
+
+// Status reports every registered migration and whether it has been applied.
+// Migrations that are recorded in the database but no longer registered are reported as well.
+func (m *Migrator) Status() ([]Status, error) {
+	records, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		byID[record.Migration] = record
+	}
+
+	var statuses []Status
+	for _, migration := range m.migrations {
+		status := Status{ID: migration.ID}
+		if record, ok := byID[migration.ID]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.Batch = record.Batch
+			status.AppliedAt = &appliedAt
+			delete(byID, migration.ID)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range records {
+		if _, ok := byID[record.Migration]; ok {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, Status{ID: record.Migration, Applied: true, Batch: record.Batch, AppliedAt: &appliedAt})
+		}
+	}
+	return statuses, nil
+}
+
+// Fresh drops every table in the database of the migrator, including schema_migrations, and runs all its migrations from scratch.
+// It is destructive and is meant for development databases only.
+func (m *Migrator) Fresh() ([]string, error) {
+	tables, err := m.db.Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	var drop []interface{}
+	for _, table := range tables {
+		// SQLite keeps internal bookkeeping tables that cannot be dropped.
+		if strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+		drop = append(drop, table)
+	}
+	if len(drop) > 0 {
+		if err := m.db.Migrator().DropTable(drop...); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.Migrate()
+}
+
+// EnsureUpToDate returns an error listing the pending migrations, if there are any.
+// It is used at startup in production to refuse booting against an outdated schema.
+func (m *Migrator) EnsureUpToDate() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(pending))
+	for i, migration := range pending {
+		ids[i] = migration.ID
+	}
+	return errors.New("pending migrations: " + strings.Join(ids, ", "))
+}
+
+// lastBatch returns the highest batch number recorded, or zero if nothing has run yet.
+func (m *Migrator) lastBatch() (int, error) {
+	var batch int
+	err := m.db.Model(&SchemaMigration{}).Select("COALESCE(MAX(batch), 0)").Scan(&batch).Error
+	return batch, err
+}
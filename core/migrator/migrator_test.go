@@ -0,0 +1,232 @@
+package migrator
+
+import (
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// createTable returns a migration creating, and dropping on rollback, a table with a single column.
+func createTable(id string, table string) *Migration {
+	return &Migration{
+		ID: id,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE " + table).Error
+		},
+	}
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+	tests := []struct {
+		name         string
+		batches      [][]*Migration
+		step         int
+		wantReverted []string
+		wantTables   []string
+	}{
+		{
+			name:         "rollback without step reverts the last batch",
+			batches:      [][]*Migration{{createTable("1_a", "a")}, {createTable("2_b", "b"), createTable("3_c", "c")}},
+			wantReverted: []string{"3_c", "2_b"},
+			wantTables:   []string{"a"},
+		},
+		{
+			name:         "rollback with step reverts across batches",
+			batches:      [][]*Migration{{createTable("1_a", "a")}, {createTable("2_b", "b")}},
+			step:         2,
+			wantReverted: []string{"2_b", "1_a"},
+		},
+		{
+			name:         "step larger than the applied migrations reverts all of them",
+			batches:      [][]*Migration{{createTable("1_a", "a"), createTable("2_b", "b")}},
+			step:         10,
+			wantReverted: []string{"2_b", "1_a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			var migrations []*Migration
+			for i, batch := range tt.batches {
+				migrations = append(migrations, batch...)
+				ran, err := New(db, migrations).Migrate()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(ran) != len(batch) {
+					t.Fatalf("batch %d ran %v", i+1, ran)
+				}
+			}
+
+			reverted, err := New(db, migrations).Rollback(tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(reverted, tt.wantReverted) {
+				t.Errorf("reverted %v, want %v", reverted, tt.wantReverted)
+			}
+			for _, table := range []string{"a", "b", "c"} {
+				want := false
+				for _, kept := range tt.wantTables {
+					want = want || kept == table
+				}
+				if got := db.Migrator().HasTable(table); got != want {
+					t.Errorf("table %s exists = %v, want %v", table, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateBatchesAndStatus(t *testing.T) {
+	db := testdb.Open(t)
+	first := []*Migration{createTable("2_b", "b"), createTable("1_a", "a")}
+	ran, err := New(db, first).Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1_a", "2_b"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want the migrations sorted by ID %v", ran, want)
+	}
+	if ran, err := New(db, first).Migrate(); err != nil || len(ran) != 0 {
+		t.Fatalf("second run ran %v, %v, want nothing", ran, err)
+	}
+
+	all := append(first, createTable("3_c", "c"))
+	if err := New(db, all).EnsureUpToDate(); err == nil || !strings.Contains(err.Error(), "3_c") {
+		t.Fatalf("EnsureUpToDate = %v, want the pending 3_c", err)
+	}
+	if _, err := New(db, all).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(db, all).EnsureUpToDate(); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := New(db, all).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBatches := map[string]int{"1_a": 1, "2_b": 1, "3_c": 2}
+	for _, status := range statuses {
+		if !status.Applied || status.Batch != wantBatches[status.ID] {
+			t.Errorf("status %s = applied %v batch %d, want batch %d", status.ID, status.Applied, status.Batch, wantBatches[status.ID])
+		}
+	}
+}
+
+func TestFailingMigration(t *testing.T) {
+	tests := []struct {
+		name      string
+		migration *Migration
+		wantErr   string
+	}{
+		{
+			name: "error of Up",
+			migration: &Migration{ID: "2_fail", Up: func(tx *gorm.DB) error {
+				if err := tx.Exec("CREATE TABLE partial (id INTEGER)").Error; err != nil {
+					return err
+				}
+				return errors.New("boom")
+			}},
+			wantErr: "migration 2_fail failed: boom",
+		},
+		{
+			name:      "missing Up",
+			migration: &Migration{ID: "2_fail"},
+			wantErr:   "migration 2_fail has no Up function",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			migrations := []*Migration{createTable("1_a", "a"), tt.migration, createTable("3_c", "c")}
+			ran, err := New(db, migrations).Migrate()
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Migrate error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ran, []string{"1_a"}) {
+				t.Errorf("ran %v, want only 1_a", ran)
+			}
+			if db.Migrator().HasTable("partial") || db.Migrator().HasTable("c") {
+				t.Error("the failed migration and the following ones left tables behind")
+			}
+			pending, err := New(db, migrations).Pending()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pending) != 2 || pending[0].ID != "2_fail" {
+				t.Errorf("pending %d migrations starting with %s, want 2_fail and 3_c", len(pending), pending[0].ID)
+			}
+		})
+	}
+}
+
+func TestRollbackErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []*Migration
+		wantErr    string
+	}{
+		{
+			name:       "migration without Down",
+			migrations: []*Migration{{ID: "1_a", Up: func(tx *gorm.DB) error { return nil }}},
+			wantErr:    "migration 1_a has no Down function and cannot be rolled back",
+		},
+		{
+			name:       "applied migration that is no longer registered",
+			migrations: nil,
+			wantErr:    "migration 1_a has been applied but is not registered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			if _, err := New(db, []*Migration{{ID: "1_a", Up: func(tx *gorm.DB) error { return nil }}}).Migrate(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := New(db, tt.migrations).Rollback(0); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Rollback error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnections(t *testing.T) {
+	migrations := []*Migration{
+		createTable("1_a", "a"),
+		{ID: "2_b", Connection: "Legacy", Up: createTable("2_b", "b").Up},
+		{ID: "3_c", Connection: "archive", Up: createTable("3_c", "c").Up},
+	}
+	if got, want := Connections(migrations), []string{"main", "archive", "legacy"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Connections = %v, want %v", got, want)
+	}
+
+	db := testdb.Open(t)
+	ran, err := NewForConnection("legacy", db, migrations).Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ran, []string{"2_b"}) {
+		t.Errorf("the legacy connection ran %v, want only 2_b", ran)
+	}
+}
+
+func TestDuplicateIDPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New did not panic on a duplicate migration ID")
+		}
+	}()
+	New(testdb.Open(t), []*Migration{createTable("1_a", "a"), createTable("1_a", "b")})
+}
@@ -0,0 +1,217 @@
+// Package query turns list endpoint query strings into safe GORM query scopes.
+// It understands the following parameters:
+//
+//	?filter[name]=john                  equality filter
+//	?filter[created_at][gte]=2023-01-01 filter with an operator (eq, neq, gt, gte, lt, lte, like, in, null)
+//	?sort=-created_at,name              sort columns, "-" sorts descending
+//	?include=posts                      eager loaded relations
+//	?fields=id,name                     selected columns
+//
+// Every entity declares which columns and relations may be used in an Options allowlist.
+// Anything outside the allowlist is rejected with an error, and column names are always quoted,
+// so query strings can never inject SQL.
+//
+// Example usage:
+//
+//	q, err := query.Parse(c, userQueryOptions)
+//	if err != nil {
+//		core.SendResponse(c, http.StatusBadRequest, core.FormatError(err))
+//		return
+//	}
+//	pagination, err := users.ApplyScope(q.Scope()).Paginate(page, perPage)
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options is the allowlist of an entity.
+// Filterable, Sortable and Selectable hold column names. Includable maps the name used in the query string
+// to the GORM relation name, e.g. "posts" to "Posts". DefaultSort is used when the request has no sort parameter.
+type Options struct {
+	Filterable  []string
+	Sortable    []string
+	Selectable  []string
+	Includable  map[string]string
+	DefaultSort string
+}
+
+// Filter is a single validated filter condition.
+type Filter struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+// Sort is a single validated sort column.
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Query is the validated result of parsing a query string.
+type Query struct {
+	Filters  []Filter
+	Sorts    []Sort
+	Includes []string
+	Fields   []string
+}
+
+// operators lists the supported filter operators.
+var operators = map[string]bool{
+	"eq": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true, "like": true, "in": true, "null": true,
+}
+
+var filterKey = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// Parse reads the filter, sort, include and fields parameters of the request and validates them against the options.
+// It returns an error describing the first parameter that is not allowed.
+func Parse(c *gin.Context, options Options) (*Query, error) {
+	values := c.Request.URL.Query()
+	q := &Query{}
+
+	// Sort the keys so filters are applied, and errors reported, in a stable order.
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter") {
+			continue
+		}
+		match := filterKey.FindStringSubmatch(key)
+		if match == nil {
+			return nil, fmt.Errorf("invalid filter parameter %q", key)
+		}
+		column, operator := match[1], match[2]
+		if operator == "" {
+			operator = "eq"
+		}
+		if !contains(options.Filterable, column) {
+			return nil, fmt.Errorf("filtering by %q is not allowed", column)
+		}
+		if !operators[operator] {
+			return nil, fmt.Errorf("unknown filter operator %q", operator)
+		}
+		value := values.Get(key)
+		if operator == "null" && value != "true" && value != "false" {
+			return nil, fmt.Errorf("filter[%s][null] must be true or false", column)
+		}
+		q.Filters = append(q.Filters, Filter{Column: column, Operator: operator, Value: value})
+	}
+
+	sortParam := c.Query("sort")
+	if sortParam == "" {
+		sortParam = options.DefaultSort
+	}
+	for _, column := range splitList(sortParam) {
+		desc := strings.HasPrefix(column, "-")
+		column = strings.TrimPrefix(column, "-")
+		if !contains(options.Sortable, column) {
+			return nil, fmt.Errorf("sorting by %q is not allowed", column)
+		}
+		q.Sorts = append(q.Sorts, Sort{Column: column, Desc: desc})
+	}
+
+	for _, include := range splitList(c.Query("include")) {
+		relation, ok := options.Includable[include]
+		if !ok {
+			return nil, fmt.Errorf("including %q is not allowed", include)
+		}
+		q.Includes = append(q.Includes, relation)
+	}
+
+	for _, field := range splitList(c.Query("fields")) {
+		if !contains(options.Selectable, field) {
+			return nil, fmt.Errorf("selecting %q is not allowed", field)
+		}
+		q.Fields = append(q.Fields, field)
+	}
+
+	return q, nil
+}
+
+// Scope returns a scope function that applies the query to a *gorm.DB.
+// It can be passed to the ApplyScope method of a model or repository.
+func (q *Query) Scope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, filter := range q.Filters {
+			db = db.Where(filter.Expression())
+		}
+		for _, sort := range q.Sorts {
+			db = db.Order(clause.OrderByColumn{Column: column(sort.Column), Desc: sort.Desc})
+		}
+		for _, relation := range q.Includes {
+			db = db.Preload(relation)
+		}
+		if len(q.Fields) > 0 {
+			db = db.Select(q.Fields)
+		}
+		return db
+	}
+}
+
+// Expression returns the filter as a GORM clause expression with a quoted column name and bound value.
+func (f Filter) Expression() clause.Expression {
+	col := column(f.Column)
+	switch f.Operator {
+	case "neq":
+		return clause.Neq{Column: col, Value: f.Value}
+	case "gt":
+		return clause.Gt{Column: col, Value: f.Value}
+	case "gte":
+		return clause.Gte{Column: col, Value: f.Value}
+	case "lt":
+		return clause.Lt{Column: col, Value: f.Value}
+	case "lte":
+		return clause.Lte{Column: col, Value: f.Value}
+	case "like":
+		return clause.Like{Column: col, Value: "%" + f.Value + "%"}
+	case "in":
+		var values []interface{}
+		for _, value := range splitList(f.Value) {
+			values = append(values, value)
+		}
+		return clause.IN{Column: col, Values: values}
+	case "null":
+		if f.Value == "true" {
+			return clause.Eq{Column: col, Value: nil}
+		}
+		return clause.Neq{Column: col, Value: nil}
+	default:
+		return clause.Eq{Column: col, Value: f.Value}
+	}
+}
+
+// column qualifies a column name with the table of the current model, so filters keep working with joins.
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package query
+
+import (
+	"GoAPIfy/internal/testdb"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testOptions is the allowlist of the tests.
+var testOptions = Options{
+	Filterable:  []string{"name", "age", "deleted"},
+	Sortable:    []string{"name", "age"},
+	Selectable:  []string{"id", "name"},
+	Includable:  map[string]string{"pets": "Pets"},
+	DefaultSort: "-age",
+}
+
+// parse parses the given query string against testOptions.
+func parse(rawQuery string) (*Query, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/people?"+rawQuery, nil)
+	return Parse(c, testOptions)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *Query
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  &Query{Sorts: []Sort{{Column: "age", Desc: true}}},
+		},
+		{
+			name:  "every parameter",
+			query: "filter[name]=ann&filter[age][gte]=30&sort=name,-age&include=pets&fields=id,name",
+			want: &Query{
+				Filters:  []Filter{{Column: "age", Operator: "gte", Value: "30"}, {Column: "name", Operator: "eq", Value: "ann"}},
+				Sorts:    []Sort{{Column: "name"}, {Column: "age", Desc: true}},
+				Includes: []string{"Pets"},
+				Fields:   []string{"id", "name"},
+			},
+		},
+		{name: "filter on a column that is not allowed", query: "filter[email]=a@b.c", wantErr: `filtering by "email" is not allowed`},
+		{name: "unknown operator", query: "filter[age][regex]=1", wantErr: `unknown filter operator "regex"`},
+		{name: "malformed filter", query: "filter[age]]=1", wantErr: `invalid filter parameter "filter[age]]"`},
+		{name: "invalid null filter", query: "filter[deleted][null]=yes", wantErr: "filter[deleted][null] must be true or false"},
+		{name: "sort on a column that is not allowed", query: "sort=-email", wantErr: `sorting by "email" is not allowed`},
+		{name: "include that is not allowed", query: "include=Pets", wantErr: `including "Pets" is not allowed`},
+		{name: "field that is not allowed", query: "fields=id,password", wantErr: `selecting "password" is not allowed`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parse(tt.query)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(q, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", q, tt.want)
+			}
+		})
+	}
+}
+
+// person is the model the scopes of the tests run on.
+type person struct {
+	ID      uint
+	Name    string
+	Age     int
+	Deleted *bool
+}
+
+func TestScope(t *testing.T) {
+	db := testdb.Open(t, &person{})
+	yes := true
+	people := []person{{Name: "ann", Age: 30}, {Name: "bob", Age: 40, Deleted: &yes}, {Name: "anna", Age: 20}, {Name: "carl", Age: 40}}
+	if err := db.Create(&people).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"filter[age][lt]=40", "ann,anna"},
+		{"sort=age,name", "anna,ann,bob,carl"},
+		{"filter[name]=ann", "ann"},
+		{"filter[name][neq]=ann&sort=name", "anna,bob,carl"},
+		{"filter[name][like]=ann&sort=name", "ann,anna"},
+		{"filter[age][gt]=20&filter[age][lt]=40", "ann"},
+		{"filter[age][lte]=30&sort=age", "anna,ann"},
+		{"filter[name][in]=bob,carl&sort=name", "bob,carl"},
+		{"filter[deleted][null]=false", "bob"},
+		{"filter[deleted][null]=true&sort=name", "ann,anna,carl"},
+		{"filter[name]=ann%27%20OR%20%271%27%3D%271", ""},
+		{"fields=id,name&filter[name]=carl", "carl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var found []person
+			if err := db.Model(&person{}).Scopes(q.Scope()).Find(&found).Error; err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, p := range found {
+				names = append(names, p.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,10 +4,14 @@
 package core
 
 import (
+	"GoAPIfy/core/helper"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
 // Response represents the structure of the JSON response
@@ -99,6 +103,48 @@ func SendResponse(c *gin.Context, status int, response interface{}) {
 	c.AbortWithStatusJSON(status, data)
 }
 
+// StatusError is implemented by errors that are answered with a specific HTTP status code, such as model.StaleModelError.
+type StatusError interface {
+	error
+	StatusCode() int
+}
+
+// ErrorStatus returns the HTTP status code an error is answered with.
+// Errors implementing StatusError decide for themselves, gorm.ErrRecordNotFound is answered with 404 Not Found,
+// and every other error with 500 Internal Server Error.
+func ErrorStatus(err error) int {
+	var statusError StatusError
+	if errors.As(err, &statusError) {
+		return statusError.StatusCode()
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// SendError sends an error response with the status code returned by ErrorStatus.
+// A stale model is answered with 409 Conflict, so the client knows it has to reload the record before trying again.
+// The text of errors implementing StatusError and of not found errors is sent to the client. Every other error is
+// answered with a generic message and printed instead, since its text comes from the database driver and can
+// reveal SQL, table names or connection settings.
+//
+// Example usage:
+//
+//	if err := s.Model.Load(&article).Save(); err != nil {
+//		core.SendError(c, err)
+//		return
+//	}
+func SendError(c *gin.Context, err error) {
+	status := ErrorStatus(err)
+	var statusError StatusError
+	if status >= http.StatusInternalServerError && !errors.As(err, &statusError) {
+		fmt.Println(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Request failed with %d %s: %s", status, http.StatusText(status), err)))
+		err = errors.New(http.StatusText(status))
+	}
+	SendResponse(c, status, FormatError(err))
+}
+
 // FormatError takes in an error object and returns a Gin H object with an "errors" key containing a slice of error messages.
 // If the error object is a validator.ValidationErrors object, it will extract the error messages from each error in the object.
 // If the error object is not a validator.ValidationErrors object, it will simply return a Gin H object with a single error message.
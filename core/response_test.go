@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// conflictError is a StatusError answered with 409 Conflict.
+type conflictError struct{}
+
+func (conflictError) Error() string   { return "conflict" }
+func (conflictError) StatusCode() int { return http.StatusConflict }
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"status error", conflictError{}, http.StatusConflict},
+		{"wrapped status error", fmt.Errorf("saving: %w", conflictError{}), http.StatusConflict},
+		{"record not found", gorm.ErrRecordNotFound, http.StatusNotFound},
+		{"wrapped record not found", fmt.Errorf("loading: %w", gorm.ErrRecordNotFound), http.StatusNotFound},
+		{"any other error", errors.New("database is down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorStatus(tt.err); got != tt.want {
+				t.Errorf("ErrorStatus = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// unavailableError is a StatusError answered with 503 Service Unavailable.
+type unavailableError struct{}
+
+func (unavailableError) Error() string   { return "search is down" }
+func (unavailableError) StatusCode() int { return http.StatusServiceUnavailable }
+
+func TestSendError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantPrinted bool
+	}{
+		{"status error", conflictError{}, http.StatusConflict, "conflict", false},
+		{"server status error", unavailableError{}, http.StatusServiceUnavailable, "search is down", false},
+		{"record not found", fmt.Errorf("loading: %w", gorm.ErrRecordNotFound), http.StatusNotFound, "loading: record not found", false},
+		{"driver error", errors.New("Error 1146: Table 'shop.users' doesn't exist"), http.StatusInternalServerError, "Internal Server Error", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			printed := captureStdout(t, func() { SendError(c, tt.err) })
+
+			if recorder.Code != tt.wantStatus || !c.IsAborted() {
+				t.Errorf("status = %d and aborted: %v, want an aborted %d", recorder.Code, c.IsAborted(), tt.wantStatus)
+			}
+			var response struct {
+				Meta Meta
+				Data struct{ Errors []string }
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Meta.Code != tt.wantStatus || response.Meta.Status != http.StatusText(tt.wantStatus) {
+				t.Errorf("meta = %+v, want %d", response.Meta, tt.wantStatus)
+			}
+			if len(response.Data.Errors) != 1 || response.Data.Errors[0] != tt.wantMessage {
+				t.Errorf("errors = %q, want %q", response.Data.Errors, tt.wantMessage)
+			}
+			if reported := strings.Contains(printed, tt.err.Error()); reported != tt.wantPrinted {
+				t.Errorf("printed %q, want the error printed: %v", printed, tt.wantPrinted)
+			}
+		})
+	}
+}
+
+// captureStdout returns what fn prints on the standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		var buffer bytes.Buffer
+		_, _ = io.Copy(&buffer, reader)
+		printed <- buffer.String()
+	}()
+	fn()
+	writer.Close()
+	return <-printed
+}
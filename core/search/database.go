@@ -0,0 +1,209 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// Database is the Engine that stores the documents in the application database, in the search_documents table,
+// for applications that do not run a search server. Changes are queued and written in batches like the Meilisearch
+// driver. The text search depends on the database:
+//   - PostgreSQL matches the words with a tsvector, backed by a GIN index, and ranks the results with ts_rank.
+//   - SQLite uses an FTS5 table ranked by bm25 when the driver is built with FTS5 (go build -tags sqlite_fts5),
+//     and LIKE otherwise.
+//   - Other databases use LIKE, without ranking.
+//
+// Every word of the search text must appear in a document, as a word or the start of one; LIKE also matches it
+// inside a longer word. Filters and sorting are evaluated in the application on the matching documents,
+// so this driver suits small and medium indexes.
+type Database struct {
+	*queue
+	db   *gorm.DB
+	mode string
+}
+
+// searchDocument is a document stored by the database driver.
+type searchDocument struct {
+	ID         uint   `gorm:"primaryKey"`
+	IndexName  string `gorm:"size:191;not null;uniqueIndex:idx_search_documents_document"`
+	DocumentID string `gorm:"size:191;not null;uniqueIndex:idx_search_documents_document"`
+	Content    string `gorm:"type:text"`
+	Attributes string `gorm:"type:text"`
+}
+
+// TableName returns the table the documents are stored in.
+func (searchDocument) TableName() string {
+	return "search_documents"
+}
+
+// Text search modes of the database driver.
+const (
+	modeLike     = "like"
+	modeTSVector = "tsvector"
+	modeFTS5     = "fts5"
+)
+
+// ftsTable is the SQLite FTS5 table indexing the content of the documents, by the ID of their row.
+const ftsTable = "search_documents_fts"
+
+// NewDatabase creates a new database engine and starts writing the queued changes. The search_documents table and
+// its text index are created by the migrations, run them first.
+func NewDatabase(db *gorm.DB) (*Database, error) {
+	d := &Database{db: db, mode: modeLike}
+	if !db.Migrator().HasTable(&searchDocument{}) {
+		return nil, errors.New("the search_documents table of the database search driver does not exist, run the migrations first")
+	}
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		d.mode = modeTSVector
+	case "sqlite":
+		// The FTS5 table is only created by a driver built with FTS5, and reading it fails with "no such module: fts5"
+		// on a driver built without it.
+		probe := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+		if probe.Exec("SELECT rowid FROM "+ftsTable+" LIMIT 0").Error == nil {
+			d.mode = modeFTS5
+		}
+	}
+
+	d.queue = newQueue(d.send)
+	return d, nil
+}
+
+// send writes the queued changes of a single index in one transaction.
+func (d *Database) send(b *batch) error {
+	documents := make([]searchDocument, 0, len(b.upserts))
+	changed := make([]string, 0, len(b.upserts)+len(b.deletes))
+	for id, document := range b.upserts {
+		attributes, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, searchDocument{
+			IndexName:  b.index.Name,
+			DocumentID: id,
+			Content:    strings.ToLower(content(document)),
+			Attributes: string(attributes),
+		})
+		changed = append(changed, id)
+	}
+	deleted := make([]string, 0, len(b.deletes))
+	for id := range b.deletes {
+		deleted = append(deleted, id)
+		changed = append(changed, id)
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		// The FTS5 rows of every changed document are removed first and rebuilt from the upserted rows.
+		if d.mode == modeFTS5 {
+			err := chunk(changed, d.batchSize, func(ids []string) error {
+				rows := tx.Model(&searchDocument{}).Select("id").Where("index_name = ? AND document_id IN ?", b.index.Name, ids)
+				return tx.Exec("DELETE FROM "+ftsTable+" WHERE rowid IN (?)", rows).Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(documents) > 0 {
+			upsert := clause.OnConflict{
+				Columns:   []clause.Column{{Name: "index_name"}, {Name: "document_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"content", "attributes"}),
+			}
+			if err := tx.Clauses(upsert).CreateInBatches(&documents, d.batchSize).Error; err != nil {
+				return err
+			}
+		}
+		err := chunk(deleted, d.batchSize, func(ids []string) error {
+			return tx.Where("index_name = ? AND document_id IN ?", b.index.Name, ids).Delete(&searchDocument{}).Error
+		})
+		if err != nil {
+			return err
+		}
+
+		if d.mode == modeFTS5 {
+			upserted := make([]string, 0, len(documents))
+			for _, document := range documents {
+				upserted = append(upserted, document.DocumentID)
+			}
+			return chunk(upserted, d.batchSize, func(ids []string) error {
+				rows := tx.Model(&searchDocument{}).Select("id, content").Where("index_name = ? AND document_id IN ?", b.index.Name, ids)
+				return tx.Exec("INSERT INTO "+ftsTable+" (rowid, content) ?", rows).Error
+			})
+		}
+		return nil
+	})
+}
+
+// Search runs the given query and returns the IDs of the matching documents by relevance,
+// together with the total number of matches.
+func (d *Database) Search(query Query) ([]string, int64, error) {
+	words := terms(query.Text)
+	rows := d.db.Model(&searchDocument{}).
+		Select("search_documents.document_id, search_documents.attributes").
+		Where("search_documents.index_name = ?", query.Index.Name)
+
+	switch {
+	case len(words) == 0:
+		rows = rows.Order("search_documents.id")
+	case d.mode == modeTSVector:
+		prefixes := make([]string, len(words))
+		for i, word := range words {
+			prefixes[i] = word + ":*"
+		}
+		match := strings.Join(prefixes, " & ")
+		rows = rows.Where("to_tsvector('simple', search_documents.content) @@ to_tsquery('simple', ?)", match).
+			Order(clause.Expr{SQL: "ts_rank(to_tsvector('simple', search_documents.content), to_tsquery('simple', ?)) DESC", Vars: []interface{}{match}}).
+			Order("search_documents.id")
+	case d.mode == modeFTS5:
+		prefixes := make([]string, len(words))
+		for i, word := range words {
+			prefixes[i] = `"` + word + `"*`
+		}
+		rows = rows.Joins("JOIN "+ftsTable+" ON "+ftsTable+".rowid = search_documents.id").
+			Where(ftsTable+" MATCH ?", strings.Join(prefixes, " ")).
+			Order(ftsTable + ".rank").
+			Order("search_documents.id")
+	default:
+		// The words only hold letters and numbers, so they never contain the wildcards of LIKE.
+		for _, word := range words {
+			rows = rows.Where("search_documents.content LIKE ?", "%"+word+"%")
+		}
+		rows = rows.Order("search_documents.id")
+	}
+
+	var found []searchDocument
+	if err := rows.Find(&found).Error; err != nil {
+		return nil, 0, err
+	}
+
+	candidates := make([]candidate, 0, len(found))
+	for _, row := range found {
+		document := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(row.Attributes), &document); err != nil {
+			return nil, 0, err
+		}
+		candidates = append(candidates, candidate{id: row.DocumentID, document: document})
+	}
+	return finish(candidates, query)
+}
+
+// chunk calls fn with consecutive parts of the given IDs of at most size IDs each,
+// keeping the number of bound parameters of a statement within the limits of the database.
+func chunk(ids []string, size int, fn func(ids []string) error) error {
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := fn(ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
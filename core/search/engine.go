@@ -0,0 +1,175 @@
+// Package search keeps the search indexes of the application's searchable models up to date and queries them.
+// The search engine is selected with SEARCH_DRIVER: Meilisearch, the application database, or memory for tests.
+// Every driver implements Engine, so the application never depends on a particular one.
+package search
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/meilisearch/meilisearch-go"
+	"gorm.io/gorm"
+)
+
+// Engine is a search engine that stores documents in indexes and runs full text searches on them.
+// Upsert and Delete may queue the change and send it later in a batch; Flush sends every queued change now,
+// and Close before the application exits.
+type Engine interface {
+	// Upsert adds the given document to the index, or replaces the stored document with the same ID.
+	Upsert(index Index, id string, document map[string]interface{})
+	// Delete removes the document with the given ID from the index.
+	Delete(index Index, id string)
+	// Flush sends every queued change to the search engine.
+	Flush() error
+	// Close sends every queued change and stops sending changes in the background, when the application exits.
+	Close() error
+	// Search runs the given query and returns the IDs of the matching documents by relevance,
+	// together with the total number of matches, which may be an estimate.
+	Search(query Query) ([]string, int64, error)
+}
+
+// Index describes a search index: its name, the attribute holding the ID of the documents,
+// and the attributes that can be used in filters and to sort the results.
+type Index struct {
+	Name       string
+	PrimaryKey string
+	Filterable []string
+	Sortable   []string
+}
+
+// Query is a full text search on an index. Filter uses the filter syntax of Meilisearch, such as
+// `status = "published" AND year > 2020`, and Sort holds "attribute:asc" or "attribute:desc" entries.
+// A Limit of zero returns the default number of results, 20.
+type Query struct {
+	Index  Index
+	Text   string
+	Filter string
+	Sort   []string
+	Limit  int
+	Offset int
+}
+
+// defaultLimit is the number of results returned when a query has no limit, the default of Meilisearch.
+const defaultLimit = 20
+
+// New creates the search engine selected by SEARCH_DRIVER: "meilisearch" (the default), "database" or "memory".
+// The Meilisearch driver connects to MEILI_HOST (http://127.0.0.1:7700 by default) with MEILI_MASTER_KEY,
+// and the database driver stores the documents in the given database.
+func New(db *gorm.DB) (Engine, error) {
+	switch driver := os.Getenv("SEARCH_DRIVER"); driver {
+	case "", "meilisearch":
+		host := os.Getenv("MEILI_HOST")
+		if host == "" {
+			host = "http://127.0.0.1:7700"
+		}
+		client := meilisearch.NewClient(meilisearch.ClientConfig{Host: host, APIKey: os.Getenv("MEILI_MASTER_KEY")})
+		return NewMeilisearch(client), nil
+	case "database":
+		return NewDatabase(db)
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unsupported SEARCH_DRIVER %q, use meilisearch, database or memory", driver)
+	}
+}
+
+// candidate is a document matching the text of a query, in order of relevance, before filtering.
+type candidate struct {
+	id       string
+	document map[string]interface{}
+}
+
+// finish applies the filter, sort, offset and limit of a query to the documents matching its text,
+// for the drivers that cannot run filters themselves. It returns the IDs of the page and the number of matches.
+func finish(candidates []candidate, query Query) ([]string, int64, error) {
+	filter, err := parseFilter(query.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	matched := candidates[:0:0]
+	for _, c := range candidates {
+		if filter == nil || filter.match(c.document) {
+			matched = append(matched, c)
+		}
+	}
+
+	if len(query.Sort) > 0 {
+		// A stable sort keeps the relevance order between documents that sort the same.
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, rule := range query.Sort {
+				attribute, direction, _ := strings.Cut(rule, ":")
+				order := compareValues(matched[i].document[attribute], matched[j].document[attribute])
+				if order == 0 {
+					continue
+				}
+				if direction == "desc" {
+					return order > 0
+				}
+				return order < 0
+			}
+			return false
+		})
+	}
+
+	total := int64(len(matched))
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	start := query.Offset
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	ids := make([]string, 0, end-start)
+	for _, c := range matched[start:end] {
+		ids = append(ids, c.id)
+	}
+	return ids, total, nil
+}
+
+// terms splits the text of a query into lower case words.
+func terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// content returns the searchable text of a document: every value of the document, separated by spaces.
+func content(document map[string]interface{}) string {
+	keys := make([]string, 0, len(document))
+	for key := range document {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	var write func(value interface{})
+	write = func(value interface{}) {
+		switch v := value.(type) {
+		case nil:
+		case []interface{}:
+			for _, item := range v {
+				write(item)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				write(item)
+			}
+		default:
+			builder.WriteString(fmt.Sprint(v))
+			builder.WriteByte(' ')
+		}
+	}
+	for _, key := range keys {
+		write(document[key])
+	}
+	return strings.TrimSpace(builder.String())
+}
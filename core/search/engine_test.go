@@ -0,0 +1,181 @@
+package search
+
+import (
+	"GoAPIfy/internal/testdb"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testEngines returns the drivers that run without a search server: the memory driver and the database driver
+// on SQLite. The changes of the database driver are only written by Flush.
+func testEngines(t *testing.T) map[string]func(t *testing.T) Engine {
+	return map[string]func(t *testing.T) Engine{
+		"memory": func(t *testing.T) Engine { return NewMemory() },
+		"database": func(t *testing.T) Engine {
+			t.Setenv("SEARCH_FLUSH_INTERVAL", "3600000")
+			engine, err := NewDatabase(testdb.Open(t, &searchDocument{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return engine
+		},
+	}
+}
+
+var books = Index{Name: "books", PrimaryKey: "id", Filterable: []string{"status", "year", "tags"}, Sortable: []string{"year", "title"}}
+
+// seedBooks indexes five books and a user in another index. Every book is flushed on its own, so the database driver
+// stores them, and lists the ones of equal relevance, in the order of their IDs.
+func seedBooks(t *testing.T, engine Engine) {
+	t.Helper()
+	for _, book := range []map[string]interface{}{
+		{"id": 1, "title": "Go in Action", "status": "published", "year": 2015, "tags": []string{"go", "books"}},
+		{"id": 2, "title": "Learning Go", "status": "draft", "year": 2021, "tags": []string{"go"}},
+		{"id": 3, "title": "Rust in Action", "status": "published", "year": 2019, "tags": []string{"rust"}},
+		{"id": 4, "title": "The Go Programming Language", "status": "published", "year": 2015},
+		{"id": 5, "title": "Gone with the Wind", "status": "published", "year": 1936},
+	} {
+		engine.Upsert(books, strconv.Itoa(book["id"].(int)), book)
+		if err := engine.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine.Upsert(Index{Name: "users"}, "1", map[string]interface{}{"name": "Go fan"})
+	if err := engine.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     Query
+		wantIDs   string
+		wantTotal int64
+		wantErr   bool
+	}{
+		{name: "no text", query: Query{}, wantIDs: "1,2,3,4,5", wantTotal: 5},
+		{name: "word", query: Query{Text: "go"}, wantIDs: "1,2,4,5", wantTotal: 4},
+		{name: "every word must match", query: Query{Text: "Action, go!"}, wantIDs: "1", wantTotal: 1},
+		{name: "start of a word", query: Query{Text: "act"}, wantIDs: "1,3", wantTotal: 2},
+		{name: "no match", query: Query{Text: "python"}, wantIDs: "", wantTotal: 0},
+		{name: "filter", query: Query{Text: "go", Filter: `status = published`}, wantIDs: "1,4,5", wantTotal: 3},
+		{name: "number filter", query: Query{Filter: "year > 2016"}, wantIDs: "2,3", wantTotal: 2},
+		{name: "array filter", query: Query{Filter: "tags = GO"}, wantIDs: "1,2", wantTotal: 2},
+		{name: "in filter", query: Query{Filter: "tags IN [rust, books]"}, wantIDs: "1,3", wantTotal: 2},
+		{name: "not and or", query: Query{Filter: "NOT status = draft AND (year < 2000 OR tags EXISTS)"}, wantIDs: "1,3,5", wantTotal: 3},
+		{name: "sort", query: Query{Sort: []string{"year:desc"}}, wantIDs: "2,3,1,4,5", wantTotal: 5},
+		{name: "sort on two attributes", query: Query{Sort: []string{"year:asc", "title:desc"}}, wantIDs: "5,4,1,3,2", wantTotal: 5},
+		{name: "page", query: Query{Limit: 2, Offset: 1}, wantIDs: "2,3", wantTotal: 5},
+		{name: "offset past the end", query: Query{Offset: 10}, wantIDs: "", wantTotal: 5},
+		{name: "invalid filter", query: Query{Filter: "year >"}, wantErr: true},
+	}
+
+	for driver, open := range testEngines(t) {
+		t.Run(driver, func(t *testing.T) {
+			engine := open(t)
+			seedBooks(t, engine)
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.query.Index = books
+					ids, total, err := engine.Search(tt.query)
+					if (err != nil) != tt.wantErr {
+						t.Fatalf("Search error = %v, want an error: %v", err, tt.wantErr)
+					}
+					if got := strings.Join(ids, ","); got != tt.wantIDs || total != tt.wantTotal {
+						t.Errorf("Search = %q of %d, want %q of %d", got, total, tt.wantIDs, tt.wantTotal)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestSearchChanges(t *testing.T) {
+	for driver, open := range testEngines(t) {
+		t.Run(driver, func(t *testing.T) {
+			engine := open(t)
+			seedBooks(t, engine)
+			engine.Upsert(books, "2", map[string]interface{}{"id": 2, "title": "Learning Rust", "status": "published", "year": 2022})
+			engine.Delete(books, "3")
+			engine.Delete(books, "99")
+			if err := engine.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				query   Query
+				wantIDs string
+			}{
+				{Query{Index: books, Text: "rust"}, "2"},
+				{Query{Index: books, Text: "go"}, "1,4,5"},
+				{Query{Index: books, Filter: "status = draft"}, ""},
+				{Query{Index: Index{Name: "users"}, Text: "go"}, "1"},
+				{Query{Index: Index{Name: "authors"}}, ""},
+			}
+			for _, tt := range tests {
+				ids, _, err := engine.Search(tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.Join(ids, ","); got != tt.wantIDs {
+					t.Errorf("Search %q in %s = %q, want %q", tt.query.Text+tt.query.Filter, tt.query.Index.Name, got, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRanking(t *testing.T) {
+	engine := NewMemory()
+	engine.Upsert(books, "1", map[string]interface{}{"title": "Go"})
+	engine.Upsert(books, "2", map[string]interface{}{"title": "Go, go, go", "summary": "going"})
+	engine.Upsert(books, "10", map[string]interface{}{"title": "Go go"})
+	engine.Upsert(books, "3", map[string]interface{}{"title": "Go go"})
+
+	// Documents holding the words more often rank first, and ties are ordered by ID.
+	ids, _, err := engine.Search(Query{Index: books, Text: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ids, ","); got != "2,3,10,1" {
+		t.Errorf("Search = %q, want \"2,3,10,1\"", got)
+	}
+}
+
+func TestNewDatabase(t *testing.T) {
+	_, err := NewDatabase(testdb.Open(t))
+	if err == nil || !strings.Contains(err.Error(), "run the migrations first") {
+		t.Errorf("NewDatabase without the search_documents table = %v, want an error", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("SEARCH_FLUSH_INTERVAL", "3600000")
+	db := testdb.Open(t, &searchDocument{})
+	tests := []struct {
+		driver   string
+		wantType string
+		wantErr  bool
+	}{
+		{"", "*search.Meilisearch", false},
+		{"meilisearch", "*search.Meilisearch", false},
+		{"database", "*search.Database", false},
+		{"memory", "*search.Memory", false},
+		{"elasticsearch", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			t.Setenv("SEARCH_DRIVER", tt.driver)
+			engine, err := New(db)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if err == nil && fmt.Sprintf("%T", engine) != tt.wantType {
+				t.Errorf("New = %T, want %s", engine, tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filter is a parsed search filter that the database and memory drivers evaluate against documents.
+// It supports the common part of the filter syntax of Meilisearch: the comparisons =, !=, >, >=, < and <=,
+// "attribute IN [a, b]", "attribute EXISTS", NOT, AND, OR and parentheses. Values are numbers, true, false,
+// or strings, quoted when they hold spaces. String comparisons ignore case, and a comparison on an array
+// attribute matches when any element matches.
+type filter interface {
+	match(document map[string]interface{}) bool
+}
+
+type andFilter []filter
+type orFilter []filter
+type notFilter struct{ filter }
+
+type comparison struct {
+	attribute string
+	operator  string
+	values    []string
+}
+
+func (f andFilter) match(document map[string]interface{}) bool {
+	for _, part := range f {
+		if !part.match(document) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f orFilter) match(document map[string]interface{}) bool {
+	for _, part := range f {
+		if part.match(document) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f notFilter) match(document map[string]interface{}) bool {
+	return !f.filter.match(document)
+}
+
+func (c comparison) match(document map[string]interface{}) bool {
+	value, exists := document[c.attribute]
+	if c.operator == "EXISTS" {
+		return exists
+	}
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if c.compare(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return exists && c.compare(value)
+}
+
+// compare reports whether a single value satisfies the comparison.
+func (c comparison) compare(value interface{}) bool {
+	switch c.operator {
+	case "IN":
+		for _, candidate := range c.values {
+			if compareValues(value, candidate) == 0 {
+				return true
+			}
+		}
+		return false
+	case "=":
+		return compareValues(value, c.values[0]) == 0
+	case "!=":
+		return compareValues(value, c.values[0]) != 0
+	}
+
+	// Ordering comparisons only apply to numbers.
+	number, ok := toNumber(value)
+	bound, err := strconv.ParseFloat(c.values[0], 64)
+	if !ok || err != nil {
+		return false
+	}
+	switch c.operator {
+	case ">":
+		return number > bound
+	case ">=":
+		return number >= bound
+	case "<":
+		return number < bound
+	case "<=":
+		return number <= bound
+	}
+	return false
+}
+
+// compareValues orders two values: numerically when both are numbers, otherwise as case insensitive strings.
+// Missing values sort last.
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+// toNumber returns the value of a JSON number, or of a string holding a number.
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// parseFilter parses a filter expression, and returns nil for an empty one.
+func parseFilter(expression string) (filter, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	}
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	parsed, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.position < len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter %q: unexpected %q", expression, p.tokens[p.position].text)
+	}
+	return parsed, nil
+}
+
+// token is a word, quoted string or symbol of a filter expression.
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits a filter expression into tokens.
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			var text strings.Builder
+			for ; end < len(runes) && runes[end] != r; end++ {
+				if runes[end] == '\\' && end+1 < len(runes) {
+					end++
+				}
+				text.WriteRune(runes[end])
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("invalid filter %q: unterminated string", expression)
+			}
+			tokens = append(tokens, token{text: text.String(), quoted: true})
+			i = end + 1
+		case strings.ContainsRune("()[],", r):
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			operator := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				operator += "="
+			}
+			tokens = append(tokens, token{text: operator})
+			i += len(operator)
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("()[],=!<>\"'", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{text: string(runes[i:end])})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser over the tokens of a filter expression.
+type filterParser struct {
+	tokens   []token
+	position int
+}
+
+// keyword reports whether the next token is the given unquoted keyword, and consumes it if so.
+func (p *filterParser) keyword(word string) bool {
+	if p.position < len(p.tokens) && !p.tokens[p.position].quoted && strings.EqualFold(p.tokens[p.position].text, word) {
+		p.position++
+		return true
+	}
+	return false
+}
+
+// next consumes and returns the next token.
+func (p *filterParser) next() (token, error) {
+	if p.position >= len(p.tokens) {
+		return token{}, fmt.Errorf("invalid filter: unexpected end")
+	}
+	t := p.tokens[p.position]
+	p.position++
+	return t, nil
+}
+
+func (p *filterParser) or() (filter, error) {
+	parts := orFilter{}
+	for {
+		part, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if !p.keyword("OR") {
+			break
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return parts, nil
+}
+
+func (p *filterParser) and() (filter, error) {
+	parts := andFilter{}
+	for {
+		part, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if !p.keyword("AND") {
+			break
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return parts, nil
+}
+
+func (p *filterParser) not() (filter, error) {
+	if p.keyword("NOT") {
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{inner}, nil
+	}
+	if p.keyword("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("invalid filter: missing )")
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+func (p *filterParser) comparison() (filter, error) {
+	attribute, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("EXISTS") {
+		return comparison{attribute: attribute.text, operator: "EXISTS"}, nil
+	}
+	if p.keyword("NOT") {
+		if p.keyword("EXISTS") {
+			return notFilter{comparison{attribute: attribute.text, operator: "EXISTS"}}, nil
+		}
+		if !p.keyword("IN") {
+			return nil, fmt.Errorf("invalid filter: expected EXISTS or IN after %s NOT", attribute.text)
+		}
+		in, err := p.list(attribute.text)
+		return notFilter{in}, err
+	}
+	if p.keyword("IN") {
+		return p.list(attribute.text)
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch operator.text {
+	case "=", "!=", ">", ">=", "<", "<=":
+	default:
+		return nil, fmt.Errorf("invalid filter: unknown operator %q after %s", operator.text, attribute.text)
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	return comparison{attribute: attribute.text, operator: operator.text, values: []string{value.text}}, nil
+}
+
+// list parses the values of an IN comparison.
+func (p *filterParser) list(attribute string) (filter, error) {
+	if !p.keyword("[") {
+		return nil, fmt.Errorf("invalid filter: expected [ after %s IN", attribute)
+	}
+	in := comparison{attribute: attribute, operator: "IN"}
+	for !p.keyword("]") {
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		in.values = append(in.values, value.text)
+		p.keyword(",")
+	}
+	return in, nil
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// Meilisearch is the Engine that stores the documents in Meilisearch.
+// Changes are queued and sent in batches; Flush returns once Meilisearch has accepted them, which it then applies
+// asynchronously. Filters and sorting are run by Meilisearch, so every filter of its syntax is supported.
+type Meilisearch struct {
+	*queue
+	client *meilisearch.Client
+
+	mu         sync.Mutex
+	configured map[string]bool
+}
+
+// NewMeilisearch creates a new Meilisearch engine using the given client and starts sending the queued changes.
+func NewMeilisearch(client *meilisearch.Client) *Meilisearch {
+	m := &Meilisearch{client: client, configured: map[string]bool{}}
+	m.queue = newQueue(m.send)
+	return m
+}
+
+// send sends the queued changes of a single index.
+func (m *Meilisearch) send(b *batch) error {
+	if err := m.configure(b.index); err != nil {
+		return err
+	}
+	index := m.client.Index(b.index.Name)
+
+	if len(b.upserts) > 0 {
+		documents := make([]map[string]interface{}, 0, len(b.upserts))
+		for _, document := range b.upserts {
+			documents = append(documents, document)
+		}
+		if _, err := index.AddDocumentsInBatches(documents, m.batchSize, b.index.PrimaryKey); err != nil {
+			return err
+		}
+	}
+	if len(b.deletes) > 0 {
+		ids := make([]string, 0, len(b.deletes))
+		for id := range b.deletes {
+			ids = append(ids, id)
+		}
+		if _, err := index.DeleteDocuments(ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configure creates the index and applies its filterable and sortable attributes, once per index.
+// Meilisearch processes the tasks of an index in order, so they are applied before any queued document.
+func (m *Meilisearch) configure(index Index) error {
+	m.mu.Lock()
+	done := m.configured[index.Name]
+	m.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	// Creating an index that already exists only fails the asynchronous task, not the request.
+	if _, err := m.client.CreateIndex(&meilisearch.IndexConfig{Uid: index.Name, PrimaryKey: index.PrimaryKey}); err != nil {
+		return err
+	}
+	settings := &meilisearch.Settings{FilterableAttributes: index.Filterable, SortableAttributes: index.Sortable}
+	if _, err := m.client.Index(index.Name).UpdateSettings(settings); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.configured[index.Name] = true
+	m.mu.Unlock()
+	return nil
+}
+
+// Search runs the given query and returns the IDs of the matching documents by relevance,
+// together with the estimated total number of matches.
+func (m *Meilisearch) Search(query Query) ([]string, int64, error) {
+	if err := m.configure(query.Index); err != nil {
+		return nil, 0, err
+	}
+
+	request := &meilisearch.SearchRequest{
+		AttributesToRetrieve: []string{query.Index.PrimaryKey},
+		Sort:                 query.Sort,
+		Limit:                int64(query.Limit),
+		Offset:               int64(query.Offset),
+	}
+	if query.Filter != "" {
+		request.Filter = query.Filter
+	}
+	response, err := m.client.Index(query.Index.Name).Search(query.Text, request)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]string, 0, len(response.Hits))
+	for _, hit := range response.Hits {
+		document, ok := hit.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch id := document[query.Index.PrimaryKey].(type) {
+		case float64:
+			ids = append(ids, strconv.FormatFloat(id, 'f', -1, 64))
+		case string:
+			ids = append(ids, id)
+		}
+	}
+	return ids, response.EstimatedTotalHits, nil
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is the Engine that keeps the documents in memory, for tests and local development.
+// Changes are applied immediately, so Flush and Close have nothing to do. Every word of the search text must appear in a
+// document, as a word or the start of one, and documents holding the words more often rank first.
+type Memory struct {
+	mu      sync.RWMutex
+	indexes map[string]map[string]memoryDocument
+}
+
+// memoryDocument is a stored document together with its searchable words.
+type memoryDocument struct {
+	document map[string]interface{}
+	words    []string
+}
+
+// NewMemory creates a new, empty in-memory engine.
+func NewMemory() *Memory {
+	return &Memory{indexes: map[string]map[string]memoryDocument{}}
+}
+
+// Upsert adds the given document to the index, or replaces the stored document with the same ID.
+func (m *Memory) Upsert(index Index, id string, document map[string]interface{}) {
+	// The round trip through JSON stores the document as any other driver returns it, and as a private copy.
+	document = normalize(document)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.indexes[index.Name] == nil {
+		m.indexes[index.Name] = map[string]memoryDocument{}
+	}
+	m.indexes[index.Name][id] = memoryDocument{document: document, words: terms(content(document))}
+}
+
+// Delete removes the document with the given ID from the index.
+func (m *Memory) Delete(index Index, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.indexes[index.Name], id)
+}
+
+// Flush does nothing, changes are applied immediately.
+func (m *Memory) Flush() error {
+	return nil
+}
+
+// Close does nothing, changes are applied immediately.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Search runs the given query and returns the IDs of the matching documents by relevance,
+// together with the total number of matches.
+func (m *Memory) Search(query Query) ([]string, int64, error) {
+	words := terms(query.Text)
+
+	type scored struct {
+		candidate
+		score int
+	}
+	var hits []scored
+	m.mu.RLock()
+	for id, stored := range m.indexes[query.Index.Name] {
+		score := 0
+		for _, word := range words {
+			matches := 0
+			for _, candidate := range stored.words {
+				if strings.HasPrefix(candidate, word) {
+					matches++
+				}
+			}
+			if matches == 0 {
+				score = -1
+				break
+			}
+			score += matches
+		}
+		if score >= 0 {
+			hits = append(hits, scored{candidate{id: id, document: stored.document}, score})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return compareValues(hits[i].id, hits[j].id) < 0
+	})
+	candidates := make([]candidate, len(hits))
+	for i, hit := range hits {
+		candidates[i] = hit.candidate
+	}
+	return finish(candidates, query)
+}
+
+// normalize returns a copy of the document as decoded from JSON, with numbers as float64 and nested values as
+// maps and slices, so the filters see the same values as Meilisearch does.
+func normalize(document map[string]interface{}) map[string]interface{} {
+	normalized := map[string]interface{}{}
+	if encoded, err := json.Marshal(document); err == nil {
+		_ = json.Unmarshal(encoded, &normalized)
+	}
+	return normalized
+}
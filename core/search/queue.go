@@ -0,0 +1,200 @@
+package search
+
+import (
+	"GoAPIfy/core/helper"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// queue collects the changes of the search indexes and sends them in the background, in one batch per index for
+// every SEARCH_BATCH_SIZE queued changes (500 by default) or every SEARCH_FLUSH_INTERVAL milliseconds (1000 by default),
+// whichever comes first. A document changed several times before a flush is only sent once.
+// A batch that fails to be sent is queued again and retried with an exponential backoff, from one second up to a
+// minute, merged with the changes queued since; it is only dropped after maxSendAttempts attempts, and then its index
+// must be rebuilt with Repository.Reindex. Close stops the background sender and sends the remaining changes.
+// Drivers embed it to implement Upsert, Delete, Flush and Close of Engine.
+type queue struct {
+	send      func(b *batch) error
+	batchSize int
+
+	mu      sync.Mutex
+	pending map[string]*batch
+	queued  int
+
+	flushing sync.Mutex
+	wake     chan struct{}
+
+	closing sync.Once
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// maxSendAttempts is the number of times a batch is sent before its changes are dropped.
+const maxSendAttempts = 8
+
+// batch holds the queued changes of a single index.
+// attempts counts the failed sends of its changes, and retryAt is the time the background sender retries them after.
+type batch struct {
+	index    Index
+	upserts  map[string]map[string]interface{}
+	deletes  map[string]bool
+	attempts int
+	retryAt  time.Time
+}
+
+// newQueue creates a queue that sends its batches with the given function and starts sending them.
+func newQueue(send func(b *batch) error) *queue {
+	batchSize := 500
+	if size, err := strconv.Atoi(os.Getenv("SEARCH_BATCH_SIZE")); err == nil && size > 0 {
+		batchSize = size
+	}
+	interval := time.Second
+	if milliseconds, err := strconv.Atoi(os.Getenv("SEARCH_FLUSH_INTERVAL")); err == nil && milliseconds > 0 {
+		interval = time.Duration(milliseconds) * time.Millisecond
+	}
+
+	q := &queue{
+		send:      send,
+		batchSize: batchSize,
+		pending:   map[string]*batch{},
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go func() {
+		defer close(q.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-q.wake:
+			case <-q.stop:
+				return
+			}
+			if err := q.flush(false); err != nil {
+				fmt.Println(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Search index update failed: %s", err)))
+			}
+		}
+	}()
+	return q
+}
+
+// Upsert queues the given document to be added to the index, or to replace the stored document with the same ID.
+func (q *queue) Upsert(index Index, id string, document map[string]interface{}) {
+	q.queue(index, func(b *batch) {
+		delete(b.deletes, id)
+		b.upserts[id] = document
+	})
+}
+
+// Delete queues the document with the given ID to be removed from the index.
+func (q *queue) Delete(index Index, id string) {
+	q.queue(index, func(b *batch) {
+		delete(b.upserts, id)
+		b.deletes[id] = true
+	})
+}
+
+// queue applies a change to the batch of the given index and wakes the sender up when the batch size is reached.
+func (q *queue) queue(index Index, change func(b *batch)) {
+	q.mu.Lock()
+	b, ok := q.pending[index.Name]
+	if !ok {
+		b = &batch{index: index, upserts: map[string]map[string]interface{}{}, deletes: map[string]bool{}}
+		q.pending[index.Name] = b
+	}
+	change(b)
+	q.queued++
+	full := q.queued >= q.batchSize
+	q.mu.Unlock()
+
+	if full {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush sends every queued change now, the failed batches waiting for a retry included.
+func (q *queue) Flush() error {
+	return q.flush(true)
+}
+
+// Close stops the background sender, waiting for the batches it is sending, and sends every queued change.
+// Changes queued after Close are only sent by Flush.
+func (q *queue) Close() error {
+	q.closing.Do(func() { close(q.stop) })
+	<-q.stopped
+	return q.Flush()
+}
+
+// flush sends the queued batches, except the failed ones whose retry is not due yet unless all is true,
+// and queues the batches that fail again.
+func (q *queue) flush(all bool) error {
+	q.flushing.Lock()
+	defer q.flushing.Unlock()
+
+	now := time.Now()
+	q.mu.Lock()
+	pending := map[string]*batch{}
+	for name, b := range q.pending {
+		if all || !now.Before(b.retryAt) {
+			pending[name] = b
+			delete(q.pending, name)
+		}
+	}
+	q.queued = 0
+	q.mu.Unlock()
+
+	var failed error
+	for _, b := range pending {
+		if err := q.send(b); err != nil {
+			failed = fmt.Errorf("index %s: %w", b.index.Name, err)
+			if !q.retry(b) {
+				failed = fmt.Errorf("%w, dropped %d changes after %d attempts, reindex it", failed, len(b.upserts)+len(b.deletes), b.attempts)
+			}
+		}
+	}
+	return failed
+}
+
+// retry queues the changes of a failed batch again, behind the changes of the same documents queued since it was
+// taken, and reports whether they will be retried.
+func (q *queue) retry(failed *batch) bool {
+	failed.attempts++
+	if failed.attempts >= maxSendAttempts {
+		return false
+	}
+	backoff := time.Second << (failed.attempts - 1)
+	if backoff > time.Minute {
+		backoff = time.Minute
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, ok := q.pending[failed.index.Name]
+	if !ok {
+		b = &batch{index: failed.index, upserts: map[string]map[string]interface{}{}, deletes: map[string]bool{}}
+		q.pending[failed.index.Name] = b
+	}
+	for id, document := range failed.upserts {
+		if _, deleted := b.deletes[id]; !deleted {
+			if _, upserted := b.upserts[id]; !upserted {
+				b.upserts[id] = document
+			}
+		}
+	}
+	for id := range failed.deletes {
+		if _, upserted := b.upserts[id]; !upserted {
+			b.deletes[id] = true
+		}
+	}
+	b.attempts = failed.attempts
+	b.retryAt = time.Now().Add(backoff)
+	return true
+}
@@ -0,0 +1,256 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is the send function of the queue tests. It records the sent batches as "index: +upserted -deleted"
+// and fails the sends for which fail returns true.
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+	fail func(b *batch) bool
+	// during runs inside the send, as if other changes were queued while the batch is being sent.
+	during func()
+}
+
+var errSend = errors.New("search engine unavailable")
+
+func (r *recorder) send(b *batch) error {
+	if r.during != nil {
+		r.during()
+		r.during = nil
+	}
+	var changes []string
+	for id, document := range b.upserts {
+		changes = append(changes, fmt.Sprintf("+%s=%v", id, document["title"]))
+	}
+	for id := range b.deletes {
+		changes = append(changes, "-"+id)
+	}
+	sort.Strings(changes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, b.index.Name+": "+strings.Join(changes, " "))
+	if r.fail != nil && r.fail(b) {
+		return errSend
+	}
+	return nil
+}
+
+// sentBatches returns the recorded batches sorted, and forgets them.
+func (r *recorder) sentBatches() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sent := r.sent
+	r.sent = nil
+	sort.Strings(sent)
+	return strings.Join(sent, " | ")
+}
+
+// newTestQueue returns a queue whose background sender only runs when the batch size is reached.
+func newTestQueue(t *testing.T, r *recorder) *queue {
+	t.Setenv("SEARCH_FLUSH_INTERVAL", "3600000")
+	return newQueue(r.send)
+}
+
+func doc(title string) map[string]interface{} {
+	return map[string]interface{}{"title": title}
+}
+
+func TestQueueBatches(t *testing.T) {
+	posts, users := Index{Name: "posts"}, Index{Name: "users"}
+	tests := []struct {
+		name     string
+		changes  func(q *queue)
+		wantSent string
+	}{
+		{
+			name:     "nothing queued",
+			changes:  func(q *queue) {},
+			wantSent: "",
+		},
+		{
+			name: "a document changed twice is sent once",
+			changes: func(q *queue) {
+				q.Upsert(posts, "1", doc("a"))
+				q.Upsert(posts, "1", doc("b"))
+			},
+			wantSent: "posts: +1=b",
+		},
+		{
+			name: "a delete replaces an upsert",
+			changes: func(q *queue) {
+				q.Upsert(posts, "1", doc("a"))
+				q.Delete(posts, "1")
+				q.Upsert(posts, "2", doc("c"))
+			},
+			wantSent: "posts: +2=c -1",
+		},
+		{
+			name: "an upsert replaces a delete",
+			changes: func(q *queue) {
+				q.Delete(posts, "1")
+				q.Upsert(posts, "1", doc("a"))
+			},
+			wantSent: "posts: +1=a",
+		},
+		{
+			name: "one batch per index",
+			changes: func(q *queue) {
+				q.Upsert(posts, "1", doc("a"))
+				q.Upsert(users, "1", doc("ann"))
+				q.Delete(users, "2")
+			},
+			wantSent: "posts: +1=a | users: +1=ann -2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			q := newTestQueue(t, r)
+			tt.changes(q)
+			if err := q.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := r.sentBatches(); got != tt.wantSent {
+				t.Errorf("sent %q, want %q", got, tt.wantSent)
+			}
+			// Sent changes are not sent again.
+			if err := q.Flush(); err != nil || r.sentBatches() != "" {
+				t.Errorf("a second Flush sent changes again (%v)", err)
+			}
+		})
+	}
+}
+
+func TestQueueBatchSize(t *testing.T) {
+	t.Setenv("SEARCH_BATCH_SIZE", "2")
+	r := &recorder{}
+	q := newTestQueue(t, r)
+	q.Upsert(Index{Name: "posts"}, "1", doc("a"))
+	q.Upsert(Index{Name: "posts"}, "2", doc("b"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got := r.sentBatches(); got != "" {
+			if got != "posts: +1=a +2=b" {
+				t.Errorf("sent %q, want the full batch", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("a full batch was not sent in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	t.Setenv("SEARCH_BATCH_SIZE", "2")
+	r := &recorder{}
+	q := newTestQueue(t, r)
+	q.Upsert(Index{Name: "posts"}, "1", doc("a"))
+
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.sentBatches(); got != "posts: +1=a" {
+		t.Errorf("Close sent %q, want the queued change", got)
+	}
+	if err := q.Close(); err != nil {
+		t.Errorf("second Close error = %v", err)
+	}
+
+	// The background sender is stopped, so a full batch waits for Flush.
+	q.Upsert(Index{Name: "posts"}, "2", doc("b"))
+	q.Upsert(Index{Name: "posts"}, "3", doc("c"))
+	time.Sleep(50 * time.Millisecond)
+	if got := r.sentBatches(); got != "" {
+		t.Errorf("sent %q after Close, want nothing", got)
+	}
+	if err := q.Flush(); err != nil || r.sentBatches() != "posts: +2=b +3=c" {
+		t.Errorf("Flush after Close did not send the queued changes (%v)", err)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	posts := Index{Name: "posts"}
+	failures := 1
+	r := &recorder{fail: func(b *batch) bool {
+		failures--
+		return failures >= 0
+	}}
+	q := newTestQueue(t, r)
+	q.Upsert(posts, "1", doc("a"))
+	q.Delete(posts, "2")
+	q.Upsert(posts, "3", doc("c"))
+	// Changes queued while the failing batch is sent win over the ones of the batch.
+	r.during = func() {
+		q.Upsert(posts, "1", doc("newer"))
+		q.Upsert(posts, "2", doc("back"))
+	}
+
+	if err := q.Flush(); !errors.Is(err, errSend) {
+		t.Fatalf("Flush error = %v, want %v", err, errSend)
+	}
+	r.sentBatches()
+	b := q.pending["posts"]
+	if b == nil || b.attempts != 1 || time.Until(b.retryAt) <= 0 {
+		t.Fatalf("pending batch = %+v, want a retry after the first attempt", b)
+	}
+
+	// The background sender waits for the backoff, Flush does not.
+	if err := q.flush(false); err != nil || r.sentBatches() != "" {
+		t.Errorf("the failed batch was retried before its backoff (%v)", err)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.sentBatches(), "posts: +1=newer +2=back +3=c"; got != want {
+		t.Errorf("retried %q, want %q", got, want)
+	}
+}
+
+func TestQueueDrop(t *testing.T) {
+	r := &recorder{fail: func(b *batch) bool { return true }}
+	q := newTestQueue(t, r)
+	q.Upsert(Index{Name: "posts"}, "1", doc("a"))
+	q.Delete(Index{Name: "posts"}, "2")
+
+	for attempt := 1; attempt < maxSendAttempts; attempt++ {
+		if err := q.Flush(); err == nil || strings.Contains(err.Error(), "dropped") {
+			t.Fatalf("attempt %d: Flush error = %v, want a retried failure", attempt, err)
+		}
+	}
+	err := q.Flush()
+	if err == nil || !strings.Contains(err.Error(), "dropped 2 changes after 8 attempts") {
+		t.Fatalf("last Flush error = %v, want the dropped changes", err)
+	}
+	if len(q.pending) != 0 {
+		t.Errorf("%d batches still pending after the drop", len(q.pending))
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute}
+	q := newTestQueue(t, &recorder{})
+	for i, backoff := range want {
+		failed := &batch{index: Index{Name: "posts"}, upserts: map[string]map[string]interface{}{}, deletes: map[string]bool{}, attempts: i}
+		before := time.Now()
+		if !q.retry(failed) {
+			t.Fatalf("attempt %d was not retried", i+1)
+		}
+		if got := q.pending["posts"].retryAt.Sub(before); got < backoff || got > backoff+time.Second {
+			t.Errorf("backoff after attempt %d = %s, want %s", i+1, got, backoff)
+		}
+	}
+}
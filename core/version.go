@@ -0,0 +1,31 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatchVersion reads the version of a Versioned model a client sent in the If-Match header.
+// It accepts the formats written by SetVersionETag, "3" and W/"3", as well as a bare 3.
+// It returns false when the header is absent or is "*", and an error when the header is not a version.
+func IfMatchVersion(c *gin.Context) (uint, bool, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("If-Match must hold a single record version, got %q", header)
+	}
+	return uint(version), true, nil
+}
+
+// SetVersionETag sets the ETag header to the version of a Versioned model, so clients can send it back in If-Match.
+func SetVersionETag(c *gin.Context, version uint) {
+	c.Header("ETag", strconv.Quote(strconv.FormatUint(uint64(version), 10)))
+}
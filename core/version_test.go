@@ -0,0 +1,59 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    uint
+		wantOK  bool
+		wantErr bool
+	}{
+		{header: ""},
+		{header: "*"},
+		{header: `"3"`, want: 3, wantOK: true},
+		{header: `W/"3"`, want: 3, wantOK: true},
+		{header: " 12 ", want: 12, wantOK: true},
+		{header: `"3", "4"`, wantErr: true},
+		{header: `"abc"`, wantErr: true},
+		{header: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("PUT", "/articles/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+			version, ok, err := IfMatchVersion(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if version != tt.want || ok != tt.wantOK {
+				t.Errorf("IfMatchVersion = %d, %v, want %d, %v", version, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestSetVersionETag makes sure the ETag written by SetVersionETag is read back by IfMatchVersion.
+func TestSetVersionETag(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	SetVersionETag(c, 7)
+	if got := recorder.Header().Get("ETag"); got != `"7"` {
+		t.Fatalf("ETag = %s, want \"7\"", got)
+	}
+
+	c.Request = httptest.NewRequest("PUT", "/articles/1", nil)
+	c.Request.Header.Set("If-Match", recorder.Header().Get("ETag"))
+	if version, ok, err := IfMatchVersion(c); err != nil || !ok || version != 7 {
+		t.Errorf("IfMatchVersion = %d, %v, %v, want 7", version, ok, err)
+	}
+}
@@ -0,0 +1,298 @@
+// Package factory builds model records filled with fake data, for seeders and tests.
+// A factory is declared once with Define, and every call on it returns a new factory,
+// so the declared factories can be shared and specialized freely.
+package factory
+
+import (
+	"GoAPIfy/model"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// Factory builds records of the model T from a definition, with named states, sequences, overrides and related records.
+type Factory[T any] struct {
+	definition func(n int) T
+	states     map[string]func(*T)
+	// createdStates are the named states applied by Create once the records are saved, see CreatedState.
+	createdStates map[string]func(*T) error
+	// steps modify every record in the order they were added to the factory, i receives the index of the record in the batch.
+	steps     []func(record *T, i int)
+	created   []func(record *T) error
+	relations []relation
+	// counter numbers the records of the definition, it is shared by every factory derived from it.
+	counter *int64
+	err     error
+}
+
+// relation is a related record, or slice of records, built for every record of a factory.
+type relation struct {
+	name    string
+	count   int
+	related Related
+}
+
+// Related is implemented by every Factory, whatever its model, so the records of a factory can be given related
+// records built by the factory of another model, see Has and For.
+type Related interface {
+	makeValues(count int) ([]reflect.Value, error)
+}
+
+// Define declares the factory of the model T. The definition returns a new record filled with fake data, usually
+// with the functions of github.com/bxcodec/faker/v3; it receives the sequence number of the record, starting at 1,
+// which is unique for the factory and can make unique values such as emails.
+//
+// Example usage:
+//
+//	var Post = factory.Define(func(n int) model.Post {
+//		return model.Post{Title: faker.Sentence(), Slug: fmt.Sprintf("post-%d", n), Published: true}
+//	}).State("draft", func(p *model.Post) {
+//		p.Published = false
+//	})
+func Define[T any](definition func(n int) T) *Factory[T] {
+	return &Factory[T]{definition: definition, states: map[string]func(*T){}, createdStates: map[string]func(*T) error{}, counter: new(int64)}
+}
+
+// clone returns a copy of the factory that can be changed without affecting the receiver.
+func (f *Factory[T]) clone() *Factory[T] {
+	next := *f
+	next.states = make(map[string]func(*T), len(f.states))
+	for name, apply := range f.states {
+		next.states[name] = apply
+	}
+	next.createdStates = make(map[string]func(*T) error, len(f.createdStates))
+	for name, apply := range f.createdStates {
+		next.createdStates[name] = apply
+	}
+	next.steps = append([]func(*T, int){}, f.steps...)
+	next.created = append([]func(*T) error{}, f.created...)
+	next.relations = append([]relation{}, f.relations...)
+	return &next
+}
+
+// State declares a named state, a change of the definition applied with Apply, such as "unverified" or "admin".
+func (f *Factory[T]) State(name string, apply func(record *T)) *Factory[T] {
+	next := f.clone()
+	next.states[name] = apply
+	return next
+}
+
+// CreatedState declares a named state applied by Create once the records are saved, for the changes that need
+// their generated fields, such as the ID. Make builds records that are never saved, so it leaves the state out,
+// and so do Has and For for the related records.
+//
+// Example usage:
+//
+//	var Team = factory.Define(newTeam).CreatedState("featured", func(t *model.Team) error {
+//		return featured.Add(t.ID)
+//	})
+func (f *Factory[T]) CreatedState(name string, apply func(record *T) error) *Factory[T] {
+	next := f.clone()
+	next.createdStates[name] = apply
+	return next
+}
+
+// Apply returns a factory building records in the given named states, applied in order.
+// An unknown state makes Make and Create fail.
+//
+// Example usage:
+//
+//	admins, err := factory.User.Apply("admin", "unverified").Create(s.Model, 2)
+func (f *Factory[T]) Apply(states ...string) *Factory[T] {
+	next := f.clone()
+	for _, name := range states {
+		if created, ok := f.createdStates[name]; ok {
+			next.created = append(next.created, created)
+			continue
+		}
+		apply, ok := f.states[name]
+		if !ok {
+			if next.err == nil {
+				next.err = fmt.Errorf("factory of %s has no %q state", reflect.TypeOf((*T)(nil)).Elem().Name(), name)
+			}
+			continue
+		}
+		next.steps = append(next.steps, func(record *T, _ int) { apply(record) })
+	}
+	return next
+}
+
+// Sequence returns a factory applying the given changes in turn to the records it builds: the first change to the
+// first record, the second one to the second record, and starting over after the last one.
+//
+// Example usage:
+//
+//	posts, err := factory.Post.Sequence(
+//		func(p *model.Post) { p.Published = true },
+//		func(p *model.Post) { p.Published = false },
+//	).Make(10)
+func (f *Factory[T]) Sequence(changes ...func(record *T)) *Factory[T] {
+	next := f.clone()
+	if len(changes) > 0 {
+		next.steps = append(next.steps, func(record *T, i int) { changes[i%len(changes)](record) })
+	}
+	return next
+}
+
+// Override returns a factory setting attributes of the records it builds, after the definition, the states and the
+// sequences added before it.
+//
+// Example usage:
+//
+//	user, err := factory.User.Override(func(u *model.User) { u.Email = "admin@example.com" }).CreateOne(s.Model)
+func (f *Factory[T]) Override(apply func(record *T)) *Factory[T] {
+	next := f.clone()
+	next.steps = append(next.steps, func(record *T, _ int) { apply(record) })
+	return next
+}
+
+// Has returns a factory giving every record it builds count records of the named relation, a has-many, has-one or
+// many-to-many field of T, built by the related factory. Create saves them together with the records.
+//
+// Example usage:
+//
+//	author, err := factory.User.Has("Posts", 3, factory.Post.Apply("draft")).CreateOne(s.Model)
+func (f *Factory[T]) Has(name string, count int, related Related) *Factory[T] {
+	next := f.clone()
+	next.relations = append(next.relations, relation{name: name, count: count, related: related})
+	return next
+}
+
+// For returns a factory giving every record it builds a parent record of the named belongs-to relation,
+// built by the related factory. Create saves the parent before the record.
+//
+// Example usage:
+//
+//	posts, err := factory.Post.For("Author", factory.User).Create(s.Model, 5)
+func (f *Factory[T]) For(name string, related Related) *Factory[T] {
+	next := f.clone()
+	next.relations = append(next.relations, relation{name: name, count: 1, related: related})
+	return next
+}
+
+// Make builds n records in memory, without saving them.
+func (f *Factory[T]) Make(n int) ([]T, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	records := make([]T, n)
+	for i := range records {
+		records[i] = f.definition(int(atomic.AddInt64(f.counter, 1)))
+		for _, step := range f.steps {
+			step(&records[i], i)
+		}
+		for _, r := range f.relations {
+			if err := r.assign(reflect.ValueOf(&records[i]).Elem()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return records, nil
+}
+
+// MakeOne builds a single record in memory, without saving it.
+func (f *Factory[T]) MakeOne() (T, error) {
+	records, err := f.Make(1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return records[0], nil
+}
+
+// Create builds n records and saves them, with their related records, in a single transaction through the
+// model wrapper, so their observers run, then applies the created states to them.
+// It returns the records with their generated fields, such as the ID.
+//
+// Example usage:
+//
+//	users, err := factory.User.Apply("unverified").Create(s.Model, 10)
+func (f *Factory[T]) Create(m model.Model, n int) ([]T, error) {
+	records, err := f.Make(n)
+	if err != nil || n == 0 {
+		return records, err
+	}
+	err = m.Transaction(func(tx model.Model) error {
+		_, err := model.NewRepository[T](tx).CreateInBatches(records, 100)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		for _, apply := range f.created {
+			if err := apply(&records[i]); err != nil {
+				return records, err
+			}
+		}
+	}
+	return records, nil
+}
+
+// CreateOne builds a single record and saves it, see Create.
+func (f *Factory[T]) CreateOne(m model.Model) (T, error) {
+	records, err := f.Create(m, 1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return records[0], nil
+}
+
+// makeValues builds count records for the relation of another factory.
+func (f *Factory[T]) makeValues(count int) ([]reflect.Value, error) {
+	records, err := f.Make(count)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]reflect.Value, len(records))
+	for i := range records {
+		values[i] = reflect.ValueOf(&records[i])
+	}
+	return values, nil
+}
+
+// assign builds the related records and sets them on the relation field of the given record.
+func (r relation) assign(record reflect.Value) error {
+	field := record.FieldByName(r.name)
+	if !field.IsValid() || !field.CanSet() {
+		return fmt.Errorf("%s has no %s field", record.Type().Name(), r.name)
+	}
+	related, err := r.related.makeValues(r.count)
+	if err != nil {
+		return err
+	}
+
+	// convert returns the related record, a pointer, as a value of the given type, a struct or a pointer to it.
+	convert := func(value reflect.Value, target reflect.Type) (reflect.Value, error) {
+		switch target {
+		case value.Type():
+			return value, nil
+		case value.Type().Elem():
+			return value.Elem(), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot assign a %s to the %s field of %s", value.Type().Elem().Name(), r.name, record.Type().Name())
+	}
+
+	if field.Kind() == reflect.Slice {
+		items := reflect.MakeSlice(field.Type(), 0, len(related))
+		for _, value := range related {
+			item, err := convert(value, field.Type().Elem())
+			if err != nil {
+				return err
+			}
+			items = reflect.Append(items, item)
+		}
+		field.Set(items)
+		return nil
+	}
+	if len(related) == 0 {
+		return nil
+	}
+	item, err := convert(related[0], field.Type())
+	if err != nil {
+		return err
+	}
+	field.Set(item)
+	return nil
+}
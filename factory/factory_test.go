@@ -0,0 +1,372 @@
+package factory
+
+import (
+	"GoAPIfy/internal/testdb"
+	"GoAPIfy/middleware"
+	"GoAPIfy/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// factAuthor has many factPost records, every factPost belongs to a factAuthor.
+type factAuthor struct {
+	ID    uint
+	Name  string
+	Role  string
+	Posts []factPost `gorm:"foreignKey:AuthorID"`
+}
+
+type factPost struct {
+	ID       uint
+	Title    string
+	Draft    bool
+	AuthorID uint
+	Author   *factAuthor
+}
+
+// newAuthors returns a new factory of authors named after their sequence number, with an "admin" state.
+func newAuthors() *Factory[factAuthor] {
+	return Define(func(n int) factAuthor {
+		return factAuthor{Name: fmt.Sprintf("author %d", n), Role: "member"}
+	}).State("admin", func(a *factAuthor) {
+		a.Role = "admin"
+	})
+}
+
+// newPosts returns a new factory of published posts titled after their sequence number, with a "draft" state.
+func newPosts() *Factory[factPost] {
+	return Define(func(n int) factPost {
+		return factPost{Title: fmt.Sprintf("post %d", n)}
+	}).State("draft", func(p *factPost) {
+		p.Draft = true
+	})
+}
+
+// formatAuthors formats authors as "author 1 member [post 1,post 2]".
+func formatAuthors(authors []factAuthor) string {
+	var formatted []string
+	for _, a := range authors {
+		var titles []string
+		for _, p := range a.Posts {
+			title := p.Title
+			if p.Draft {
+				title += " (draft)"
+			}
+			titles = append(titles, title)
+		}
+		formatted = append(formatted, fmt.Sprintf("%s %s [%s]", a.Name, a.Role, strings.Join(titles, ",")))
+	}
+	return strings.Join(formatted, " | ")
+}
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory func() *Factory[factAuthor]
+		n       int
+		want    string
+		wantErr string
+	}{
+		{"definition", newAuthors, 2, "author 1 member [] | author 2 member []", ""},
+		{"none", newAuthors, 0, "", ""},
+		{"state", func() *Factory[factAuthor] { return newAuthors().Apply("admin") }, 1, "author 1 admin []", ""},
+		{"unknown state", func() *Factory[factAuthor] { return newAuthors().Apply("owner", "admin") }, 1, "", `factory of factAuthor has no "owner" state`},
+		{"sequence", func() *Factory[factAuthor] {
+			return newAuthors().Sequence(
+				func(a *factAuthor) { a.Role = "a" },
+				func(a *factAuthor) { a.Role = "b" },
+			)
+		}, 3, "author 1 a [] | author 2 b [] | author 3 a []", ""},
+		{"override after a state", func() *Factory[factAuthor] {
+			return newAuthors().Apply("admin").Override(func(a *factAuthor) { a.Role = "owner" })
+		}, 1, "author 1 owner []", ""},
+		{"state after an override", func() *Factory[factAuthor] {
+			return newAuthors().Override(func(a *factAuthor) { a.Role = "owner" }).Apply("admin")
+		}, 1, "author 1 admin []", ""},
+		{"has many", func() *Factory[factAuthor] { return newAuthors().Has("Posts", 2, newPosts()) }, 2,
+			"author 1 member [post 1,post 2] | author 2 member [post 3,post 4]", ""},
+		{"has many in a state", func() *Factory[factAuthor] { return newAuthors().Has("Posts", 1, newPosts().Apply("draft")) }, 1,
+			"author 1 member [post 1 (draft)]", ""},
+		{"related factory in an unknown state", func() *Factory[factAuthor] { return newAuthors().Has("Posts", 1, newPosts().Apply("archived")) }, 1,
+			"", `factory of factPost has no "archived" state`},
+		{"unknown relation", func() *Factory[factAuthor] { return newAuthors().Has("Comments", 1, newPosts()) }, 1,
+			"", "factAuthor has no Comments field"},
+		{"relation of another model", func() *Factory[factAuthor] { return newAuthors().Has("Posts", 1, newAuthors()) }, 1,
+			"", "cannot assign a factAuthor to the Posts field of factAuthor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authors, err := tt.factory().Make(tt.n)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatAuthors(authors); got != tt.want {
+				t.Errorf("made %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDerivedFactories makes sure deriving a factory leaves the receiver unchanged, and shares its sequence.
+func TestDerivedFactories(t *testing.T) {
+	authors := newAuthors()
+	admins := authors.Apply("admin")
+	authors.State("owner", func(a *factAuthor) { a.Role = "owner" })
+
+	for _, tt := range []struct {
+		factory *Factory[factAuthor]
+		want    string
+		wantErr bool
+	}{
+		{authors, "author 1 member []", false},
+		{admins, "author 2 admin []", false},
+		{authors, "author 3 member []", false},
+		// The state declared on a derived factory is not declared on authors.
+		{authors.Apply("owner"), "", true},
+	} {
+		made, err := tt.factory.Make(1)
+		if (err != nil) != tt.wantErr || formatAuthors(made) != tt.want {
+			t.Errorf("made %q, %v, want %q", formatAuthors(made), err, tt.want)
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	posts, err := newPosts().For("Author", newAuthors().Apply("admin")).Make(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range posts {
+		if p.Author == nil || p.Author.Name != fmt.Sprintf("author %d", i+1) || p.Author.Role != "admin" {
+			t.Errorf("author of %s = %+v, want an admin of its own", p.Title, p.Author)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		create      func(m model.Model) error
+		wantAuthors string
+		wantPosts   string
+		wantErr     bool
+	}{
+		{
+			name: "records",
+			create: func(m model.Model) error {
+				_, err := newAuthors().Create(m, 2)
+				return err
+			},
+			wantAuthors: "author 1 member [] | author 2 member []",
+		},
+		{
+			name: "records with related records",
+			create: func(m model.Model) error {
+				_, err := newAuthors().Has("Posts", 3, newPosts()).CreateOne(m)
+				return err
+			},
+			wantAuthors: "author 1 member [post 1,post 2,post 3]",
+			wantPosts:   "post 1 by 1,post 2 by 1,post 3 by 1",
+		},
+		{
+			name: "records with parents",
+			create: func(m model.Model) error {
+				_, err := newPosts().For("Author", newAuthors()).Create(m, 2)
+				return err
+			},
+			wantAuthors: "author 1 member [post 1] | author 2 member [post 2]",
+			wantPosts:   "post 1 by 1,post 2 by 2",
+		},
+		{
+			name: "unknown state",
+			create: func(m model.Model) error {
+				_, err := newAuthors().Apply("owner").Create(m, 2)
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			// Both authors have the ID 1, so the insert fails and no author is stored.
+			name: "failed batch",
+			create: func(m model.Model) error {
+				_, err := newAuthors().Override(func(a *factAuthor) { a.ID = 1 }).Create(m, 2)
+				return err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, &factAuthor{}, &factPost{})
+			if err := tt.create(model.NewModel(db)); (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantErr)
+			}
+
+			var authors []factAuthor
+			if err := db.Preload("Posts").Order("id").Find(&authors).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got := formatAuthors(authors); got != tt.wantAuthors {
+				t.Errorf("stored authors %q, want %q", got, tt.wantAuthors)
+			}
+			var posts []factPost
+			if err := db.Order("id").Find(&posts).Error; err != nil {
+				t.Fatal(err)
+			}
+			var stored []string
+			for _, p := range posts {
+				stored = append(stored, fmt.Sprintf("%s by %d", p.Title, p.AuthorID))
+			}
+			if got := strings.Join(stored, ","); got != tt.wantPosts {
+				t.Errorf("stored posts %q, want %q", got, tt.wantPosts)
+			}
+		})
+	}
+}
+
+func TestCreatedState(t *testing.T) {
+	errListing := errors.New("listing failed")
+	var listed []uint
+	authors := newAuthors().CreatedState("listed", func(a *factAuthor) error {
+		listed = append(listed, a.ID)
+		return nil
+	}).CreatedState("unlisted", func(a *factAuthor) error {
+		return errListing
+	})
+
+	tests := []struct {
+		name       string
+		build      func(m model.Model) error
+		wantListed string
+		wantErr    error
+	}{
+		{"created", func(m model.Model) error { _, err := authors.Apply("listed").Create(m, 2); return err }, "[1 2]", nil},
+		{"created with another state", func(m model.Model) error { _, err := authors.Apply("listed", "admin").Create(m, 1); return err }, "[1]", nil},
+		{"made", func(m model.Model) error { _, err := authors.Apply("listed").Make(2); return err }, "[]", nil},
+		{"not applied", func(m model.Model) error { _, err := authors.Create(m, 2); return err }, "[]", nil},
+		{"failing", func(m model.Model) error { _, err := authors.Apply("unlisted").Create(m, 1); return err }, "[]", errListing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed = []uint{}
+			if err := tt.build(model.NewModel(testdb.Open(t, &factAuthor{}, &factPost{}))); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := fmt.Sprint(listed); got != tt.wantListed {
+				t.Errorf("listed %s, want %s", got, tt.wantListed)
+			}
+		})
+	}
+}
+
+func TestUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		make         func() (model.User, error)
+		wantPassword string
+		wantVerified bool
+	}{
+		{"default", User.MakeOne, "password", true},
+		{"unverified", User.Apply("unverified").MakeOne, "password", false},
+		{"password", User.Override(Password("secret")).MakeOne, "secret", true},
+		{"deprecated generate", func() (model.User, error) {
+			user, err := (&UserFactory{}).Generate("secret")
+			if err != nil {
+				return model.User{}, err
+			}
+			return *user, nil
+		}, "secret", false},
+	}
+
+	emails := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := tt.make()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Name == "" || !strings.Contains(user.Email, "@") || emails[user.Email] {
+				t.Errorf("user %q <%s> has no fake name or unique email", user.Name, user.Email)
+			}
+			emails[user.Email] = true
+			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(tt.wantPassword)); err != nil {
+				t.Errorf("password is not the hash of %q: %v", tt.wantPassword, err)
+			}
+			if verified := user.VerifiedAt != nil; verified != tt.wantVerified {
+				t.Errorf("verified = %v, want %v", verified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestAdminUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, &model.User{})
+	m := model.NewModel(db)
+
+	tests := []struct {
+		name      string
+		admins    string
+		make      func() (model.User, error)
+		wantAdmin bool
+		// wantAdmins is ADMIN_USER_IDS after the user is made, with %d standing for the ID of the user.
+		wantAdmins string
+	}{
+		{"admin", "", func() (model.User, error) { return User.Apply("admin").CreateOne(m) }, true, "%d"},
+		{"admin added to the listed ones", "40, 41", func() (model.User, error) { return User.Apply("admin").CreateOne(m) }, true, "40, 41,%d"},
+		{"unverified admin", "", func() (model.User, error) { return User.Apply("unverified", "admin").CreateOne(m) }, true, "%d"},
+		{"user", "40", func() (model.User, error) { return User.CreateOne(m) }, false, "40"},
+		// The ID of a user that is not saved is unknown, so Make leaves the admin state out.
+		{"admin made in memory", "", User.Apply("admin").MakeOne, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMIN_USER_IDS", tt.admins)
+			user, err := tt.make()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantAdmins := tt.wantAdmins
+			if strings.Contains(wantAdmins, "%d") {
+				wantAdmins = fmt.Sprintf(wantAdmins, user.ID)
+			}
+			if admins := os.Getenv("ADMIN_USER_IDS"); admins != wantAdmins {
+				t.Errorf("ADMIN_USER_IDS = %q, want %q", admins, wantAdmins)
+			}
+
+			recorder := httptest.NewRecorder()
+			router := gin.New()
+			router.GET("/admin", func(c *gin.Context) { c.Set("currentUser", user) }, middleware.Admin(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+			if admin := recorder.Code == http.StatusOK; admin != tt.wantAdmin {
+				t.Errorf("the Admin middleware answered %d, want the user to be an administrator: %v", recorder.Code, tt.wantAdmin)
+			}
+		})
+	}
+
+	// Adding an administrator twice lists it once.
+	t.Setenv("ADMIN_USER_IDS", "7")
+	if err := AddAdmin(&model.User{Model: gorm.Model{ID: 7}}); err != nil || os.Getenv("ADMIN_USER_IDS") != "7" {
+		t.Errorf("ADMIN_USER_IDS = %q, %v, want 7", os.Getenv("ADMIN_USER_IDS"), err)
+	}
+}
@@ -2,30 +2,100 @@ package factory
 
 import (
 	"GoAPIfy/model"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// User is the factory of verified users with a fake name, a unique email and the password "password".
+// The "unverified" state leaves the email unverified; set another password with Password.
+// The "admin" state makes the created users administrators with AddAdmin. It only applies to Create, since the ID
+// of a user is only known once it is saved.
+//
+// Example usage:
+//
+//	users, err := factory.User.Create(s.Model, 10)
+//	user, err := factory.User.Apply("unverified").Override(factory.Password("secret")).MakeOne()
+//	admin, err := factory.User.Apply("admin").CreateOne(s.Model)
+var User = Define(func(n int) model.User {
+	verifiedAt := time.Now()
+	return model.User{
+		Name:       faker.Name(),
+		Email:      uniqueEmail(faker.Email(), n),
+		Password:   defaultPassword(),
+		VerifiedAt: &verifiedAt,
+	}
+}).State("unverified", func(u *model.User) {
+	u.VerifiedAt = nil
+}).CreatedState("admin", AddAdmin)
+
+// AddAdmin lists the ID of the user in ADMIN_USER_IDS, the administrators middleware.Admin lets through.
+// It changes the environment of the current process only, such as the one of the tests: the administrators of
+// a server are the ones of its own environment.
+func AddAdmin(u *model.User) error {
+	id := strconv.FormatUint(uint64(u.ID), 10)
+	admins := os.Getenv("ADMIN_USER_IDS")
+	for _, admin := range strings.Split(admins, ",") {
+		if strings.TrimSpace(admin) == id {
+			return nil
+		}
+	}
+	if strings.TrimSpace(admins) != "" {
+		id = admins + "," + id
+	}
+	return os.Setenv("ADMIN_USER_IDS", id)
+}
+
+// defaultPassword returns the bcrypt hash of "password", computed once since hashing is deliberately slow.
+var defaultPassword = func() func() string {
+	var once sync.Once
+	var hash string
+	return func() string {
+		once.Do(func() { hash = hashPassword("password") })
+		return hash
+	}
+}()
+
+// Password returns an override setting the password of the users to the bcrypt hash of the given one.
+func Password(password string) func(*model.User) {
+	hash := hashPassword(password)
+	return func(u *model.User) {
+		u.Password = hash
+	}
+}
+
+// uniqueEmail prefixes the local part of a fake email with the sequence number of the record, since faker may repeat emails.
+func uniqueEmail(email string, n int) string {
+	return fmt.Sprintf("%d.%s", n, email)
+}
+
+// hashPassword returns the bcrypt hash of the given password.
+func hashPassword(password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+}
+
 // UserFactory is a factory that generates User models with randomized data.
+//
+// Deprecated: use the User factory, which also saves the users and supports states and overrides.
 type UserFactory struct{}
 
 // Generate generates a new User model with randomized data.
+//
+// Deprecated: use User.Override(Password(password)).MakeOne().
 func (f *UserFactory) Generate(password string) (*model.User, error) {
-	// Generate a random name and email address
-	name := faker.Name()
-	email := faker.Email()
-
-	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	user, err := User.Override(Password(password)).Apply("unverified").MakeOne()
 	if err != nil {
 		return nil, err
 	}
-
-	// Return a new User instance with the randomized data
-	return &model.User{
-		Name:     name,
-		Email:    email,
-		Password: string(hashedPassword),
-	}, nil
+	return &user, nil
 }
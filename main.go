@@ -2,26 +2,34 @@ package main
 
 import (
 	"GoAPIfy/config"
+	"GoAPIfy/core/cache"
 	"GoAPIfy/core/database"
 	"GoAPIfy/core/helper"
+	"GoAPIfy/core/migrator"
+	"GoAPIfy/core/search"
 	"GoAPIfy/core/service"
 	"GoAPIfy/cron"
+	"GoAPIfy/middleware"
+	"GoAPIfy/migration"
 	"GoAPIfy/model"
+	"GoAPIfy/observer"
 	"GoAPIfy/route"
-	"GoAPIfy/seeder"
 	"GoAPIfy/service/appService"
 	logo "GoAPIfy/tools/core"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"github.com/meilisearch/meilisearch-go"
-	"gorm.io/gorm"
 )
 
 func main() {
@@ -33,21 +41,12 @@ func main() {
 	}
 
 	// Get the configuration from the environment variables
-	databaseType := os.Getenv("DATABASE_TYPE")
 	productionStr := os.Getenv("APP_PRODUCTION")
 	production, err := strconv.ParseBool(productionStr)
 	if err != nil {
 		log.Fatal(helper.ColorizeCmd(helper.Green, "Error converting APP_PRODUCTION to boolean."))
 	}
 
-	// Connecting to Meilisearch Server and initialize
-	fmt.Println(helper.ColorizeCmd(helper.Green, "Connecting to Meilisearch..."))
-	meilisearchClient := meilisearch.NewClient(meilisearch.ClientConfig{
-		Host:   "http://127.0.0.1:7700",
-		APIKey: os.Getenv("MEILI_MASTER_KEY"),
-	})
-	fmt.Println(helper.ColorizeCmd(helper.Green, "Meilisearch initialized..."))
-
 	// Print a message to indicate that the web server is being deployed
 	fmt.Println(helper.ColorizeCmd(helper.Green, "Deploying Web Server..."))
 
@@ -57,54 +56,81 @@ func main() {
 	// Set up Cross-Origin Resource Sharing (CORS)
 	server.Use(cors.New(cors.Config{
 		AllowOrigins:     config.AllowOriginConfig(),
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Query-Count", "X-Query-Time"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Record the queries of every request, report suspected N+1 queries, and outside of production
+	// send the query count and time in the X-Query-Count and X-Query-Time response headers
+	server.Use(middleware.QueryLog(production))
+
 	// Print a message to indicate that the server configuration has been loaded
 	fmt.Println(helper.ColorizeCmd(helper.Blue, "Configuration loaded."))
 
 	// Print a message to indicate that the server is connecting to the database
 	fmt.Println(helper.ColorizeCmd(helper.Green, "Connect to database..."))
 
-	var db *gorm.DB
-	switch databaseType {
-	case "mysql", "mariadb":
-		db, err = database.InitMysql(production)
-	case "postgres", "postgresql":
-		db, err = database.InitPostgres(production)
-	case "sqlite":
-		db, err = database.InitSQLite(production)
-	case "mssql", "sqlserver":
-		db, err = database.InitMSSQL(production)
-	default:
-		log.Fatal("DATABASE_TYPE is not supported. Make sure you have configure your database correctly.")
+	// Open the main database and every other connection declared in DB_CONNECTIONS
+	connections, err := database.InitConnections(production)
+	if err != nil {
+		log.Fatal(err)
 	}
+	db := connections.Default()
 
 	// Initialize Redis client
 	fmt.Println(helper.ColorizeCmd(helper.Green, "Connecting to Redis (if enabled)..."))
 	redisClient := database.InitRedis()
 
-	// Print a message to indicate that the models are being migrated
-	fmt.Println(helper.ColorizeCmd(helper.Green, "Migrating models..."))
+	// Check the database schema of every connection targeted by a migration against the registered migrations.
+	// In production the server refuses to boot with pending migrations, run `apify migrate` first.
+	// In development pending migrations are applied automatically.
+	for _, name := range migrator.Connections(migration.All()) {
+		conn, err := connections.Get(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		schemaMigrator := migrator.NewForConnection(name, conn, migration.All())
+		if production {
+			fmt.Println(helper.ColorizeCmd(helper.Green, fmt.Sprintf("Checking migrations of the %s connection...", name)))
+			if err := schemaMigrator.EnsureUpToDate(); err != nil {
+				log.Fatal(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Refusing to start, %s on the %s connection. Run `apify migrate` first.", err, name)))
+			}
+		} else {
+			fmt.Println(helper.ColorizeCmd(helper.Green, fmt.Sprintf("Migrating models of the %s connection...", name)))
+			ran, err := schemaMigrator.Migrate()
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, id := range ran {
+				fmt.Println(helper.ColorizeCmd(helper.Green, fmt.Sprintf("Migrated: %s", id)))
+			}
+		}
+	}
 
-	// Automatically migrate the models to the database schema
-	model.AutoMigration(db)
+	// Initialize the search engine selected by SEARCH_DRIVER
+	fmt.Println(helper.ColorizeCmd(helper.Green, "Initializing search engine..."))
+	searchEngine, err := search.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Loading modelService
-	modelService := model.NewModel(db)
+	// Loading modelService, query results are cached in Redis when it is enabled and in memory otherwise,
+	// the indexes of searchable models are kept up to date in the search engine,
+	// and the named connections can be selected with Model.On
+	modelService := model.NewModel(db).WithCache(cache.New(redisClient)).WithSearch(searchEngine).WithConnections(connections)
 
-	appService := appService.AppService{Model: modelService, MeiliSearch: meilisearchClient, Redis: redisClient}
+	appService := appService.AppService{Model: modelService, Search: searchEngine, Redis: redisClient}
 
 	// Initialize Cron Jobs
 	fmt.Println(helper.ColorizeCmd(helper.Magenta, "Initialize Cron Jobs"))
 	cron := cron.NewCron(appService)
 	cron.Start()
 
-	seeder.RegisterSeeders(appService)
+	// Register the model lifecycle observers before anything writes to the database
+	observer.RegisterObservers(appService)
 
 	// Define the API routes
 	fmt.Println(helper.ColorizeCmd(helper.Green, "Defining routes..."))
@@ -121,6 +147,26 @@ func main() {
 	// Print a message to indicate that the web server is starting
 	fmt.Println(helper.ColorizeCmd(helper.Green, "Starting Web Server..."))
 
-	// Start the web server on port 8000
-	server.Run(":8000")
+	// Start the web server on port 8000 until the process is interrupted or terminated
+	httpServer := &http.Server{Addr: ":8000", Handler: server}
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-stop.Done()
+
+	// Let the requests in progress finish, then send the queued search index updates before exiting
+	fmt.Println(helper.ColorizeCmd(helper.Yellow, "Shutting down Web Server..."))
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		fmt.Println(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Web Server shutdown failed: %s", err)))
+	}
+	if err := appService.Search.Close(); err != nil {
+		fmt.Println(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Search index update failed: %s", err)))
+	}
+	fmt.Println(helper.ColorizeCmd(helper.Green, "Web Server stopped."))
 }
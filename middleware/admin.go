@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"GoAPIfy/core"
+	"GoAPIfy/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin is a middleware that only lets administrators through, the users whose ID is listed in the comma separated
+// ADMIN_USER_IDS environment variable. Every other request gets a 403 Forbidden response, and so does every request
+// when ADMIN_USER_IDS is empty. The user is taken from the "currentUser" set by the Authentication middleware,
+// so register Admin after it.
+func Admin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser, ok := c.Get("currentUser")
+		user, isUser := currentUser.(model.User)
+		if !ok || !isUser || !isAdmin(user) {
+			errorMessage := core.FormatError(errors.New("access denied : administrators only!"))
+			core.SendResponse(c, http.StatusForbidden, errorMessage)
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the ID of the user is listed in ADMIN_USER_IDS.
+func isAdmin(user model.User) bool {
+	id := fmt.Sprint(user.ID)
+	for _, admin := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && admin == id {
+			return true
+		}
+	}
+	return false
+}
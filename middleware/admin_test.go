@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"GoAPIfy/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		adminIDs    string
+		currentUser interface{}
+		wantStatus  int
+	}{
+		{"listed administrator", "3, 7", model.User{Model: gorm.Model{ID: 7}}, http.StatusOK},
+		{"user that is not listed", "3,7", model.User{Model: gorm.Model{ID: 5}}, http.StatusForbidden},
+		{"prefix of a listed ID", "17", model.User{Model: gorm.Model{ID: 1}}, http.StatusForbidden},
+		{"no administrators configured", "", model.User{Model: gorm.Model{ID: 1}}, http.StatusForbidden},
+		{"unauthenticated request", "1", nil, http.StatusForbidden},
+		{"current user of another type", "1", "1", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMIN_USER_IDS", tt.adminIDs)
+			router := gin.New()
+			router.GET("/admin", func(c *gin.Context) {
+				if tt.currentUser != nil {
+					c.Set("currentUser", tt.currentUser)
+				}
+			}, Admin(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"GoAPIfy/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Audit is a middleware that stores who makes the request in the request context, so the changes of Auditable
+// models made with s.Model.WithContext(c.Request.Context()) record the acting user, IP address, user agent and request ID.
+// The user is taken from the "currentUser" set by the Authentication middleware, so register Audit after it.
+// The request ID is taken from the "X-Request-ID" header, or generated when the client did not send one,
+// and is echoed in the "X-Request-ID" response header.
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+		c.Header("X-Request-ID", requestID)
+
+		actor := model.AuditActor{
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: requestID,
+		}
+		if currentUser, ok := c.Get("currentUser"); ok {
+			if user, ok := currentUser.(model.User); ok {
+				actor.UserID = &user.ID
+			}
+		}
+
+		c.Request = c.Request.WithContext(model.WithAuditActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"GoAPIfy/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name          string
+		requestID     string
+		currentUser   interface{}
+		wantRequestID string
+		wantUserID    uint
+	}{
+		{"request ID of the client", "req-1", nil, "req-1", 0},
+		{"generated request ID", "", nil, "", 0},
+		{"request ID that is too long", strings.Repeat("x", 65), nil, "", 0},
+		{"authenticated user", "req-2", model.User{Model: gorm.Model{ID: 7}}, "req-2", 7},
+		{"current user of another type", "req-3", "7", "req-3", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actor model.AuditActor
+			var found bool
+			router := gin.New()
+			router.GET("/invoices", func(c *gin.Context) {
+				if tt.currentUser != nil {
+					c.Set("currentUser", tt.currentUser)
+				}
+			}, Audit(), func(c *gin.Context) {
+				actor, found = model.AuditActorFromContext(c.Request.Context())
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+			request.RemoteAddr = "192.0.2.1:1234"
+			request.Header.Set("User-Agent", "test-agent")
+			if tt.requestID != "" {
+				request.Header.Set("X-Request-ID", tt.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if !found {
+				t.Fatal("the request context carries no audit actor")
+			}
+			if actor.IPAddress != "192.0.2.1" || actor.UserAgent != "test-agent" {
+				t.Errorf("actor = %+v, want the IP address and user agent of the request", actor)
+			}
+			if tt.wantRequestID != "" && actor.RequestID != tt.wantRequestID {
+				t.Errorf("request ID = %q, want %q", actor.RequestID, tt.wantRequestID)
+			}
+			if len(actor.RequestID) == 0 || len(actor.RequestID) > 64 || recorder.Header().Get("X-Request-ID") != actor.RequestID {
+				t.Errorf("request ID = %q, echoed as %q", actor.RequestID, recorder.Header().Get("X-Request-ID"))
+			}
+			if (actor.UserID == nil) != (tt.wantUserID == 0) || (actor.UserID != nil && *actor.UserID != tt.wantUserID) {
+				t.Errorf("user ID = %v, want %d", actor.UserID, tt.wantUserID)
+			}
+		})
+	}
+}
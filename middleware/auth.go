@@ -60,17 +60,21 @@ func Authentication(authService auth.AuthService, s appService.AppService) gin.H
 			return
 		}
 
-		subFloat, ok := sub.(float64)
-		if !ok {
-			errorMessage := core.FormatError(errors.New("access denied : user claim is not a number!"))
+		// The subject is a number for auto-increment IDs, and a string for UUIDModel and ULIDModel IDs
+		var userID interface{}
+		switch value := sub.(type) {
+		case float64:
+			userID = uint(value)
+		case string:
+			userID = value
+		}
+		if userID == nil || userID == "" {
+			errorMessage := core.FormatError(errors.New("access denied : user claim is not a valid id!"))
 			core.SendResponse(c, http.StatusUnauthorized, errorMessage)
 			return
 		}
 
-		userID := uint(subFloat)
-
-		var userModel model.User
-		err = s.Model.Load(&userModel).Find(userID)
+		userModel, err := model.NewRepository[model.User](s.Model).WithContext(c.Request.Context()).Find(userID)
 		if err != nil {
 			errorMessage := core.FormatError(errors.New("access denied : user is unauthorized!"))
 			core.SendResponse(c, http.StatusUnauthorized, errorMessage)
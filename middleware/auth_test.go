@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"GoAPIfy/internal/testdb"
+	"GoAPIfy/model"
+	"GoAPIfy/service/appService"
+	"GoAPIfy/service/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const key = "0123456789abcdef0123456789abcdef"
+	db := testdb.Open(t, &model.User{})
+	verifiedAt := time.Now()
+	for _, user := range []model.User{{Name: "ann", VerifiedAt: &verifiedAt}, {Name: "bob"}} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := appService.AppService{Model: model.NewModel(db)}
+	authService := &auth.JWTService{SigningKey: []byte(key)}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantUser      string
+	}{
+		{"number subject", "Bearer " + signedToken(t, key, jwt.MapClaims{"sub": 1}), http.StatusOK, "ann"},
+		// The subject of a UUIDModel or ULIDModel user is a string.
+		{"string subject", "Bearer " + signedToken(t, key, jwt.MapClaims{"sub": "1"}), http.StatusOK, "ann"},
+		{"unverified user", "Bearer " + signedToken(t, key, jwt.MapClaims{"sub": 2}), http.StatusUnauthorized, ""},
+		{"unknown user", "Bearer " + signedToken(t, key, jwt.MapClaims{"sub": "01ARZ3NDEKTSV4RRFFQ69G5FAV"}), http.StatusUnauthorized, ""},
+		{"empty subject", "Bearer " + signedToken(t, key, jwt.MapClaims{"sub": ""}), http.StatusUnauthorized, ""},
+		{"subject of another type", "Bearer " + signedToken(t, key, jwt.MapClaims{"sub": true}), http.StatusUnauthorized, ""},
+		{"missing subject", "Bearer " + signedToken(t, key, jwt.MapClaims{"name": "ann"}), http.StatusUnauthorized, ""},
+		{"forged token", "Bearer " + signedToken(t, "another key of at least 32 characters", jwt.MapClaims{"sub": 1}), http.StatusUnauthorized, ""},
+		{"missing token", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var currentUser string
+			router := gin.New()
+			router.GET("/me", Authentication(authService, s), func(c *gin.Context) {
+				currentUser = c.MustGet("currentUser").(model.User).Name
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus || currentUser != tt.wantUser {
+				t.Errorf("status = %d with the user %q, want %d with %q", recorder.Code, currentUser, tt.wantStatus, tt.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"GoAPIfy/core/database"
+	"GoAPIfy/core/helper"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryLog is a middleware that records the queries of every request, made with s.Model.WithContext(c.Request.Context()),
+// in a database.QueryLog stored in the request context and in the gin context under "queryLog".
+// At the end of the request every SELECT shape repeated at least DATABASE_N_PLUS_ONE_THRESHOLD times (5 by default,
+// 0 disables the check) is reported as a suspected N+1 query. Outside of production the number of queries and
+// the time spent in them are sent in the "X-Query-Count" and "X-Query-Time" (milliseconds) response headers.
+// Register it before the middlewares that replace the request context, such as Tenant and Audit.
+func QueryLog(production bool) gin.HandlerFunc {
+	threshold := 5
+	if count, err := strconv.Atoi(os.Getenv("DATABASE_N_PLUS_ONE_THRESHOLD")); err == nil && count >= 0 {
+		threshold = count
+	}
+
+	return func(c *gin.Context) {
+		queries := database.NewQueryLog()
+		c.Set("queryLog", queries)
+		c.Request = c.Request.WithContext(database.WithQueryLog(c.Request.Context(), queries))
+
+		if !production {
+			// Headers must be set before the body is written, which usually happens inside the handler.
+			writer := &queryLogWriter{ResponseWriter: c.Writer, queries: queries}
+			c.Writer = writer
+			defer writer.stamp()
+		}
+		c.Next()
+
+		for _, repeated := range queries.Repeated(threshold) {
+			fmt.Println(helper.ColorizeCmd(helper.Yellow, fmt.Sprintf("Possible N+1 query on %s %s, run %d times from %s: %s",
+				c.Request.Method, c.FullPath(), repeated.Count, strings.Join(repeated.Callers, ", "), repeated.Shape)))
+		}
+	}
+}
+
+// QueryLogFrom returns the query log of the request, or nil when the QueryLog middleware is not registered.
+func QueryLogFrom(c *gin.Context) *database.QueryLog {
+	queries, _ := c.Get("queryLog")
+	log, _ := queries.(*database.QueryLog)
+	return log
+}
+
+// queryLogWriter adds the query headers to the response right before its header is written.
+type queryLogWriter struct {
+	gin.ResponseWriter
+	queries *database.QueryLog
+}
+
+// stamp sets the query headers, unless the header of the response has already been written.
+func (w *queryLogWriter) stamp() {
+	if w.Written() {
+		return
+	}
+	w.Header().Set("X-Query-Count", strconv.Itoa(w.queries.Count()))
+	w.Header().Set("X-Query-Time", strconv.FormatFloat(float64(w.queries.Duration())/float64(time.Millisecond), 'f', 2, 64))
+}
+
+func (w *queryLogWriter) WriteHeaderNow() {
+	w.stamp()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryLogWriter) Write(data []byte) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queryLogWriter) WriteString(s string) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"GoAPIfy/core/database"
+	"GoAPIfy/internal/testdb"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// queryPost is the table queried by the query log tests.
+type queryPost struct {
+	ID     uint
+	UserID uint
+}
+
+// captureStdout returns what fn prints on the standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		var buffer bytes.Buffer
+		_, _ = io.Copy(&buffer, reader)
+		printed <- buffer.String()
+	}()
+	fn()
+	writer.Close()
+	return <-printed
+}
+
+// loadPosts loads the posts of the given users one user at a time, the N+1 pattern.
+func loadPosts(ctx context.Context, db *gorm.DB, users int) error {
+	for userID := 1; userID <= users; userID++ {
+		if err := db.WithContext(ctx).Where("user_id = ?", userID).Find(&[]queryPost{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestQueryLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, &queryPost{})
+	if err := database.UseQueryLogger(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		production bool
+		threshold  string
+		users      int
+		// writeBody makes the handler write a body, otherwise the header is written after the handlers.
+		writeBody   bool
+		wantHeaders bool
+		wantCount   int
+		wantNPlus1  bool
+	}{
+		{name: "headers", users: 2, writeBody: true, wantHeaders: true, wantCount: 2},
+		{name: "headers without a body", users: 1, wantHeaders: true, wantCount: 1},
+		{name: "no headers in production", production: true, users: 2, writeBody: true, wantCount: 2},
+		{name: "N+1 query", users: 5, writeBody: true, wantHeaders: true, wantCount: 5, wantNPlus1: true},
+		{name: "N+1 query in production", production: true, users: 5, wantCount: 5, wantNPlus1: true},
+		{name: "below the threshold", threshold: "6", users: 5, wantHeaders: true, wantCount: 5},
+		{name: "lower threshold", threshold: "2", users: 2, wantHeaders: true, wantCount: 2, wantNPlus1: true},
+		{name: "check disabled", threshold: "0", users: 8, wantHeaders: true, wantCount: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DATABASE_N_PLUS_ONE_THRESHOLD", tt.threshold)
+			var queries *database.QueryLog
+			router := gin.New()
+			router.GET("/posts", QueryLog(tt.production), func(c *gin.Context) {
+				queries = QueryLogFrom(c)
+				if err := loadPosts(c.Request.Context(), db, tt.users); err != nil {
+					c.AbortWithStatus(http.StatusInternalServerError)
+					return
+				}
+				if tt.writeBody {
+					c.JSON(http.StatusOK, gin.H{"posts": []string{}})
+				}
+			})
+
+			recorder := httptest.NewRecorder()
+			printed := captureStdout(t, func() {
+				router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/posts", nil))
+			})
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+			}
+			if queries == nil || queries.Count() != tt.wantCount {
+				t.Fatalf("query log = %v, want %d queries", queries, tt.wantCount)
+			}
+			for _, query := range queries.Queries() {
+				if !strings.Contains(query.Caller, "GoAPIfy/middleware.loadPosts") {
+					t.Errorf("caller = %q, want loadPosts", query.Caller)
+				}
+			}
+
+			count, queryTime := recorder.Header().Get("X-Query-Count"), recorder.Header().Get("X-Query-Time")
+			if tt.wantHeaders {
+				if milliseconds, err := strconv.ParseFloat(queryTime, 64); count != strconv.Itoa(tt.wantCount) || err != nil || milliseconds < 0 {
+					t.Errorf("headers = %q queries in %q ms, want %d queries", count, queryTime, tt.wantCount)
+				}
+			} else if count != "" || queryTime != "" {
+				t.Errorf("headers = %q queries in %q ms, want none", count, queryTime)
+			}
+
+			nPlus1 := strings.Contains(printed, "Possible N+1 query on GET /posts, run "+strconv.Itoa(tt.users)+" times from GoAPIfy/middleware.loadPosts")
+			if nPlus1 != tt.wantNPlus1 {
+				t.Errorf("printed %q, want an N+1 report: %v", printed, tt.wantNPlus1)
+			}
+		})
+	}
+}
+
+func TestQueryLogFrom(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if queries := QueryLogFrom(c); queries != nil {
+		t.Errorf("QueryLogFrom without the middleware = %v, want nil", queries)
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"GoAPIfy/core"
+	"GoAPIfy/model"
+	"GoAPIfy/service/auth"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TenantResolver extracts the identifier of the tenant from a request, such as a subdomain or the value of a header.
+// It returns false when the request does not name a tenant this way.
+type TenantResolver func(c *gin.Context) (string, bool)
+
+// TenantLookup turns the identifier found by a TenantResolver into the id of the tenant.
+// It returns an error when no tenant has the given identifier.
+type TenantLookup func(ctx context.Context, identifier string) (uint, error)
+
+// TenantAuthorizer checks that the request may use the tenant it resolved, usually that the authenticated user
+// belongs to it. It returns an error when it may not.
+type TenantAuthorizer func(c *gin.Context, tenantID uint) error
+
+// Tenant is a middleware that resolves the tenant of the request and scopes the queries of the request to it.
+// It tries the given resolvers in order, passes the first identifier found to lookup and the tenant to authorize.
+// The tenant is stored in the context of the request, so queries run with s.Model.WithContext(c.Request.Context())
+// only see the records of the tenant, and in the "tenantID" key of the gin context.
+// A request that names no tenant is answered with 400 Bad Request, one that names an unknown tenant with 404 Not Found
+// and one that authorize refuses with 403 Forbidden.
+//
+// The subdomain and the header are chosen by the client, so authorize is required: it is what keeps a user from
+// reading the records of another tenant. Tenant panics when it is nil, since that is always a programming error.
+//
+// Example usage:
+//
+//	tenantGroup.Use(middleware.Tenant(middleware.TenantIDLookup,
+//		middleware.TenantClaimAuthorizer(authService, "tenant_id", middleware.TenantIDLookup),
+//		middleware.TenantFromSubdomain("example.com"),
+//	))
+func Tenant(lookup TenantLookup, authorize TenantAuthorizer, resolvers ...TenantResolver) gin.HandlerFunc {
+	if authorize == nil {
+		panic("middleware: Tenant needs a TenantAuthorizer")
+	}
+	return func(c *gin.Context) {
+		var identifier string
+		var found bool
+		for _, resolve := range resolvers {
+			if identifier, found = resolve(c); found {
+				break
+			}
+		}
+		if !found {
+			errorMessage := core.FormatError(errors.New("tenant is missing : the request does not name a tenant!"))
+			core.SendResponse(c, http.StatusBadRequest, errorMessage)
+			c.Abort()
+			return
+		}
+
+		tenantID, err := lookup(c.Request.Context(), identifier)
+		if err != nil {
+			errorMessage := core.FormatError(fmt.Errorf("tenant is unknown : %w", err))
+			core.SendResponse(c, http.StatusNotFound, errorMessage)
+			c.Abort()
+			return
+		}
+
+		if err := authorize(c, tenantID); err != nil {
+			errorMessage := core.FormatError(fmt.Errorf("access denied : %w", err))
+			core.SendResponse(c, http.StatusForbidden, errorMessage)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(model.WithTenant(c.Request.Context(), tenantID))
+		c.Set("tenantID", tenantID)
+		c.Next()
+	}
+}
+
+// TenantIDLookup is a TenantLookup for identifiers that are the numeric id of the tenant.
+func TenantIDLookup(ctx context.Context, identifier string) (uint, error) {
+	tenantID, err := strconv.ParseUint(identifier, 10, 0)
+	if err != nil || tenantID == 0 {
+		return 0, fmt.Errorf("%q is not a tenant id", identifier)
+	}
+	return uint(tenantID), nil
+}
+
+// TenantClaimAuthorizer is a TenantAuthorizer for users that belong to a single tenant, named by the given claim of
+// their JWT token: it only lets through the requests whose resolved tenant is the one of the claim, looked up with
+// the given lookup. The token is validated with the given auth service, so the claim cannot be forged by the client.
+func TenantClaimAuthorizer(authService auth.AuthService, claim string, lookup TenantLookup) TenantAuthorizer {
+	resolve := TenantFromClaim(authService, claim)
+	return func(c *gin.Context, tenantID uint) error {
+		identifier, found := resolve(c)
+		if !found {
+			return errors.New("the token names no tenant!")
+		}
+		userTenantID, err := lookup(c.Request.Context(), identifier)
+		if err != nil || userTenantID != tenantID {
+			return errors.New("the user does not belong to the tenant!")
+		}
+		return nil
+	}
+}
+
+// TenantFromSubdomain resolves the tenant from the subdomain of the given base domain the request was sent to,
+// such as "acme" for a request to acme.example.com with the base domain example.com.
+func TenantFromSubdomain(baseDomain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(c *gin.Context) (string, bool) {
+		host := strings.ToLower(c.Request.Host)
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		subdomain := strings.TrimSuffix(host, suffix)
+		if subdomain == host || subdomain == "" || strings.Contains(subdomain, ".") {
+			return "", false
+		}
+		return subdomain, true
+	}
+}
+
+// TenantFromHeader resolves the tenant from the value of the given request header, such as X-Tenant-ID.
+func TenantFromHeader(name string) TenantResolver {
+	return func(c *gin.Context) (string, bool) {
+		value := strings.TrimSpace(c.GetHeader(name))
+		return value, value != ""
+	}
+}
+
+// TenantFromClaim resolves the tenant from the given claim of the JWT token in the "Authorization" header.
+// The token is validated with the given auth service, so the claim cannot be forged by the client.
+func TenantFromClaim(authService auth.AuthService, claim string) TenantResolver {
+	return func(c *gin.Context) (string, bool) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return "", false
+		}
+		tokenData, err := authService.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			return "", false
+		}
+		claimsPtr, ok := tokenData.Claims.(*jwt.MapClaims)
+		if !ok {
+			return "", false
+		}
+
+		switch value := (*claimsPtr)[claim].(type) {
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64), true
+		case string:
+			return value, value != ""
+		default:
+			return "", false
+		}
+	}
+}
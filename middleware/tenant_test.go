@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"GoAPIfy/model"
+	"GoAPIfy/service/auth"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// signedToken returns a token signed with the given key that carries the given claims.
+func signedToken(t *testing.T, key string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// allowTenant is a TenantAuthorizer that lets every request through, for the tests of the resolvers.
+func allowTenant(*gin.Context, uint) error { return nil }
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const key = "0123456789abcdef0123456789abcdef"
+	authService := &auth.JWTService{SigningKey: []byte(key)}
+
+	tests := []struct {
+		name       string
+		host       string
+		headers    map[string]string
+		wantStatus int
+		wantTenant uint
+	}{
+		{"subdomain", "7.example.com", nil, http.StatusOK, 7},
+		{"subdomain with a port", "7.example.com:8080", nil, http.StatusOK, 7},
+		{"header", "example.com", map[string]string{"X-Tenant-ID": "4"}, http.StatusOK, 4},
+		{"claim", "example.com", map[string]string{"Authorization": "Bearer " + signedToken(t, key, jwt.MapClaims{"tenant_id": 9})}, http.StatusOK, 9},
+		{"claim wins over the header", "example.com", map[string]string{
+			"Authorization": "Bearer " + signedToken(t, key, jwt.MapClaims{"tenant_id": 9}),
+			"X-Tenant-ID":   "4",
+		}, http.StatusOK, 9},
+		{"forged claim falls back to the header", "example.com", map[string]string{
+			"Authorization": "Bearer " + signedToken(t, "another key of at least 32 characters", jwt.MapClaims{"tenant_id": 9}),
+			"X-Tenant-ID":   "4",
+		}, http.StatusOK, 4},
+		{"nested subdomain", "a.b.example.com", nil, http.StatusBadRequest, 0},
+		{"other domain", "7.example.org", nil, http.StatusBadRequest, 0},
+		{"no tenant", "example.com", nil, http.StatusBadRequest, 0},
+		{"unknown tenant", "acme.example.com", nil, http.StatusNotFound, 0},
+		{"tenant zero", "example.com", map[string]string{"X-Tenant-ID": "0"}, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextTenant, ginTenant uint
+			router := gin.New()
+			router.GET("/projects", Tenant(TenantIDLookup, allowTenant,
+				TenantFromClaim(authService, "tenant_id"),
+				TenantFromSubdomain("example.com"),
+				TenantFromHeader("X-Tenant-ID"),
+			), func(c *gin.Context) {
+				contextTenant, _ = model.TenantFromContext(c.Request.Context())
+				ginTenant = c.GetUint("tenantID")
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/projects", nil)
+			request.Host = tt.host
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if contextTenant != tt.wantTenant || ginTenant != tt.wantTenant {
+				t.Errorf("tenant = %d in the request context and %d in the gin context, want %d",
+					contextTenant, ginTenant, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestTenantClaimAuthorizer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const key = "0123456789abcdef0123456789abcdef"
+	authService := &auth.JWTService{SigningKey: []byte(key)}
+	bearer := func(key string, claims jwt.MapClaims) string { return "Bearer " + signedToken(t, key, claims) }
+
+	tests := []struct {
+		name          string
+		host          string
+		authorization string
+		wantStatus    int
+	}{
+		{"tenant of the user", "7.example.com", bearer(key, jwt.MapClaims{"tenant_id": 7}), http.StatusOK},
+		{"tenant of the user as a string", "7.example.com", bearer(key, jwt.MapClaims{"tenant_id": "7"}), http.StatusOK},
+		{"tenant of another user", "8.example.com", bearer(key, jwt.MapClaims{"tenant_id": 7}), http.StatusForbidden},
+		{"token without a tenant", "7.example.com", bearer(key, jwt.MapClaims{"sub": 1}), http.StatusForbidden},
+		{"forged token", "7.example.com", bearer("another key of at least 32 characters", jwt.MapClaims{"tenant_id": 7}), http.StatusForbidden},
+		{"no token", "7.example.com", "", http.StatusForbidden},
+		{"claim that is not a tenant", "7.example.com", bearer(key, jwt.MapClaims{"tenant_id": "acme"}), http.StatusForbidden},
+		// The tenant is looked up before it is authorized.
+		{"unknown tenant", "acme.example.com", bearer(key, jwt.MapClaims{"tenant_id": 7}), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			router := gin.New()
+			router.GET("/projects", Tenant(TenantIDLookup,
+				TenantClaimAuthorizer(authService, "tenant_id", TenantIDLookup),
+				TenantFromSubdomain("example.com"),
+			), func(c *gin.Context) {
+				reached = true
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/projects", nil)
+			request.Host = tt.host
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus || reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("status = %d and handler reached: %v, want %d", recorder.Code, reached, tt.wantStatus)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Tenant without an authorizer did not panic")
+		}
+	}()
+	Tenant(TenantIDLookup, nil, TenantFromHeader("X-Tenant-ID"))
+}
+
+func TestTenantIDLookup(t *testing.T) {
+	for _, identifier := range []string{"1", "42", strconv.FormatUint(uint64(^uint32(0)), 10)} {
+		if _, err := TenantIDLookup(context.Background(), identifier); err != nil {
+			t.Errorf("TenantIDLookup(%q) = %v", identifier, err)
+		}
+	}
+	for _, identifier := range []string{"", "0", "-1", "acme", "1.5"} {
+		if _, err := TenantIDLookup(context.Background(), identifier); err == nil {
+			t.Errorf("TenantIDLookup(%q) succeeded", identifier)
+		}
+	}
+}
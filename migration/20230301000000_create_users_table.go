@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"GoAPIfy/core/migrator"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m20230301000000User is the users table as this migration creates it. It is a frozen copy of model.User,
+// so later changes of the model never change what this migration does; they need a migration of their own.
+type m20230301000000User struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	Name       string
+	Email      string
+	Password   string
+	AvatarPath *string
+	VerifiedAt *time.Time
+}
+
+func (m20230301000000User) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(&migrator.Migration{
+		ID: "20230301000000_create_users_table",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&m20230301000000User{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("users")
+		},
+	})
+}
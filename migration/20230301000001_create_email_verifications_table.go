@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"GoAPIfy/core/migrator"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m20230301000001EmailVerification is the email_verifications table as this migration creates it, a frozen copy
+// of model.EmailVerification. User adds the foreign key constraint on user_id.
+type m20230301000001EmailVerification struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	UserID    uint
+	User      m20230301000000User
+	Token     string
+}
+
+func (m20230301000001EmailVerification) TableName() string {
+	return "email_verifications"
+}
+
+func init() {
+	register(&migrator.Migration{
+		ID: "20230301000001_create_email_verifications_table",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&m20230301000001EmailVerification{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("email_verifications")
+		},
+	})
+}
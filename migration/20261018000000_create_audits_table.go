@@ -0,0 +1,39 @@
+package migration
+
+import (
+	"GoAPIfy/core/migrator"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m20261018000000Audit is the audits table as this migration creates it, a frozen copy of model.Audit.
+type m20261018000000Audit struct {
+	ID            uint   `gorm:"primaryKey"`
+	AuditableType string `gorm:"size:255;not null;index:idx_audits_auditable"`
+	AuditableID   string `gorm:"size:64;not null;index:idx_audits_auditable"`
+	Event         string `gorm:"size:32;not null"`
+	OldValues     string `gorm:"type:text"`
+	NewValues     string `gorm:"type:text"`
+	UserID        *uint  `gorm:"index"`
+	IPAddress     string `gorm:"size:45"`
+	UserAgent     string `gorm:"size:512"`
+	RequestID     string `gorm:"size:64"`
+	CreatedAt     time.Time
+}
+
+func (m20261018000000Audit) TableName() string {
+	return "audits"
+}
+
+func init() {
+	register(&migrator.Migration{
+		ID: "20261018000000_create_audits_table",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&m20261018000000Audit{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("audits")
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"GoAPIfy/core/migrator"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m20261018000001SeederRun is the seeders_ran table as this migration creates it, a frozen copy of model.SeederRun.
+type m20261018000001SeederRun struct {
+	ID          uint      `gorm:"primaryKey"`
+	Seeder      string    `gorm:"size:255;uniqueIndex"`
+	Environment string    `gorm:"size:64"`
+	RanAt       time.Time `gorm:"not null"`
+}
+
+func (m20261018000001SeederRun) TableName() string {
+	return "seeders_ran"
+}
+
+func init() {
+	register(&migrator.Migration{
+		ID: "20261018000001_create_seeders_ran_table",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&m20261018000001SeederRun{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("seeders_ran")
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"GoAPIfy/core/migrator"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// m20261018000002SearchDocument is the search_documents table of the database search driver as this migration
+// creates it, a frozen copy of the document of search.Database.
+type m20261018000002SearchDocument struct {
+	ID         uint   `gorm:"primaryKey"`
+	IndexName  string `gorm:"size:191;not null;uniqueIndex:idx_search_documents_document"`
+	DocumentID string `gorm:"size:191;not null;uniqueIndex:idx_search_documents_document"`
+	Content    string `gorm:"type:text"`
+	Attributes string `gorm:"type:text"`
+}
+
+func (m20261018000002SearchDocument) TableName() string {
+	return "search_documents"
+}
+
+// The text index depends on the database: a GIN index on PostgreSQL, and an FTS5 table on SQLite when the driver is
+// built with FTS5 (go build -tags sqlite_fts5). The table is only used with SEARCH_DRIVER=database.
+func init() {
+	register(&migrator.Migration{
+		ID: "20261018000002_create_search_documents_table",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&m20261018000002SearchDocument{}); err != nil {
+				return err
+			}
+			switch tx.Dialector.Name() {
+			case "postgres":
+				return tx.Exec("CREATE INDEX IF NOT EXISTS idx_search_documents_content ON search_documents USING GIN (to_tsvector('simple', content))").Error
+			case "sqlite":
+				// Creating the table fails with "no such module: fts5" when the driver is built without FTS5.
+				probe := tx.Session(&gorm.Session{Logger: tx.Logger.LogMode(logger.Silent)})
+				probe.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS search_documents_fts USING fts5(content)")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "sqlite" {
+				if err := tx.Exec("DROP TABLE IF EXISTS search_documents_fts").Error; err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable("search_documents")
+		},
+	})
+}
@@ -0,0 +1,25 @@
+// Package migration holds the application's database migrations.
+// Every migration lives in its own timestamped file and registers itself from an init function,
+// so adding a migration never requires editing this file. Use `apify migration [name]` to create one.
+// A migration never uses the structs of the model package, which change over time: it migrates a frozen copy of the
+// struct declared in its own file, named after the migration version, so it creates the same schema forever.
+package migration
+
+import (
+	"GoAPIfy/core/migrator"
+)
+
+// migrations holds every migration registered by the files in this package.
+var migrations []*migrator.Migration
+
+// register adds a migration to the list of application migrations.
+// It is called from the init function of each migration file.
+func register(m *migrator.Migration) {
+	migrations = append(migrations, m)
+}
+
+// All returns every registered application migration.
+// The migrator takes care of ordering them by ID.
+func All() []*migrator.Migration {
+	return migrations
+}
@@ -0,0 +1,83 @@
+package migration
+
+import (
+	"GoAPIfy/core/database"
+	"GoAPIfy/core/migrator"
+	"GoAPIfy/internal/testdb"
+	"GoAPIfy/model"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// TestMigrationsMatchModels makes sure the frozen structs of the migrations create every column of the registered
+// models, so a model field added without its migration is caught.
+func TestMigrationsMatchModels(t *testing.T) {
+	db := testdb.Open(t)
+	if _, err := migrator.New(db, All()).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range model.Registered(database.DefaultConnection) {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			t.Fatal(err)
+		}
+		t.Run(stmt.Schema.Table, func(t *testing.T) {
+			columnTypes, err := db.Migrator().ColumnTypes(stmt.Schema.Table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			existing := map[string]bool{}
+			for _, columnType := range columnTypes {
+				existing[strings.ToLower(columnType.Name())] = true
+			}
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName != "" && !field.IgnoreMigration && !existing[field.DBName] {
+					t.Errorf("column %s of %T is not created by any migration", field.DBName, m)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationsRollBack(t *testing.T) {
+	db := testdb.Open(t)
+	m := migrator.New(db, All())
+	ran, err := m.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		table string
+	}{
+		{"users"},
+		{"email_verifications"},
+		{"audits"},
+		{"seeders_ran"},
+		{"search_documents"},
+	}
+	for _, tt := range tests {
+		if !db.Migrator().HasTable(tt.table) {
+			t.Errorf("table %s was not created", tt.table)
+		}
+	}
+
+	reverted, err := m.Rollback(len(ran))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(ran) {
+		t.Fatalf("reverted %d of the %d migrations", len(reverted), len(ran))
+	}
+	for _, tt := range tests {
+		if db.Migrator().HasTable(tt.table) {
+			t.Errorf("table %s was not dropped", tt.table)
+		}
+	}
+	if _, err := m.Migrate(); err != nil {
+		t.Fatalf("migrating again after the rollback failed: %s", err)
+	}
+}
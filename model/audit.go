@@ -0,0 +1,378 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Auditable records every change of a model in the audits table when embedded in it.
+// Create, Save, Delete, ForceDelete, Restore, UpdateColumn, UpdateColumns, CreateInBatches, Upsert and UpdateWhere
+// through the model wrapper or a Repository write one Audit per affected record, in the same transaction as the change.
+// Raw SQL is not audited. Fields tagged `audit:"-"`, such as password hashes, and encrypted fields
+// are left out of the audit.
+// The acting user, IP address, user agent and request ID are taken from the AuditActor of the context of the query,
+// which the Audit middleware sets for every request; pass it with WithContext(c.Request.Context()).
+//
+// Example usage:
+//
+//	type Invoice struct {
+//		gorm.Model
+//		model.Auditable
+//		Total  int
+//		Secret string `audit:"-"`
+//	}
+//
+//	history, err := invoices.History(&invoice)
+//	err = invoices.Revert(&invoice, history[0].ID)
+type Auditable struct{}
+
+// audited marks the models that embed Auditable.
+func (Auditable) audited() {}
+
+// auditableModel is implemented by every model that embeds Auditable.
+type auditableModel interface {
+	audited()
+}
+
+var auditableType = reflect.TypeOf((*auditableModel)(nil)).Elem()
+
+// isAuditable reports whether the given model type embeds Auditable.
+func isAuditable(modelType reflect.Type) bool {
+	return modelType != nil && reflect.PtrTo(modelType).Implements(auditableType)
+}
+
+func init() {
+	Register(&Audit{})
+}
+
+// Audit is a single recorded change of an Auditable record.
+// OldValues and NewValues only hold the columns that changed: OldValues is empty for a created record,
+// and NewValues is empty for a deleted one.
+type Audit struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	AuditableType string      `gorm:"size:255;not null;index:idx_audits_auditable" json:"auditable_type"`
+	AuditableID   string      `gorm:"size:64;not null;index:idx_audits_auditable" json:"auditable_id"`
+	Event         EventName   `gorm:"size:32;not null" json:"event"`
+	OldValues     AuditValues `gorm:"type:text" json:"old_values" anonymise:"keys"`
+	NewValues     AuditValues `gorm:"type:text" json:"new_values" anonymise:"keys"`
+	UserID        *uint       `gorm:"index" json:"user_id"`
+	IPAddress     string      `gorm:"size:45" json:"ip_address" anonymise:"hash"`
+	UserAgent     string      `gorm:"size:512" json:"user_agent"`
+	RequestID     string      `gorm:"size:64" json:"request_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// AuditValues holds column values of an audited record, stored as a JSON object.
+type AuditValues map[string]interface{}
+
+// Value encodes the values as JSON for the database.
+func (v AuditValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(map[string]interface{}(v))
+	return string(encoded), err
+}
+
+// Scan decodes the values from the JSON stored in the database.
+func (v *AuditValues) Scan(value interface{}) error {
+	var encoded []byte
+	switch data := value.(type) {
+	case nil:
+		*v = AuditValues{}
+		return nil
+	case string:
+		encoded = []byte(data)
+	case []byte:
+		encoded = data
+	default:
+		return fmt.Errorf("cannot scan %T into AuditValues", value)
+	}
+	values := AuditValues{}
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// AuditActor describes who made the changes of a request. It is recorded on every Audit.
+type AuditActor struct {
+	UserID    *uint
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
+
+// auditActorContextKey carries the AuditActor of the current request in a context.
+type auditActorContextKey struct{}
+
+// WithAuditActor returns a copy of the given context that carries the given actor.
+// The Audit middleware stores the actor of the request this way; cron jobs and commands can set their own.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorContextKey{}, actor)
+}
+
+// AuditActorFromContext returns the actor carried by the given context, and false if there is none.
+func AuditActorFromContext(ctx context.Context) (AuditActor, bool) {
+	if ctx == nil {
+		return AuditActor{}, false
+	}
+	actor, ok := ctx.Value(auditActorContextKey{}).(AuditActor)
+	return actor, ok
+}
+
+// ErrNotAudited is returned by History and Revert for a model that does not embed Auditable.
+var ErrNotAudited = errors.New("the model is not auditable")
+
+// History returns the audits of the loaded record, oldest first.
+// The loaded model must be a single Auditable record with its primary key set.
+//
+// Example usage:
+//
+//	history, err := s.Model.Load(&invoice).History()
+func (m *model) History() ([]Audit, error) {
+	auditableType, auditableID, _, err := m.auditSubject()
+	if err != nil {
+		return nil, err
+	}
+	var audits []Audit
+	err = m.db.Session(&gorm.Session{NewDB: true}).
+		Where("auditable_type = ? AND auditable_id = ?", auditableType, auditableID).
+		Order("id ASC").Find(&audits).Error
+	return audits, err
+}
+
+// Revert brings the loaded record back to the state it had right after the given audit entry,
+// by undoing every change recorded after it, and reloads the record. The revert is itself audited as an update.
+// Soft deleted records are restored when they were deleted after the entry; permanently deleted records cannot be reverted.
+// The version of a Versioned model is incremented rather than reverted, so copies loaded before the revert become stale.
+// It returns gorm.ErrRecordNotFound when the audit does not belong to the record.
+//
+// Example usage:
+//
+//	err := s.Model.Load(&invoice).Revert(auditID)
+func (m *model) Revert(auditID uint) error {
+	auditableType, auditableID, conditions, err := m.auditSubject()
+	if err != nil {
+		return err
+	}
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return err
+	}
+
+	return m.transaction(m.db.Session(&gorm.Session{}), func(tx *model) error {
+		conn := tx.db.Session(&gorm.Session{NewDB: true})
+		subject := conn.Where("auditable_type = ? AND auditable_id = ?", auditableType, auditableID)
+
+		var target Audit
+		if err := subject.Session(&gorm.Session{}).Where("id = ?", auditID).Take(&target).Error; err != nil {
+			return err
+		}
+		var later []Audit
+		if err := subject.Session(&gorm.Session{}).Where("id > ?", auditID).Order("id DESC").Find(&later).Error; err != nil {
+			return err
+		}
+
+		// Walking back from the newest change, the old values of the earliest change after the entry win.
+		previous := map[string]interface{}{}
+		for _, audit := range later {
+			for column, value := range audit.OldValues {
+				previous[column] = value
+			}
+		}
+		values, err := revertValues(stmt.Schema, previous)
+		if err != nil {
+			return err
+		}
+
+		record := reflect.New(stmt.Schema.ModelType).Interface()
+		if err := conn.Unscoped().Where(clause.And(conditions...)).Take(record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%s %s no longer exists and cannot be reverted", auditableType, auditableID)
+			}
+			return err
+		}
+		if len(values) > 0 {
+			if err := tx.derive(conn.Unscoped()).Load(record).UpdateColumns(values); err != nil {
+				return err
+			}
+		}
+		return conn.Unscoped().Where(clause.And(conditions...)).Take(m.tempData).Error
+	})
+}
+
+// History returns the audits of the given record, oldest first. See model.History.
+func (r *Repository[T]) History(entity *T) ([]Audit, error) {
+	return r.entity(entity).History()
+}
+
+// Revert brings the given record back to the state it had right after the given audit entry. See model.Revert.
+func (r *Repository[T]) Revert(entity *T, auditID uint) error {
+	return r.entity(entity).Revert(auditID)
+}
+
+// auditSubject returns the type and ID the audits of the loaded record are stored under,
+// together with the primary key conditions of the record.
+func (m *model) auditSubject() (string, string, []clause.Expression, error) {
+	if !isAuditable(entityType(m.tempData)) {
+		return "", "", nil, ErrNotAudited
+	}
+	entities := m.loadedEntities()
+	if len(entities) != 1 {
+		return "", "", nil, fmt.Errorf("the audits of a single record can be read, %d are loaded", len(entities))
+	}
+
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return "", "", nil, err
+	}
+	conditions, ok := m.primaryKeyConditions(stmt.Schema, entities[0])
+	if !ok {
+		return "", "", nil, fmt.Errorf("the primary key of the %s record is not set", stmt.Schema.Table)
+	}
+	return stmt.Schema.Table, recordID(m.db.Statement.Context, stmt.Schema, entities[0].Interface()), conditions, nil
+}
+
+// audit records the changes of an observed write of an Auditable model.
+func (m *model) audit(changes []change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	ctx := m.db.Statement.Context
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return err
+	}
+	actor, _ := AuditActorFromContext(ctx)
+
+	var audits []Audit
+	for _, c := range changes {
+		oldValues := auditAttributes(ctx, stmt.Schema, c.old)
+		newValues := auditAttributes(ctx, stmt.Schema, c.new)
+		for column, value := range oldValues {
+			if encoded, ok := newValues[column]; ok && string(encoded.(json.RawMessage)) == string(value.(json.RawMessage)) {
+				delete(oldValues, column)
+				delete(newValues, column)
+			}
+		}
+		if c.after == Updated && len(newValues) == 0 {
+			continue
+		}
+
+		subject := c.new
+		if subject == nil {
+			subject = c.old
+		}
+		audits = append(audits, Audit{
+			AuditableType: stmt.Schema.Table,
+			AuditableID:   recordID(ctx, stmt.Schema, subject),
+			Event:         c.after,
+			OldValues:     oldValues,
+			NewValues:     newValues,
+			UserID:        actor.UserID,
+			IPAddress:     actor.IPAddress,
+			UserAgent:     truncate(actor.UserAgent, 512),
+			RequestID:     actor.RequestID,
+		})
+	}
+	if len(audits) == 0 {
+		return nil
+	}
+	return m.db.Session(&gorm.Session{NewDB: true}).Create(&audits).Error
+}
+
+// auditAttributes returns the JSON encoded column values of the given record, or an empty map for nil.
+func auditAttributes(ctx context.Context, s *schema.Schema, record interface{}) AuditValues {
+	values := AuditValues{}
+	if record == nil {
+		return values
+	}
+	entity := reflect.Indirect(reflect.ValueOf(record))
+	encrypted := encryptedColumns(s)
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.Tag.Get("audit") == "-" || encrypted[field.DBName] {
+			continue
+		}
+		value, _ := field.ValueOf(ctx, entity)
+		encoded, err := json.Marshal(normalizeAuditValue(value))
+		if err != nil {
+			continue
+		}
+		values[field.DBName] = json.RawMessage(encoded)
+	}
+	return values
+}
+
+// normalizeAuditValue makes equal times read from the database and set in Go encode the same way.
+func normalizeAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC()
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC()
+	case gorm.DeletedAt:
+		if !v.Valid {
+			return nil
+		}
+		return v.Time.UTC()
+	}
+	return value
+}
+
+// recordID returns the primary key of the given record as a string, as stored in the audits table and search indexes.
+func recordID(ctx context.Context, s *schema.Schema, record interface{}) string {
+	entity := reflect.Indirect(reflect.ValueOf(record))
+	id := ""
+	for i, field := range s.PrimaryFields {
+		value, _ := field.ValueOf(ctx, entity)
+		if i > 0 {
+			id += ","
+		}
+		id += fmt.Sprint(value)
+	}
+	return id
+}
+
+// revertValues converts the JSON values of an audit back to values of the types of the model's fields.
+// The primary key and the version of Versioned models are never reverted.
+func revertValues(s *schema.Schema, previous map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for column, value := range previous {
+		field := s.LookUpField(column)
+		if field == nil || field.PrimaryKey || field.DBName == versionColumn {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		typed := reflect.New(field.FieldType)
+		if err := json.Unmarshal(encoded, typed.Interface()); err != nil {
+			return nil, fmt.Errorf("cannot revert %s: %w", column, err)
+		}
+		values[field.DBName] = typed.Elem().Interface()
+	}
+	return values, nil
+}
+
+// truncate shortens the given string to at most size bytes.
+func truncate(value string, size int) string {
+	if len(value) > size {
+		return value[:size]
+	}
+	return value
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// auditInvoice is the Auditable model of the audit tests, its secret is never audited.
+type auditInvoice struct {
+	gorm.Model
+	Auditable
+	Total  int
+	Note   string
+	Secret string `audit:"-"`
+}
+
+// formatAudit formats an audit as "event old -> new" with the columns sorted, leaving out the timestamps
+// that change with every write and showing whether deleted_at is set.
+func formatAudit(a Audit) string {
+	format := func(values AuditValues) string {
+		var formatted []string
+		for column, value := range values {
+			switch column {
+			case "created_at", "updated_at":
+				continue
+			case "deleted_at":
+				value = value != nil
+			}
+			formatted = append(formatted, fmt.Sprintf("%s=%v", column, value))
+		}
+		sort.Strings(formatted)
+		return strings.Join(formatted, " ")
+	}
+	return fmt.Sprintf("%s %s -> %s", a.Event, format(a.OldValues), format(a.NewValues))
+}
+
+// invoiceHistory returns the formatted history of the given invoice.
+func invoiceHistory(t *testing.T, invoices *Repository[auditInvoice], invoice auditInvoice) []string {
+	t.Helper()
+	audits, err := invoices.History(&invoice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history []string
+	for _, a := range audits {
+		history = append(history, formatAudit(a))
+	}
+	return history
+}
+
+func TestAuditTrail(t *testing.T) {
+	tests := []struct {
+		name        string
+		write       func(invoices *Repository[auditInvoice], invoice *auditInvoice) error
+		wantHistory []string
+	}{
+		{
+			name:        "create",
+			write:       func(invoices *Repository[auditInvoice], invoice *auditInvoice) error { return nil },
+			wantHistory: nil,
+		},
+		{
+			name: "update only records the changed columns",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				invoice.Total = 20
+				return invoices.Update(invoice)
+			},
+			wantHistory: []string{"updated total=10 -> total=20"},
+		},
+		{
+			name: "a save without changes only records the timestamp",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				return invoices.Update(invoice)
+			},
+			wantHistory: []string{"updated  -> "},
+		},
+		{
+			name: "excluded columns are not recorded",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				invoice.Secret = "changed"
+				return invoices.Update(invoice)
+			},
+			wantHistory: []string{"updated  -> "},
+		},
+		{
+			name: "an update without changes is not recorded",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				return invoices.entity(invoice).UpdateColumns(map[string]interface{}{"total": 10, "secret": "changed"})
+			},
+		},
+		{
+			name: "update columns",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				return invoices.entity(invoice).UpdateColumns(map[string]interface{}{"note": "paid", "total": 30})
+			},
+			wantHistory: []string{"updated note=draft total=10 -> note=paid total=30"},
+		},
+		{
+			name: "delete and restore",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				if err := invoices.Delete(invoice); err != nil {
+					return err
+				}
+				return invoices.Restore(invoice)
+			},
+			wantHistory: []string{
+				"deleted deleted_at=false id=1 note=draft total=10 -> ",
+				"restored deleted_at=true -> deleted_at=false",
+			},
+		},
+		{
+			name: "raw SQL is not recorded",
+			write: func(invoices *Repository[auditInvoice], invoice *auditInvoice) error {
+				return invoices.query().Exec("UPDATE audit_invoices SET total = 99").Error
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoices := NewRepository[auditInvoice](NewModel(testdb.Open(t, &auditInvoice{}, &Audit{})))
+			invoice := auditInvoice{Total: 10, Note: "draft", Secret: "s"}
+			if err := invoices.Create(&invoice); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.write(invoices, &invoice); err != nil {
+				t.Fatal(err)
+			}
+
+			want := append([]string{"created  -> deleted_at=false id=1 note=draft total=10"}, tt.wantHistory...)
+			if got := invoiceHistory(t, invoices, invoice); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("history =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	m := NewModel(testdb.Open(t, &auditInvoice{}, &Audit{}))
+	userID := uint(7)
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: &userID, IPAddress: "192.0.2.1", UserAgent: strings.Repeat("a", 600), RequestID: "req-1"})
+	invoices := NewRepository[auditInvoice](m)
+
+	invoice := auditInvoice{Total: 1}
+	if err := invoices.WithContext(ctx).Create(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	if err := invoices.Delete(&invoice); err != nil {
+		t.Fatal(err)
+	}
+
+	audits, err := invoices.History(&invoice)
+	if err != nil || len(audits) != 2 {
+		t.Fatalf("History = %d audits, %v, want 2", len(audits), err)
+	}
+	if a := audits[0]; a.UserID == nil || *a.UserID != 7 || a.IPAddress != "192.0.2.1" || len(a.UserAgent) != 512 || a.RequestID != "req-1" {
+		t.Errorf("audit of the request = %+v, want the actor of the context with the user agent truncated", a)
+	}
+	if a := audits[1]; a.UserID != nil || a.IPAddress != "" || a.RequestID != "" {
+		t.Errorf("audit without an actor = %+v, want no actor", a)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	invoices := NewRepository[auditInvoice](NewModel(testdb.Open(t, &auditInvoice{}, &Audit{})))
+	invoice := auditInvoice{Total: 10, Note: "draft"}
+	if err := invoices.Create(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	invoice.Total = 20
+	if err := invoices.Update(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	invoice.Total, invoice.Note = 30, "sent"
+	if err := invoices.Update(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	if err := invoices.Delete(&invoice); err != nil {
+		t.Fatal(err)
+	}
+
+	// Audit 2 is the first update: reverting to it undoes the second update and the delete.
+	if err := invoices.Revert(&invoice, 2); err != nil {
+		t.Fatal(err)
+	}
+	if invoice.Total != 20 || invoice.Note != "draft" || invoice.DeletedAt.Valid {
+		t.Errorf("reverted invoice = %d %q deleted %v, want 20 \"draft\" restored", invoice.Total, invoice.Note, invoice.DeletedAt.Valid)
+	}
+	stored, err := invoices.Find(invoice.ID)
+	if err != nil || stored.Total != 20 || stored.Note != "draft" {
+		t.Errorf("stored invoice = %+v, %v, want the reverted one", stored, err)
+	}
+	history := invoiceHistory(t, invoices, invoice)
+	if last := history[len(history)-1]; last != "updated deleted_at=true note=sent total=30 -> deleted_at=false note=draft total=20" {
+		t.Errorf("audit of the revert = %q", last)
+	}
+
+	errorTests := []struct {
+		name    string
+		revert  func() error
+		wantErr error
+	}{
+		{"audit of another record", func() error {
+			other := auditInvoice{Total: 1}
+			if err := invoices.Create(&other); err != nil {
+				return err
+			}
+			return invoices.Revert(&other, 2)
+		}, gorm.ErrRecordNotFound},
+		{"unknown audit", func() error { return invoices.Revert(&invoice, 999) }, gorm.ErrRecordNotFound},
+		{"model that is not auditable", func() error {
+			return NewRepository[chunkItem](invoices.m).Revert(&chunkItem{ID: 1}, 1)
+		}, ErrNotAudited},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.revert(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Revert error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := invoices.ForceDelete(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	if err := invoices.Revert(&invoice, 2); err == nil {
+		t.Error("Revert of a permanently deleted record succeeded")
+	}
+}
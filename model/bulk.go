@@ -0,0 +1,256 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateInBatches inserts the loaded slice of models with one INSERT statement per batch of the given size.
+// It returns the number of inserted records. The generated fields of every model, such as the ID, are filled in.
+// GORM runs the batches inside a single transaction, so either every record is inserted or none is.
+//
+// Example usage:
+//
+//	users := []model.User{{Name: "Alice"}, {Name: "Bob"}}
+//	inserted, err := s.Model.Load(&users).CreateInBatches(500)
+func (m *model) CreateInBatches(batchSize int) (int64, error) {
+	var rowsAffected int64
+	insert := func(tx *model) error {
+		result := tx.db.CreateInBatches(tx.tempData, batchSize)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	}
+
+	if !m.tracked(Creating, Created) {
+		err := insert(m)
+		return rowsAffected, m.written(err)
+	}
+	err := m.observe(func(tx *model) ([]change, error) {
+		var changes []change
+		for _, entity := range tx.loadedEntities() {
+			changes = append(changes, change{before: Creating, after: Created, new: entity.Interface()})
+		}
+		return changes, nil
+	}, insert)
+	return rowsAffected, m.written(err)
+}
+
+// Upsert inserts the loaded model, or slice of models, and updates the records that already exist instead.
+// It takes in the columns of the unique index that detects an existing record, and the columns to overwrite on
+// existing records; when no update columns are given every column is overwritten.
+// The statement is generated for the current dialect: ON CONFLICT on Postgres and SQLite, ON DUPLICATE KEY UPDATE
+// on MySQL and MariaDB, and MERGE on SQL Server. MySQL ignores the conflict columns and uses any unique index.
+// It returns the number of affected records as reported by the database; MySQL counts an updated record twice.
+// Generated IDs are filled in for inserted records on every dialect, and for updated records on Postgres, SQLite
+// and SQL Server. Upsert does not fire the model observers, since it cannot tell inserted records from updated ones
+// before the write. The changes of Auditable models are still audited and the indexes of Searchable models updated:
+// the records matching the conflict columns, or the primary key when none are given, are read inside a transaction
+// before and after the write, which costs two more queries per 500 records. On MySQL a record matched by another
+// unique index than the conflict columns is audited as created.
+//
+// Example usage:
+//
+//	affected, err := s.Model.Load(&users).Upsert([]string{"email"}, []string{"name", "updated_at"})
+func (m *model) Upsert(conflictColumns []string, updateColumns []string) (int64, error) {
+	onConflict := clause.OnConflict{}
+	for _, column := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	if len(updateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	} else {
+		onConflict.UpdateAll = true
+	}
+
+	var rowsAffected int64
+	upsert := func(tx *model) error {
+		result := tx.db.Clauses(onConflict).Create(tx.tempData)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	}
+
+	modelType := entityType(m.tempData)
+	if !isAuditable(modelType) && (m.search == nil || !isSearchable(modelType)) {
+		err := upsert(m)
+		return rowsAffected, m.written(err)
+	}
+	err := m.transaction(m.db.Session(&gorm.Session{}), func(tx *model) error {
+		_, stored, err := tx.upsertedRecords(conflictColumns)
+		if err != nil {
+			return err
+		}
+		if err := upsert(tx); err != nil {
+			return err
+		}
+		keys, written, err := tx.upsertedRecords(conflictColumns)
+		if err != nil {
+			return err
+		}
+
+		changes := make([]change, 0, len(keys))
+		for _, key := range keys {
+			if old, ok := stored[key]; ok {
+				changes = append(changes, change{before: Updating, after: Updated, old: old, new: written[key]})
+			} else {
+				changes = append(changes, change{before: Creating, after: Created, new: written[key]})
+			}
+		}
+		if isAuditable(modelType) {
+			if err := tx.audit(changes); err != nil {
+				return err
+			}
+		}
+		if tx.search != nil && isSearchable(modelType) {
+			return tx.index(changes)
+		}
+		return nil
+	})
+	return rowsAffected, m.written(err)
+}
+
+// upsertBatchSize is the number of loaded entities upsertedRecords looks up with a single query.
+const upsertBatchSize = 500
+
+// upsertedRecords loads the stored records matching the conflict columns of the loaded entities, or their primary key
+// when no conflict columns are given, trashed records included. It returns the records keyed by their conflict values,
+// and the keys in the order of the loaded entities. Entities without a primary key are skipped when matching by it.
+func (m *model) upsertedRecords(conflictColumns []string) ([]string, map[string]interface{}, error) {
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return nil, nil, err
+	}
+	ctx := m.db.Statement.Context
+	fields := stmt.Schema.PrimaryFields
+	if len(conflictColumns) > 0 {
+		fields = nil
+		for _, column := range conflictColumns {
+			field := stmt.Schema.LookUpField(column)
+			if field == nil {
+				return nil, nil, fmt.Errorf("unknown conflict column %s of %s", column, stmt.Schema.Table)
+			}
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil, fmt.Errorf("cannot match the upserted %s records, they have no primary key", stmt.Schema.Table)
+	}
+	key := func(entity reflect.Value) (string, []clause.Expression, bool) {
+		values := make([]interface{}, len(fields))
+		conditions := make([]clause.Expression, len(fields))
+		for i, field := range fields {
+			value, zero := field.ValueOf(ctx, entity)
+			if zero && field.PrimaryKey {
+				return "", nil, false
+			}
+			values[i] = normalizeAuditValue(value)
+			conditions[i] = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value}
+		}
+		encoded, _ := json.Marshal(values)
+		return string(encoded), conditions, true
+	}
+
+	var keys []string
+	var matches []clause.Expression
+	seen := map[string]bool{}
+	for _, entity := range m.loadedEntities() {
+		k, conditions, ok := key(entity.Elem())
+		if !ok || seen[k] {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+		matches = append(matches, clause.And(conditions...))
+	}
+
+	records := map[string]interface{}{}
+	conn := m.db.Session(&gorm.Session{NewDB: true}).Unscoped()
+	for start := 0; start < len(matches); start += upsertBatchSize {
+		end := start + upsertBatchSize
+		if end > len(matches) {
+			end = len(matches)
+		}
+		found := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+		if err := conn.Where(clause.Or(matches[start:end]...)).Find(found.Interface()).Error; err != nil {
+			return nil, nil, err
+		}
+		for i := 0; i < found.Elem().Len(); i++ {
+			record := found.Elem().Index(i)
+			if k, _, ok := key(record); ok {
+				records[k] = record.Addr().Interface()
+			}
+		}
+	}
+
+	// Records that are not stored, such as the ones skipped by the write, have no change.
+	stored := keys[:0]
+	for _, k := range keys {
+		if _, ok := records[k]; ok {
+			stored = append(stored, k)
+		}
+	}
+	return stored, records, nil
+}
+
+// UpdateWhere updates the given columns of every record that matches the current query with a single UPDATE statement.
+// Unlike UpdateColumns it runs the GORM update hooks and sets updated_at. It returns the number of updated records.
+// A query without conditions is refused with gorm.ErrMissingWhereClause, so a whole table is never updated by accident.
+//
+// Example usage:
+//
+//	updated, err := s.Model.Load(&model.User{}).Where("verified_at IS NULL").UpdateWhere(map[string]interface{}{"name": "Unverified"})
+func (m *model) UpdateWhere(values map[string]interface{}) (int64, error) {
+	var rowsAffected int64
+	update := func(tx *model) error {
+		result := tx.db.Updates(tx.versionedValues(values))
+		rowsAffected = result.RowsAffected
+		return result.Error
+	}
+
+	if !m.tracked(Updating, Updated) {
+		err := update(m)
+		return rowsAffected, m.written(err)
+	}
+	err := m.observe(func(tx *model) ([]change, error) {
+		records, err := tx.currentRecords(tx.db.Statement.Unscoped)
+		if err != nil {
+			return nil, err
+		}
+		var changes []change
+		for _, record := range records {
+			updated, err := tx.applyValues(record, values)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change{before: Updating, after: Updated, old: record, new: updated})
+		}
+		return changes, nil
+	}, update)
+	return rowsAffected, m.written(err)
+}
+
+// CreateInBatches inserts the given entities in batches of the given size and returns the number of inserted records.
+// The generated fields of every entity, such as the ID, are filled in. See model.CreateInBatches.
+func (r *Repository[T]) CreateInBatches(entities []T, batchSize int) (int64, error) {
+	return r.entities(&entities).CreateInBatches(batchSize)
+}
+
+// Upsert inserts the given entities and updates the ones that already exist instead. See model.Upsert.
+func (r *Repository[T]) Upsert(entities []T, conflictColumns []string, updateColumns []string) (int64, error) {
+	return r.entities(&entities).Upsert(conflictColumns, updateColumns)
+}
+
+// UpdateWhere updates the given columns of every record that matches the current query and returns the number
+// of updated records. See model.UpdateWhere.
+func (r *Repository[T]) UpdateWhere(values map[string]interface{}) (int64, error) {
+	return r.m.derive(r.query()).UpdateWhere(values)
+}
+
+// entities returns a model bound to the given entities on a connection without any of the chained conditions.
+func (r *Repository[T]) entities(entities *[]T) *model {
+	root := r.m.derive(r.m.db.Session(&gorm.Session{NewDB: true}))
+	return root.Load(entities)
+}
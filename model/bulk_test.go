@@ -0,0 +1,222 @@
+package model
+
+import (
+	"GoAPIfy/core/search"
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// bulkContact is the plain model of the bulk tests, its email is unique.
+type bulkContact struct {
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Name  string
+	Score int
+}
+
+// bulkMember is audited and searchable, so its upserts are read back to be recorded.
+type bulkMember struct {
+	Auditable
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Name  string
+}
+
+func (bulkMember) SearchIndex() string { return "bulk_members" }
+func (m bulkMember) SearchDocument() map[string]interface{} {
+	return map[string]interface{}{"name": m.Name}
+}
+func (bulkMember) FilterableAttributes() []string { return nil }
+func (bulkMember) SortableAttributes() []string   { return nil }
+
+// bulkObservedContact is observed, bulkCreated counts its Created events.
+type bulkObservedContact struct {
+	ID   uint
+	Name string
+}
+
+var (
+	bulkCreated     int
+	bulkObserveOnce sync.Once
+)
+
+// storedContacts returns the stored contacts formatted as "email=name:score", ordered by email.
+func storedContacts(t *testing.T, contacts *Repository[bulkContact]) string {
+	t.Helper()
+	all, err := contacts.Order("email").All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var formatted []string
+	for _, c := range all {
+		formatted = append(formatted, fmt.Sprintf("%s=%s:%d", c.Email, c.Name, c.Score))
+	}
+	return strings.Join(formatted, ",")
+}
+
+func TestCreateInBatches(t *testing.T) {
+	bulkObserveOnce.Do(func() {
+		Observe(Created, func(e *Event[bulkObservedContact]) error {
+			bulkCreated++
+			return nil
+		})
+	})
+	m := NewModel(testdb.Open(t, &bulkContact{}, &bulkObservedContact{}))
+
+	contacts := []bulkContact{{Email: "a", Name: "A"}, {Email: "b", Name: "B"}, {Email: "c", Name: "C"}}
+	inserted, err := NewRepository[bulkContact](m).CreateInBatches(contacts, 2)
+	if err != nil || inserted != 3 {
+		t.Fatalf("CreateInBatches = %d, %v, want 3", inserted, err)
+	}
+	// The generated IDs are filled in.
+	loaded := []bulkContact{{Email: "d"}, {Email: "e"}}
+	if _, err := m.Load(&loaded).CreateInBatches(10); err != nil {
+		t.Fatal(err)
+	}
+	if loaded[0].ID != 4 || loaded[1].ID != 5 {
+		t.Errorf("IDs = %d, %d, want 4, 5", loaded[0].ID, loaded[1].ID)
+	}
+
+	// A duplicate fails the whole insert.
+	duplicates := []bulkContact{{Email: "f"}, {Email: "a"}}
+	if _, err := m.Load(&duplicates).CreateInBatches(1); err == nil {
+		t.Error("CreateInBatches with a duplicate email succeeded")
+	}
+	if count, _ := NewRepository[bulkContact](m).Count(); count != 5 {
+		t.Errorf("%d contacts after the failed insert, want 5", count)
+	}
+
+	bulkCreated = 0
+	observed := []bulkObservedContact{{Name: "x"}, {Name: "y"}}
+	if _, err := NewRepository[bulkObservedContact](m).CreateInBatches(observed, 1); err != nil {
+		t.Fatal(err)
+	}
+	if bulkCreated != 2 {
+		t.Errorf("%d Created events, want 2", bulkCreated)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		name            string
+		entities        []bulkContact
+		conflictColumns []string
+		updateColumns   []string
+		want            string
+	}{
+		{
+			name:            "insert and update by email",
+			entities:        []bulkContact{{Email: "a", Name: "A2", Score: 9}, {Email: "c", Name: "C", Score: 3}},
+			conflictColumns: []string{"email"},
+			want:            "a=A2:9,b=B:2,c=C:3",
+		},
+		{
+			name:            "only the update columns are overwritten",
+			entities:        []bulkContact{{Email: "a", Name: "A2", Score: 9}},
+			conflictColumns: []string{"email"},
+			updateColumns:   []string{"score"},
+			want:            "a=A:9,b=B:2",
+		},
+		{
+			name:     "by primary key",
+			entities: []bulkContact{{ID: 2, Email: "b", Name: "B2", Score: 5}, {Email: "d", Name: "D", Score: 4}},
+			want:     "a=A:1,b=B2:5,d=D:4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contacts := NewRepository[bulkContact](NewModel(testdb.Open(t, &bulkContact{})))
+			for _, c := range []bulkContact{{Email: "a", Name: "A", Score: 1}, {Email: "b", Name: "B", Score: 2}} {
+				if err := contacts.Create(&c); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := contacts.Upsert(tt.entities, tt.conflictColumns, tt.updateColumns); err != nil {
+				t.Fatal(err)
+			}
+			if got := storedContacts(t, contacts); got != tt.want {
+				t.Errorf("stored = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpsertAudited makes sure upserted records are audited as created or updated and indexed.
+func TestUpsertAudited(t *testing.T) {
+	engine := search.NewMemory()
+	m := NewModel(testdb.Open(t, &bulkMember{}, &Audit{})).WithSearch(engine)
+	members := NewRepository[bulkMember](m)
+	ann := bulkMember{Email: "ann@example.com", Name: "Ann"}
+	if err := members.Create(&ann); err != nil {
+		t.Fatal(err)
+	}
+
+	upserted := []bulkMember{{Email: "ann@example.com", Name: "Anna"}, {Email: "bob@example.com", Name: "Bob"}}
+	if _, err := members.Upsert(upserted, []string{"email"}, []string{"name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var audits []Audit
+	if err := m.Load(&audits).Order("id").Get(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range audits {
+		got = append(got, a.AuditableID+" "+string(a.Event)+" "+formatAuditValues(a.OldValues)+" -> "+formatAuditValues(a.NewValues))
+	}
+	want := []string{
+		"1 created  -> email=ann@example.com id=1 name=Ann",
+		"1 updated name=Ann -> name=Anna",
+		"2 created  -> email=bob@example.com id=2 name=Bob",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("audits =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	ids, _, err := engine.Search(search.Query{Index: search.Index{Name: "bulk_members"}, Text: "anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("search for the updated name = %v, want [1]", ids)
+	}
+}
+
+// formatAuditValues formats audited values as "column=value", sorted by column.
+func formatAuditValues(values AuditValues) string {
+	var formatted []string
+	for column, value := range values {
+		formatted = append(formatted, fmt.Sprintf("%s=%v", column, value))
+	}
+	sort.Strings(formatted)
+	return strings.Join(formatted, " ")
+}
+
+func TestUpdateWhere(t *testing.T) {
+	contacts := NewRepository[bulkContact](NewModel(testdb.Open(t, &bulkContact{})))
+	for _, c := range []bulkContact{{Email: "a", Name: "A", Score: 1}, {Email: "b", Name: "B", Score: 2}, {Email: "c", Name: "C", Score: 3}} {
+		if err := contacts.Create(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updated, err := contacts.Where("score >= ?", 2).UpdateWhere(map[string]interface{}{"name": "X"})
+	if err != nil || updated != 2 {
+		t.Fatalf("UpdateWhere = %d, %v, want 2", updated, err)
+	}
+	if got, want := storedContacts(t, contacts), "a=A:1,b=X:2,c=X:3"; got != want {
+		t.Errorf("stored = %q, want %q", got, want)
+	}
+
+	if _, err := contacts.UpdateWhere(map[string]interface{}{"name": "Y"}); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("UpdateWhere without conditions = %v, want gorm.ErrMissingWhereClause", err)
+	}
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"GoAPIfy/core/cache"
+	"GoAPIfy/core/helper"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// cacheOptions holds the caching requested on a query chain with Remember or Cache.
+type cacheOptions struct {
+	key string
+	ttl time.Duration
+}
+
+// WithCache returns a model that can cache query results in the given cache, see Remember and Cache.
+// Without it Remember and Cache are ignored and every query hits the database.
+//
+// Example usage:
+//
+//	modelService := model.NewModel(db).WithCache(cache.New(redisClient))
+func (m *model) WithCache(c *cache.Cache) *model {
+	next := m.derive(m.db)
+	next.cache = c
+	return next
+}
+
+// Remember caches the result of the query under the given key for ttl.
+// It takes in a key, which must identify the query including its conditions, and the duration the result is kept.
+// The finishers Get, Find, Count and Paginate, and the finders of Repository, read the result from the cache when present.
+// The entry is tagged with the table of the loaded model and of the eager loaded relations, and is invalidated
+// automatically when a record of one of those tables is written through the model wrapper or a Repository.
+// Queries inside a transaction are never cached, since they must see the uncommitted writes of the transaction.
+//
+// Example usage:
+//
+//	err := s.Model.Load(&users).Where("verified_at IS NOT NULL").Remember("users:verified", 5*time.Minute).Get()
+func (m *model) Remember(key string, ttl time.Duration) *model {
+	next := m.derive(m.db)
+	next.remember = &cacheOptions{key: key, ttl: ttl}
+	return next
+}
+
+// Cache caches the result of the query for ttl under a key derived from the generated SQL.
+// It behaves like Remember, without having to name every query.
+func (m *model) Cache(ttl time.Duration) *model {
+	return m.Remember("", ttl)
+}
+
+// FlushCache invalidates every cached query result of the loaded model's table.
+// Writes through the model wrapper do this automatically; use it after changing the table with Execute or raw SQL.
+func (m *model) FlushCache() error {
+	if m.cache == nil {
+		return nil
+	}
+	return m.cache.Flush(m.db.Statement.Context, m.cacheTags(m.tempData, false)...)
+}
+
+// cached runs a read query through the cache when the chain asked for it with Remember or Cache.
+// op names the finisher, so different finishers of the same chain get different entries.
+// run builds and executes the query into the given destination on the given connection.
+func (m *model) cached(op string, dest interface{}, run func(db *gorm.DB, dest interface{}) *gorm.DB) error {
+	// Decrypted values of encrypted attributes must never leave the database, see EncryptedSerializer.
+	if m.remember == nil || m.cache == nil || m.txDepth > 0 || m.readsEncrypted(dest) {
+		return run(m.db, dest).Error
+	}
+
+	destType := reflect.TypeOf(dest).Elem()
+	key := m.remember.key
+	if key == "" {
+		// Build the SQL without running it to derive the key from it.
+		dry := run(m.db.Session(&gorm.Session{DryRun: true, Logger: logger.Discard}), reflect.New(destType).Interface())
+		if dry.Error != nil {
+			return dry.Error
+		}
+		sum := sha256.Sum256([]byte(op + "\x00" + dry.Dialector.Explain(dry.Statement.SQL.String(), dry.Statement.Vars...)))
+		key = "query:" + hex.EncodeToString(sum[:])
+	} else {
+		key = key + ":" + op
+		// A named key does not include the tenant condition of the SQL, so results of different tenants must not share it.
+		if withoutTenant(m.db.Statement.Context) {
+			key += ":all-tenants"
+		} else if tenantID, ok := TenantFromContext(m.db.Statement.Context); ok {
+			key += ":tenant:" + strconv.FormatUint(uint64(tenantID), 10)
+		}
+	}
+
+	model := m.tempData
+	if model == nil {
+		model = dest
+	}
+	ctx := m.db.Statement.Context
+	data, err := m.cache.Remember(ctx, key, m.cacheTags(model, true), m.remember.ttl, func() ([]byte, error) {
+		fresh := reflect.New(destType)
+		if err := run(m.db.Session(&gorm.Session{}), fresh.Interface()).Error; err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		if err := gob.NewEncoder(&buffer).EncodeValue(fresh); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// gob leaves fields that are zero in the cached value untouched, so start from a zero destination.
+	target := reflect.ValueOf(dest)
+	target.Elem().Set(reflect.Zero(destType))
+	return gob.NewDecoder(bytes.NewReader(data)).DecodeValue(target)
+}
+
+// cacheTags returns the tags of the table of the given model, and optionally of the eager loaded relations of the query.
+func (m *model) cacheTags(model interface{}, withPreloads bool) []string {
+	stmt := &gorm.Statement{DB: m.db}
+	if model == nil || stmt.Parse(model) != nil {
+		return nil
+	}
+
+	tags := []string{"table:" + stmt.Schema.Table}
+	if !withPreloads {
+		return tags
+	}
+	for preload := range m.db.Statement.Preloads {
+		s := stmt.Schema
+		for _, name := range strings.Split(preload, ".") {
+			relation, ok := s.Relationships.Relations[name]
+			if !ok {
+				break
+			}
+			s = relation.FieldSchema
+			tags = append(tags, "table:"+s.Table)
+			if relation.JoinTable != nil {
+				tags = append(tags, "table:"+relation.JoinTable.Table)
+			}
+		}
+	}
+
+	// The order of the tags is part of the cache key, while preloads are kept in a map.
+	sort.Strings(tags[1:])
+	return tags
+}
+
+// written invalidates the cached queries of the loaded model's table after a successful write.
+// Inside a transaction the invalidation waits until the outermost transaction has been committed,
+// so no other request can cache the old data again in between.
+func (m *model) written(err error) error {
+	if err != nil || m.cache == nil {
+		return err
+	}
+	m.invalidate(m.cacheTags(m.tempData, false)...)
+	return nil
+}
+
+// invalidate flushes the cached queries with the given tags, once the current transaction is committed.
+func (m *model) invalidate(tags ...string) {
+	if m.cache == nil {
+		return
+	}
+	if m.pendingTags != nil {
+		*m.pendingTags = append(*m.pendingTags, tags...)
+		return
+	}
+	flushCache(m.db.Statement.Context, m.cache, tags)
+}
+
+// flushCache flushes the cached queries with the given tags after a write. The write itself succeeded, so a failed
+// flush is reported instead of returned: the stale results stay cached until they expire or the tags are flushed again.
+func flushCache(ctx context.Context, c *cache.Cache, tags []string) {
+	if err := c.Flush(ctx, tags...); err != nil {
+		fmt.Println(helper.ColorizeCmd(helper.Red, fmt.Sprintf("Cache invalidation of %s failed, stale results may be served: %s", strings.Join(tags, ", "), err)))
+	}
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"GoAPIfy/core/cache"
+	"GoAPIfy/internal/testdb"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cacheAuthor and cacheBook are the models of the cache tests, a book belongs to an author.
+type cacheAuthor struct {
+	ID   uint
+	Name string
+}
+
+type cacheBook struct {
+	ID       uint
+	Title    string
+	AuthorID uint
+	Author   cacheAuthor
+}
+
+// brokenFlushStore is a cache store whose tag versions cannot be written once broken, so every flush fails.
+type brokenFlushStore struct {
+	*cache.LRUStore
+	broken bool
+}
+
+func (s *brokenFlushStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if s.broken && ttl == 0 {
+		return errors.New("store unavailable")
+	}
+	return s.LRUStore.Set(ctx, key, value, ttl)
+}
+
+func TestCachedQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		// read runs the cached query, between changes the data after the first read.
+		read      func(books *Repository[cacheBook]) (int64, error)
+		between   func(db *gorm.DB, m Model) error
+		wantFirst int64
+		wantAgain int64
+	}{
+		{
+			name:      "a raw write does not invalidate",
+			read:      func(books *Repository[cacheBook]) (int64, error) { return books.Cache(time.Minute).Count() },
+			between:   func(db *gorm.DB, m Model) error { return db.Create(&cacheBook{Title: "raw", AuthorID: 1}).Error },
+			wantFirst: 1,
+			wantAgain: 1,
+		},
+		{
+			name: "a write through the repository invalidates",
+			read: func(books *Repository[cacheBook]) (int64, error) { return books.Cache(time.Minute).Count() },
+			between: func(db *gorm.DB, m Model) error {
+				return NewRepository[cacheBook](m).Create(&cacheBook{Title: "new", AuthorID: 1})
+			},
+			wantFirst: 1,
+			wantAgain: 2,
+		},
+		{
+			name: "FlushCache invalidates after a raw write",
+			read: func(books *Repository[cacheBook]) (int64, error) { return books.Cache(time.Minute).Count() },
+			between: func(db *gorm.DB, m Model) error {
+				if err := db.Create(&cacheBook{Title: "raw", AuthorID: 1}).Error; err != nil {
+					return err
+				}
+				return m.Load(&cacheBook{}).FlushCache()
+			},
+			wantFirst: 1,
+			wantAgain: 2,
+		},
+		{
+			name: "a named key caches the result",
+			read: func(books *Repository[cacheBook]) (int64, error) {
+				return books.Where("title = ?", "first").Remember("books:first", time.Minute).Count()
+			},
+			between:   func(db *gorm.DB, m Model) error { return db.Create(&cacheBook{Title: "first", AuthorID: 1}).Error },
+			wantFirst: 1,
+			wantAgain: 1,
+		},
+		{
+			name: "a write of an eager loaded relation invalidates",
+			read: func(books *Repository[cacheBook]) (int64, error) {
+				all, err := books.With("Author").Cache(time.Minute).All()
+				if err != nil || len(all) == 0 {
+					return 0, err
+				}
+				return int64(len(all[0].Author.Name)), nil
+			},
+			between: func(db *gorm.DB, m Model) error {
+				author := cacheAuthor{ID: 1, Name: "Ursula"}
+				return NewRepository[cacheAuthor](m).Update(&author)
+			},
+			wantFirst: int64(len("Ann")),
+			wantAgain: int64(len("Ursula")),
+		},
+		{
+			name:      "queries without Cache are not cached",
+			read:      func(books *Repository[cacheBook]) (int64, error) { return books.Count() },
+			between:   func(db *gorm.DB, m Model) error { return db.Create(&cacheBook{Title: "raw", AuthorID: 1}).Error },
+			wantFirst: 1,
+			wantAgain: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, &cacheAuthor{}, &cacheBook{})
+			m := NewModel(db).WithCache(cache.NewWithStore(cache.NewLRUStore(100)))
+			if err := db.Create(&cacheBook{Title: "first", Author: cacheAuthor{ID: 1, Name: "Ann"}}).Error; err != nil {
+				t.Fatal(err)
+			}
+			books := NewRepository[cacheBook](m)
+
+			if got, err := tt.read(books); err != nil || got != tt.wantFirst {
+				t.Fatalf("first read = %d, %v, want %d", got, err, tt.wantFirst)
+			}
+			if err := tt.between(db, m); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := tt.read(books); err != nil || got != tt.wantAgain {
+				t.Errorf("second read = %d, %v, want %d", got, err, tt.wantAgain)
+			}
+		})
+	}
+}
+
+func TestCacheInsideTransaction(t *testing.T) {
+	db := testdb.Open(t, &cacheAuthor{}, &cacheBook{})
+	m := NewModel(db).WithCache(cache.NewWithStore(cache.NewLRUStore(100)))
+	books := NewRepository[cacheBook](m)
+	if _, err := books.Cache(time.Minute).Count(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.Transaction(func(tx Model) error {
+		if err := NewRepository[cacheBook](tx).Create(&cacheBook{Title: "uncommitted"}); err != nil {
+			return err
+		}
+		// The transaction sees its own writes, even through a cached query.
+		if count, err := NewRepository[cacheBook](tx).Cache(time.Minute).Count(); err != nil || count != 1 {
+			t.Errorf("Count inside the transaction = %d, %v, want 1", count, err)
+		}
+		return errTxTest
+	})
+	if !errors.Is(err, errTxTest) {
+		t.Fatal(err)
+	}
+	if count, err := books.Cache(time.Minute).Count(); err != nil || count != 0 {
+		t.Errorf("Count after the rollback = %d, %v, want 0", count, err)
+	}
+}
+
+// TestCacheFlushFailure makes sure a write succeeds when the invalidation of the cache fails.
+func TestCacheFlushFailure(t *testing.T) {
+	store := &brokenFlushStore{LRUStore: cache.NewLRUStore(100)}
+	m := NewModel(testdb.Open(t, &cacheAuthor{}, &cacheBook{})).WithCache(cache.NewWithStore(store))
+	books := NewRepository[cacheBook](m)
+	if _, err := books.Cache(time.Minute).Count(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.broken = true
+	if err := books.Create(&cacheBook{Title: "new"}); err != nil {
+		t.Fatalf("Create with a failing cache flush = %v, want nil", err)
+	}
+	if count, err := books.Count(); err != nil || count != 1 {
+		t.Errorf("Count = %d, %v, want 1", count, err)
+	}
+}
@@ -0,0 +1,300 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStop can be returned by the callbacks of Chunk, ChunkByID and Each to stop the iteration early.
+// The iteration then returns nil instead of the error.
+var ErrStop = errors.New("stop iteration")
+
+// eachChunkSize is the number of records Each and Iterate load at a time.
+const eachChunkSize = 500
+
+// Chunk retrieves the records that match the current query in chunks of the given size and calls fn after each chunk
+// has been loaded into the loaded slice. Only one chunk is held in memory at a time. Every chunk is loaded into a
+// newly allocated slice, so the records of a chunk kept by fn are not overwritten by the next one.
+// Chunks are read with LIMIT/OFFSET, ordered by the primary key when the query has no order, so records inserted or
+// deleted by fn shift the following chunks; use ChunkByID when fn changes the matched records.
+// It stops at the first error returned by fn, returns nil when fn returns ErrStop, and returns the error of the context
+// when it is cancelled between two chunks.
+//
+// Example usage:
+//
+//	var users []model.User
+//	err := s.Model.Load(&users).Where("verified_at IS NULL").Chunk(ctx, 1000, func() error {
+//		return notify(users)
+//	})
+func (m *model) Chunk(ctx context.Context, size int, fn func() error) error {
+	return chunkByOffset(ctx, m.db.Session(&gorm.Session{}), size, m.tempData, fn)
+}
+
+// ChunkByID retrieves the records that match the current query in chunks of the given size, like Chunk,
+// but pages with "WHERE id > last id" instead of OFFSET. It stays correct when fn deletes or updates the records it
+// receives, and later chunks are as fast as the first one. Records are always walked in primary key order,
+// so any order of the query is ignored.
+func (m *model) ChunkByID(ctx context.Context, size int, fn func() error) error {
+	return chunkByID(ctx, m.db.Session(&gorm.Session{}), size, m.tempData, fn)
+}
+
+// Each loads every record that matches the current query, in primary key order, into the loaded struct and calls fn
+// after each of them. Records are loaded in chunks behind the scenes like Repository.Each, so the whole result set is
+// never held in memory. Return ErrStop from fn to stop early.
+//
+// Example usage:
+//
+//	var user model.User
+//	err := s.Model.Load(&user).Where("verified_at IS NULL").Each(ctx, func() error {
+//		return notify(user)
+//	})
+func (m *model) Each(ctx context.Context, fn func() error) error {
+	record := reflect.ValueOf(m.tempData)
+	if record.Kind() != reflect.Ptr || record.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("each needs a pointer to a struct, got %T", m.tempData)
+	}
+	chunk := reflect.New(reflect.SliceOf(record.Elem().Type()))
+	return chunkByID(ctx, m.db.Session(&gorm.Session{}), eachChunkSize, chunk.Interface(), func() error {
+		for i := 0; i < chunk.Elem().Len(); i++ {
+			record.Elem().Set(chunk.Elem().Index(i))
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Chunk retrieves the records that match the current query in chunks of the given size and calls fn with every chunk.
+// Every chunk is a new slice, fn may keep it. See model.Chunk.
+func (r *Repository[T]) Chunk(ctx context.Context, size int, fn func(chunk []T) error) error {
+	var chunk []T
+	return chunkByOffset(ctx, r.query(), size, &chunk, func() error { return fn(chunk) })
+}
+
+// ChunkByID retrieves the records that match the current query in primary key order, in chunks of the given size,
+// and calls fn with every chunk. See model.ChunkByID.
+func (r *Repository[T]) ChunkByID(ctx context.Context, size int, fn func(chunk []T) error) error {
+	var chunk []T
+	return chunkByID(ctx, r.query(), size, &chunk, func() error { return fn(chunk) })
+}
+
+// Each calls fn with every record that matches the current query, in primary key order.
+// Records are loaded in chunks behind the scenes, so the whole result set is never held in memory,
+// and fn may run other queries, also inside a transaction. Return ErrStop from fn to stop early.
+func (r *Repository[T]) Each(ctx context.Context, fn func(entity T) error) error {
+	return r.ChunkByID(ctx, eachChunkSize, func(chunk []T) error {
+		for _, entity := range chunk {
+			if err := fn(entity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Iterate returns an Iterator over the records that match the current query, in primary key order.
+// It loads the records in chunks of the given size, or 500 when size is not positive.
+//
+// Example usage:
+//
+//	it := users.Iterate(ctx, 1000)
+//	for it.Next() {
+//		user := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+func (r *Repository[T]) Iterate(ctx context.Context, size int) *Iterator[T] {
+	if size < 1 {
+		size = eachChunkSize
+	}
+	return &Iterator[T]{ctx: ctx, db: r.query(), size: size}
+}
+
+// Iterator walks the records of a query one at a time while loading them in chunks.
+// It is not safe for concurrent use.
+type Iterator[T any] struct {
+	ctx    context.Context
+	db     *gorm.DB
+	size   int
+	pager  *keysetPager
+	buffer []T
+	index  int
+	done   bool
+	err    error
+}
+
+// Next advances to the next record and reports whether there is one.
+// It returns false when the records are exhausted, the context is cancelled or a query fails; check Err afterwards.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.index++
+	if it.index < len(it.buffer) {
+		return true
+	}
+	if it.done {
+		return false
+	}
+
+	if it.pager == nil {
+		var err error
+		if it.pager, err = newKeysetPager(it.db, it.size, new(T)); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	var chunk []T
+	count, err := it.pager.next(it.ctx, &chunk)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.buffer, it.index = chunk, 0
+	it.done = count < it.size
+	return count > 0
+}
+
+// Value returns the current record.
+func (it *Iterator[T]) Value() T {
+	return it.buffer[it.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// chunkByOffset loads the records of the query into dest, a pointer to a slice, page by page using LIMIT/OFFSET.
+func chunkByOffset(ctx context.Context, db *gorm.DB, size int, dest interface{}, fn func() error) error {
+	if size < 1 {
+		return fmt.Errorf("chunk size must be positive, got %d", size)
+	}
+	query := db.WithContext(ctx)
+	if _, ordered := query.Statement.Clauses["ORDER BY"]; !ordered {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}})
+	}
+
+	for offset := 0; ; offset += size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		resetSlice(dest)
+		result := query.Offset(offset).Limit(size).Find(dest)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := fn(); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+		if int(result.RowsAffected) < size {
+			return nil
+		}
+	}
+}
+
+// chunkByID loads the records of the query into dest, a pointer to a slice, page by page using the primary key.
+func chunkByID(ctx context.Context, db *gorm.DB, size int, dest interface{}, fn func() error) error {
+	if size < 1 {
+		return fmt.Errorf("chunk size must be positive, got %d", size)
+	}
+	pager, err := newKeysetPager(db, size, dest)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		count, err := pager.next(ctx, dest)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if err := fn(); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+		if count < size {
+			return nil
+		}
+	}
+}
+
+// resetSlice sets the slice dest points to to nil, so the next Find allocates a new backing array instead of
+// reusing, and overwriting, the one of the previous chunk.
+func resetSlice(dest interface{}) {
+	slice := reflect.ValueOf(dest).Elem()
+	slice.Set(reflect.Zero(slice.Type()))
+}
+
+// keysetPager reads the records of a query page by page in primary key order, remembering the last key it returned.
+type keysetPager struct {
+	db      *gorm.DB
+	size    int
+	primary *schema.Field
+	last    interface{}
+}
+
+// newKeysetPager creates a keysetPager for the query and the model of the given value, which must have a single primary key.
+func newKeysetPager(db *gorm.DB, size int, value interface{}) (*keysetPager, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(value); err != nil {
+		return nil, err
+	}
+	if len(stmt.Schema.PrimaryFields) != 1 {
+		return nil, fmt.Errorf("cannot chunk %s by id, it must have exactly one primary key", stmt.Schema.Name)
+	}
+
+	// The primary key decides the order, drop any order of the query from a private copy of its statement.
+	query := db.Session(&gorm.Session{}).Clauses()
+	delete(query.Statement.Clauses, "ORDER BY")
+
+	return &keysetPager{db: query.Session(&gorm.Session{}), size: size, primary: stmt.Schema.PrimaryFields[0]}, nil
+}
+
+// next loads the next page into dest, a pointer to a slice, and returns the number of records loaded.
+func (p *keysetPager) next(ctx context.Context, dest interface{}) (int, error) {
+	column := clause.Column{Table: clause.CurrentTable, Name: p.primary.DBName}
+	query := p.db.WithContext(ctx).Order(clause.OrderByColumn{Column: column}).Limit(p.size)
+	if p.last != nil {
+		query = query.Where(clause.Gt{Column: column, Value: p.last})
+	}
+
+	resetSlice(dest)
+	result := query.Find(dest)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	records := reflect.Indirect(reflect.ValueOf(dest))
+	if records.Len() > 0 {
+		p.last, _ = p.primary.ValueOf(ctx, reflect.Indirect(records.Index(records.Len()-1)))
+	}
+	return records.Len(), nil
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// chunkItem is the model of the chunk tests.
+type chunkItem struct {
+	ID uint
+	N  int
+}
+
+// chunkItems creates the items 1 to 7 and returns their repository.
+func chunkItems(t *testing.T) *Repository[chunkItem] {
+	t.Helper()
+	items := NewRepository[chunkItem](NewModel(testdb.Open(t, &chunkItem{})))
+	for n := 1; n <= 7; n++ {
+		if err := items.Create(&chunkItem{N: n}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return items
+}
+
+// formatChunks formats the numbers of the given chunks as "1,2|3,4".
+func formatChunks(chunks [][]chunkItem) string {
+	var formatted []string
+	for _, chunk := range chunks {
+		var numbers []string
+		for _, item := range chunk {
+			numbers = append(numbers, fmt.Sprint(item.N))
+		}
+		formatted = append(formatted, strings.Join(numbers, ","))
+	}
+	return strings.Join(formatted, "|")
+}
+
+var errChunkTest = errors.New("failed by the test")
+
+func TestChunk(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		// run walks the items and keeps every chunk it receives, the chunks are formatted once the walk is over
+		// so a chunk overwritten by the next one is noticed.
+		run        func(items *Repository[chunkItem], keep func([]chunkItem)) error
+		wantChunks string
+		wantErr    error
+	}{
+		{
+			name: "chunk",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.Chunk(context.Background(), 3, func(chunk []chunkItem) error { keep(chunk); return nil })
+			},
+			wantChunks: "1,2,3|4,5,6|7",
+		},
+		{
+			name: "chunk keeps the order of the query",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.Order("n DESC").Chunk(context.Background(), 4, func(chunk []chunkItem) error { keep(chunk); return nil })
+			},
+			wantChunks: "7,6,5,4|3,2,1",
+		},
+		{
+			name: "chunk with conditions and an exact last chunk",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.Where("n > ?", 3).Chunk(context.Background(), 2, func(chunk []chunkItem) error { keep(chunk); return nil })
+			},
+			wantChunks: "4,5|6,7",
+		},
+		{
+			name: "chunk by id ignores the order of the query",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.Order("n DESC").ChunkByID(context.Background(), 3, func(chunk []chunkItem) error { keep(chunk); return nil })
+			},
+			wantChunks: "1,2,3|4,5,6|7",
+		},
+		{
+			name: "chunk by id while deleting the records",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.ChunkByID(context.Background(), 3, func(chunk []chunkItem) error {
+					keep(chunk)
+					for i := range chunk {
+						if err := items.Delete(&chunk[i]); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			},
+			wantChunks: "1,2,3|4,5,6|7",
+		},
+		{
+			name: "stop early",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.ChunkByID(context.Background(), 3, func(chunk []chunkItem) error { keep(chunk); return ErrStop })
+			},
+			wantChunks: "1,2,3",
+		},
+		{
+			name: "error of the callback",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.Chunk(context.Background(), 3, func(chunk []chunkItem) error { keep(chunk); return errChunkTest })
+			},
+			wantChunks: "1,2,3",
+			wantErr:    errChunkTest,
+		},
+		{
+			name: "cancelled context",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.ChunkByID(cancelled, 3, func(chunk []chunkItem) error { keep(chunk); return nil })
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "each",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				return items.Where("n % 2 = 0").Each(context.Background(), func(item chunkItem) error {
+					keep([]chunkItem{item})
+					return nil
+				})
+			},
+			wantChunks: "2|4|6",
+		},
+		{
+			name: "iterate",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				it := items.Iterate(context.Background(), 2)
+				for it.Next() {
+					keep([]chunkItem{it.Value()})
+				}
+				return it.Err()
+			},
+			wantChunks: "1|2|3|4|5|6|7",
+		},
+		{
+			name: "chunk of the model wrapper",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				var chunk []chunkItem
+				return items.m.Load(&chunk).Chunk(context.Background(), 3, func() error { keep(chunk); return nil })
+			},
+			wantChunks: "1,2,3|4,5,6|7",
+		},
+		{
+			name: "chunk by id of the model wrapper",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				var chunk []chunkItem
+				return items.m.Load(&chunk).Where("n > ?", 2).ChunkByID(context.Background(), 3, func() error { keep(chunk); return ErrStop })
+			},
+			wantChunks: "3,4,5",
+		},
+		{
+			name: "each of the model wrapper",
+			run: func(items *Repository[chunkItem], keep func([]chunkItem)) error {
+				var item chunkItem
+				return items.m.Load(&item).Where("n < ?", 4).Each(context.Background(), func() error {
+					keep([]chunkItem{item})
+					return nil
+				})
+			},
+			wantChunks: "1|2|3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chunks [][]chunkItem
+			err := tt.run(chunkItems(t), func(chunk []chunkItem) { chunks = append(chunks, chunk) })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := formatChunks(chunks); got != tt.wantChunks {
+				t.Errorf("chunks = %q, want %q", got, tt.wantChunks)
+			}
+		})
+	}
+}
+
+func TestChunkErrors(t *testing.T) {
+	items := chunkItems(t)
+	var item chunkItem
+	var slice []chunkItem
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"chunk size of zero", func() error {
+			return items.Chunk(context.Background(), 0, func([]chunkItem) error { return nil })
+		}},
+		{"negative chunk size", func() error {
+			return items.ChunkByID(context.Background(), -1, func([]chunkItem) error { return nil })
+		}},
+		{"each into a slice", func() error {
+			return items.m.Load(&slice).Each(context.Background(), func() error { return nil })
+		}},
+		{"each into a struct that is not a pointer", func() error {
+			return items.m.Load(item).Each(context.Background(), func() error { return nil })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil {
+				t.Error("succeeded")
+			}
+		})
+	}
+}
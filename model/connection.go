@@ -0,0 +1,96 @@
+package model
+
+import (
+	"GoAPIfy/core/database"
+	"errors"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ConnectionBound is implemented by models stored on a named database connection instead of the default one.
+// Load, and the repositories of the model, run its queries on that connection automatically, unless another
+// connection was selected with On.
+//
+// Example usage:
+//
+//	type LegacyCustomer struct {
+//		ID   uint
+//		Name string
+//	}
+//
+//	func (LegacyCustomer) Connection() string { return "legacy" }
+type ConnectionBound interface {
+	Connection() string
+}
+
+// connectionBoundType is the reflect type of the ConnectionBound interface.
+var connectionBoundType = reflect.TypeOf((*ConnectionBound)(nil)).Elem()
+
+// ErrConnectionsNotConfigured is returned by On when the model service has no named connections.
+var ErrConnectionsNotConfigured = errors.New("named connections are not configured, create the model service with WithConnections")
+
+// WithConnections returns a model that can select the named connections of the given set with On, and that runs
+// the queries of ConnectionBound models on their connection. The tenant scope and the blind indexes of encrypted
+// attributes are registered on every connection.
+//
+// Example usage:
+//
+//	connections, err := database.InitConnections(production)
+//	modelService := model.NewModel(connections.Default()).WithConnections(connections)
+func (m *model) WithConnections(connections *database.Connections) *model {
+	for _, name := range connections.Names() {
+		db, _ := connections.Get(name)
+		useTenancy(db)
+		useEncryption(db)
+	}
+	next := m.derive(m.db)
+	next.connections = connections
+	return next
+}
+
+// On makes the following queries run on the named database connection, such as "legacy", declared in DB_CONNECTIONS.
+// The returned model starts a new query on that connection with the context of the receiver, so the tenant of the
+// request still applies, and is not part of the transaction of the receiver: a transaction only covers the queries
+// of its own connection, start it with On(name).Transaction to write to another one. It takes precedence over the
+// connection declared by a ConnectionBound model. An unknown name makes the queries of the returned model fail.
+// It returns a new model and leaves the receiver untouched, so it can be called on the shared application model.
+//
+// Example usage:
+//
+//	var customers []LegacyCustomer
+//	err := s.Model.WithContext(c.Request.Context()).On("legacy").Load(&customers).Where("active = ?", true).Get()
+func (m *model) On(name string) *model {
+	var conn *gorm.DB
+	err := ErrConnectionsNotConfigured
+	if m.connections != nil {
+		conn, err = m.connections.Get(name)
+	}
+	if err != nil {
+		conn = m.db.Session(&gorm.Session{NewDB: true})
+		conn.AddError(err)
+	} else {
+		conn = conn.WithContext(m.db.Statement.Context)
+	}
+
+	// The connection is recorded also when it is unknown, so Load never falls back to the connection of the model.
+	next := m.derive(conn)
+	next.memoryDB, next.txDepth, next.pendingTags, next.pendingSearch = conn, 0, nil, nil
+	next.tempData, next.pivot = nil, ""
+	next.connection = strings.ToLower(name)
+	return next
+}
+
+// boundConnection returns the connection declared by the given entity, or an empty string for the default connection.
+func boundConnection(entity interface{}) string {
+	modelType := entityType(entity)
+	if modelType == nil || !reflect.PtrTo(modelType).Implements(connectionBoundType) {
+		return ""
+	}
+	name := reflect.New(modelType).Interface().(ConnectionBound).Connection()
+	if strings.EqualFold(name, database.DefaultConnection) {
+		return ""
+	}
+	return name
+}
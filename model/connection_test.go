@@ -0,0 +1,207 @@
+package model
+
+import (
+	"GoAPIfy/core/database"
+	"GoAPIfy/internal/testdb"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// connNote is stored on the default connection, connArchived on the "legacy" connection.
+// Both tables exist in both databases, so the tests see which database a query ran on.
+type connNote struct {
+	ID   uint
+	Text string
+}
+
+type connArchived struct {
+	ID   uint
+	Text string
+}
+
+func (connArchived) Connection() string { return "legacy" }
+
+// connProject is a TenantScoped model of the legacy connection.
+type connProject struct {
+	ID uint
+	TenantScoped
+	Name string
+}
+
+func (connProject) Connection() string { return "Legacy" }
+
+// openConnections returns a model whose default connection holds the records "main" and whose "legacy"
+// connection holds the records "legacy".
+func openConnections(t *testing.T) *model {
+	t.Helper()
+	main := testdb.Open(t, &connNote{}, &connArchived{}, &connProject{})
+	legacy := testdb.Open(t, &connNote{}, &connArchived{}, &connProject{})
+	for db, text := range map[*gorm.DB]string{main: "main", legacy: "legacy"} {
+		if err := db.Create(&connNote{Text: text}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&connArchived{Text: text}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	connections := database.NewConnections(main)
+	connections.Add("legacy", legacy)
+	return NewModel(main).WithConnections(connections)
+}
+
+// storedTexts returns the texts of the records of dest found by the given model.
+func storedTexts(t *testing.T, m *model, dest interface{}) (string, error) {
+	t.Helper()
+	err := m.Load(dest).Order("id").Get()
+	var texts []string
+	switch found := dest.(type) {
+	case *[]connNote:
+		for _, note := range *found {
+			texts = append(texts, note.Text)
+		}
+	case *[]connArchived:
+		for _, archived := range *found {
+			texts = append(texts, archived.Text)
+		}
+	}
+	return strings.Join(texts, ","), err
+}
+
+func TestOn(t *testing.T) {
+	m := openConnections(t)
+	tests := []struct {
+		name      string
+		model     *model
+		dest      func() interface{}
+		wantTexts string
+		wantErr   error
+	}{
+		{"default connection", m, func() interface{} { return &[]connNote{} }, "main", nil},
+		{"connection of the model", m, func() interface{} { return &[]connArchived{} }, "legacy", nil},
+		{"selected connection", m.On("legacy"), func() interface{} { return &[]connNote{} }, "legacy", nil},
+		{"name in another case", m.On("LEGACY"), func() interface{} { return &[]connNote{} }, "legacy", nil},
+		{"selected over the connection of the model", m.On("main"), func() interface{} { return &[]connArchived{} }, "main", nil},
+		{"unknown connection", m.On("crm"), func() interface{} { return &[]connNote{} }, "", database.ErrUnknownConnection},
+		{"unknown connection over the connection of the model", m.On("crm"), func() interface{} { return &[]connArchived{} }, "", database.ErrUnknownConnection},
+		{"without named connections", NewModel(m.db).On("legacy"), func() interface{} { return &[]connNote{} }, "", database.ErrUnknownConnection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			texts, err := storedTexts(t, tt.model, tt.dest())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if texts != tt.wantTexts {
+				t.Errorf("found %q, want %q", texts, tt.wantTexts)
+			}
+		})
+	}
+}
+
+func TestConnectionWrites(t *testing.T) {
+	errRollback := errors.New("rollback")
+	tests := []struct {
+		name string
+		// write writes a record with the text "new".
+		write        func(m *model) error
+		wantMain     string
+		wantLegacy   string
+		wantArchived string
+	}{
+		{
+			name: "repository of a bound model",
+			write: func(m *model) error {
+				return NewRepository[connArchived](m).Create(&connArchived{Text: "new"})
+			},
+			wantMain: "main", wantLegacy: "legacy", wantArchived: "legacy,new",
+		},
+		{
+			name: "repository on a connection",
+			write: func(m *model) error {
+				return NewRepository[connNote](m).On("legacy").Create(&connNote{Text: "new"})
+			},
+			wantMain: "main", wantLegacy: "legacy,new", wantArchived: "legacy",
+		},
+		{
+			name: "transaction on a connection",
+			write: func(m *model) error {
+				return m.On("legacy").Transaction(func(tx Model) error {
+					if err := tx.Load(&connNote{Text: "new"}).Create(); err != nil {
+						return err
+					}
+					return tx.Load(&connArchived{Text: "new"}).Create()
+				})
+			},
+			wantMain: "main", wantLegacy: "legacy,new", wantArchived: "legacy,new",
+		},
+		{
+			name: "rolled back transaction on a connection",
+			write: func(m *model) error {
+				err := m.On("legacy").Transaction(func(tx Model) error {
+					if err := tx.Load(&connArchived{Text: "new"}).Create(); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				if errors.Is(err, errRollback) {
+					return nil
+				}
+				return err
+			},
+			wantMain: "main", wantLegacy: "legacy", wantArchived: "legacy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := openConnections(t)
+			if err := tt.write(m); err != nil {
+				t.Fatal(err)
+			}
+			for _, check := range []struct {
+				model *model
+				dest  interface{}
+				want  string
+			}{
+				{m, &[]connNote{}, tt.wantMain},
+				{m.On("legacy"), &[]connNote{}, tt.wantLegacy},
+				{m, &[]connArchived{}, tt.wantArchived},
+			} {
+				texts, err := storedTexts(t, check.model, check.dest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if texts != check.want {
+					t.Errorf("stored %T %q, want %q", check.dest, texts, check.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionTenant(t *testing.T) {
+	m := openConnections(t)
+	projects := NewRepository[connProject](m)
+	for _, p := range []connProject{{TenantScoped: TenantScoped{TenantID: 1}, Name: "a"}, {TenantScoped: TenantScoped{TenantID: 2}, Name: "b"}} {
+		if err := projects.WithoutTenant().Create(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The tenant of the context and the tenant scope apply on the connection of the model.
+	found, err := projects.WithContext(WithTenant(context.Background(), 2)).All()
+	if err != nil || len(found) != 1 || found[0].Name != "b" {
+		t.Errorf("projects of tenant 2 = %+v, %v, want b", found, err)
+	}
+	if _, err := projects.All(); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("projects without a tenant = %v, want ErrMissingTenant", err)
+	}
+	if onMain, err := projects.On("main").WithoutTenant().Count(); err != nil || onMain != 0 {
+		t.Errorf("%d projects stored on the main connection (%v), want none", onMain, err)
+	}
+}
@@ -0,0 +1,287 @@
+package model
+
+import (
+	"GoAPIfy/core/math"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor is returned by CursorPaginate when the given cursor is malformed, has been tampered with,
+// or was created for another table or a different set of sort columns.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// CursorPagination represents a page of records retrieved with keyset (cursor) pagination.
+// It is the counterpart of Pagination for endpoints that page through large tables.
+// NextCursor and PrevCursor are nil when there is no next or previous page.
+type CursorPagination struct {
+	Records        interface{} `json:"records"`
+	NextCursor     *string     `json:"next_cursor"`
+	PrevCursor     *string     `json:"prev_cursor"`
+	RecordsPerPage int         `json:"record_per_page"`
+}
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// cursorPayload is the signed content of a cursor, Table is the table it was issued for.
+type cursorPayload struct {
+	Table     string            `json:"t"`
+	Columns   []string          `json:"c"`
+	Direction string            `json:"d"`
+	Values    []json.RawMessage `json:"v"`
+}
+
+// sortColumn is a validated sort column of the paginated model.
+type sortColumn struct {
+	field *schema.Field
+	desc  bool
+}
+
+// CursorPaginate retrieves a single page of the records that match the current query using keyset pagination.
+// It takes in a pointer to a slice of models, the cursor returned by a previous call (empty for the first page),
+// the number of records per page, and one or more sort columns. A column prefixed with "-" is sorted descending.
+// The primary key is appended as a tie breaker when it is not one of the sort columns, and sort columns must not be NULL.
+// Unlike Paginate, it never runs a COUNT query and stays fast and consistent on later pages.
+// It returns ErrInvalidCursor if the cursor cannot be trusted, and math.ErrMissingAppKey when APP_KEY is not set,
+// since cursors are signed with it. A cursor is only valid for the table it was issued for.
+func (m *model) CursorPaginate(model interface{}, cursor string, perPage int, columns ...string) (*CursorPagination, error) {
+	return cursorPaginate(m.db, model, cursor, perPage, columns)
+}
+
+// CursorPaginate retrieves a single page of the records that match the current query using keyset pagination.
+// See model.CursorPaginate for the meaning of the arguments. The Records field of the returned CursorPagination holds a []T.
+func (r *Repository[T]) CursorPaginate(cursor string, perPage int, columns ...string) (*CursorPagination, error) {
+	var entities []T
+	return cursorPaginate(r.query(), &entities, cursor, perPage, columns)
+}
+
+func cursorPaginate(db *gorm.DB, dest interface{}, cursor string, perPage int, columns []string) (*CursorPagination, error) {
+	if perPage < 1 {
+		perPage = 1
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(dest); err != nil {
+		return nil, err
+	}
+	sorts, specs, err := parseSortColumns(stmt.Schema, columns)
+	if err != nil {
+		return nil, err
+	}
+	table := stmt.Schema.Table
+	if db.Statement.Table != "" {
+		table = db.Statement.Table
+	}
+
+	direction := cursorNext
+	query := db
+	if cursor != "" {
+		payload, err := decodeCursor(cursor, table, specs)
+		if err != nil {
+			return nil, err
+		}
+		direction = payload.Direction
+		condition, err := keysetCondition(sorts, payload)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Clauses(clause.Where{Exprs: []clause.Expression{condition}})
+	}
+
+	// Walking backwards reverses every sort, the records are flipped back after the query.
+	for _, sort := range sorts {
+		desc := sort.desc
+		if direction == cursorPrev {
+			desc = !desc
+		}
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: sort.field.DBName}, Desc: desc})
+	}
+
+	if err := query.Limit(perPage + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	records := reflect.ValueOf(dest).Elem()
+	hasMore := records.Len() > perPage
+	if hasMore {
+		records.Set(records.Slice(0, perPage))
+	}
+	if direction == cursorPrev {
+		swap := reflect.Swapper(records.Interface())
+		for i, j := 0, records.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	pagination := &CursorPagination{Records: records.Interface(), RecordsPerPage: perPage}
+	if records.Len() == 0 {
+		return pagination, nil
+	}
+
+	// There is always a way back to where the cursor came from.
+	hasNext := hasMore || (direction == cursorPrev)
+	hasPrev := (direction == cursorNext && cursor != "") || (direction == cursorPrev && hasMore)
+
+	if hasNext {
+		next, err := encodeCursor(db, table, sorts, specs, cursorNext, records.Index(records.Len()-1))
+		if err != nil {
+			return nil, err
+		}
+		pagination.NextCursor = &next
+	}
+	if hasPrev {
+		prev, err := encodeCursor(db, table, sorts, specs, cursorPrev, records.Index(0))
+		if err != nil {
+			return nil, err
+		}
+		pagination.PrevCursor = &prev
+	}
+
+	return pagination, nil
+}
+
+// parseSortColumns validates the requested sort columns against the model schema and appends the primary key.
+// It returns the columns and their normalized textual form, which is stored in cursors.
+func parseSortColumns(s *schema.Schema, columns []string) ([]sortColumn, []string, error) {
+	var sorts []sortColumn
+	var specs []string
+	seen := map[string]bool{}
+
+	for _, column := range columns {
+		desc := strings.HasPrefix(column, "-")
+		name := strings.TrimPrefix(column, "-")
+		field := s.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return nil, nil, fmt.Errorf("cannot paginate %s by unknown column %q", s.Name, name)
+		}
+		if seen[field.DBName] {
+			continue
+		}
+		seen[field.DBName] = true
+		sorts = append(sorts, sortColumn{field: field, desc: desc})
+		if desc {
+			specs = append(specs, "-"+field.DBName)
+		} else {
+			specs = append(specs, field.DBName)
+		}
+	}
+
+	if primary := s.PrioritizedPrimaryField; primary != nil && !seen[primary.DBName] {
+		sorts = append(sorts, sortColumn{field: primary})
+		specs = append(specs, primary.DBName)
+	}
+	if len(sorts) == 0 {
+		return nil, nil, fmt.Errorf("cannot paginate %s without a sort column", s.Name)
+	}
+
+	return sorts, specs, nil
+}
+
+// keysetCondition builds the row comparison that selects the records after (or before) the cursor position:
+// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND c > ?) ...
+func keysetCondition(sorts []sortColumn, payload *cursorPayload) (clause.Expression, error) {
+	values := make([]interface{}, len(sorts))
+	for i, sort := range sorts {
+		value := reflect.New(sort.field.FieldType)
+		if err := json.Unmarshal(payload.Values[i], value.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	var ors []clause.Expression
+	for i, sort := range sorts {
+		var ands []clause.Expression
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: sortClauseColumn(sorts[j]), Value: values[j]})
+		}
+
+		after := !sort.desc
+		if payload.Direction == cursorPrev {
+			after = !after
+		}
+		if after {
+			ands = append(ands, clause.Gt{Column: sortClauseColumn(sort), Value: values[i]})
+		} else {
+			ands = append(ands, clause.Lt{Column: sortClauseColumn(sort), Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+
+	if len(ors) == 1 {
+		return ors[0], nil
+	}
+	return clause.Or(ors...), nil
+}
+
+func sortClauseColumn(sort sortColumn) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: sort.field.DBName}
+}
+
+// encodeCursor creates a signed cursor pointing at the given record of the table.
+func encodeCursor(db *gorm.DB, table string, sorts []sortColumn, specs []string, direction string, record reflect.Value) (string, error) {
+	payload := cursorPayload{Table: table, Columns: specs, Direction: direction}
+	for _, sort := range sorts {
+		value, _ := sort.field.ValueOf(db.Statement.Context, reflect.Indirect(record))
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		payload.Values = append(payload.Values, raw)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	signature, err := math.Sign(data)
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(data) + "." + encoding.EncodeToString(signature), nil
+}
+
+// decodeCursor verifies the signature of a cursor and makes sure it belongs to the table and the requested sort columns.
+func decodeCursor(cursor string, table string, specs []string) (*cursorPayload, error) {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	encoding := base64.RawURLEncoding
+	data, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := encoding.DecodeString(parts[1])
+	if err != nil || !math.VerifySignature(data, signature) {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Direction != cursorNext && payload.Direction != cursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Table != table || strings.Join(payload.Columns, ",") != strings.Join(specs, ",") || len(payload.Values) != len(specs) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &payload, nil
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"GoAPIfy/core/math"
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// cursorPost and cursorComment are the models of the cursor pagination tests.
+type cursorPost struct {
+	ID    uint
+	Title string
+	Score int
+}
+
+type cursorComment struct {
+	ID    uint
+	Score int
+}
+
+// cursorTitles returns the titles of a page of posts.
+func cursorTitles(pagination *CursorPagination) string {
+	var titles []string
+	for _, post := range pagination.Records.([]cursorPost) {
+		titles = append(titles, post.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestCursorPaginateWalksBothWays(t *testing.T) {
+	t.Setenv("APP_KEY", "test-key")
+	posts := NewRepository[cursorPost](NewModel(testdb.Open(t, &cursorPost{})))
+	// Scores tie, so the primary key decides the order within a score.
+	for i, score := range []int{3, 1, 2, 3, 1, 2, 3} {
+		if err := posts.Create(&cursorPost{Title: string(rune('a' + i)), Score: score}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		columns   []string
+		wantPages []string
+	}{
+		{"primary key", nil, []string{"a,b,c", "d,e,f", "g"}},
+		{"ascending with ties", []string{"score"}, []string{"b,e,c", "f,a,d", "g"}},
+		{"descending with ties", []string{"-score"}, []string{"a,d,g", "c,f,b", "e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cursors []string
+			cursor := ""
+			for i, want := range tt.wantPages {
+				page, err := posts.CursorPaginate(cursor, 3, tt.columns...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := cursorTitles(page); got != want {
+					t.Fatalf("page %d = %s, want %s", i+1, got, want)
+				}
+				if (page.PrevCursor != nil) != (i > 0) {
+					t.Errorf("page %d has a previous cursor: %v", i+1, page.PrevCursor != nil)
+				}
+				last := i == len(tt.wantPages)-1
+				if (page.NextCursor == nil) != last {
+					t.Fatalf("page %d has a next cursor: %v", i+1, page.NextCursor != nil)
+				}
+				if page.PrevCursor != nil {
+					cursors = append(cursors, *page.PrevCursor)
+				}
+				if !last {
+					cursor = *page.NextCursor
+				}
+			}
+
+			// Walk back from the last page with the previous cursors.
+			for i := len(cursors) - 1; i >= 0; i-- {
+				page, err := posts.CursorPaginate(cursors[i], 3, tt.columns...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := cursorTitles(page), tt.wantPages[i]; got != want {
+					t.Errorf("previous page %d = %s, want %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCursorPaginateRejectsCursors(t *testing.T) {
+	t.Setenv("APP_KEY", "test-key")
+	db := testdb.Open(t, &cursorPost{}, &cursorComment{})
+	posts := NewRepository[cursorPost](NewModel(db))
+	comments := NewRepository[cursorComment](NewModel(db))
+	for i := 0; i < 3; i++ {
+		if err := posts.Create(&cursorPost{Score: i}); err != nil {
+			t.Fatal(err)
+		}
+		if err := comments.Create(&cursorComment{Score: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := posts.CursorPaginate("", 1, "score")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := *page.NextCursor
+	data, signature, _ := strings.Cut(cursor, ".")
+
+	tests := []struct {
+		name     string
+		paginate func() (*CursorPagination, error)
+		wantErr  error
+	}{
+		{"valid cursor", func() (*CursorPagination, error) { return posts.CursorPaginate(cursor, 1, "score") }, nil},
+		{"garbage", func() (*CursorPagination, error) { return posts.CursorPaginate("not-a-cursor", 1, "score") }, ErrInvalidCursor},
+		{"tampered payload", func() (*CursorPagination, error) {
+			return posts.CursorPaginate(data+"x."+signature, 1, "score")
+		}, ErrInvalidCursor},
+		{"other sort columns", func() (*CursorPagination, error) { return posts.CursorPaginate(cursor, 1, "-score") }, ErrInvalidCursor},
+		{"other table with the same columns", func() (*CursorPagination, error) { return comments.CursorPaginate(cursor, 1, "score") }, ErrInvalidCursor},
+		{"other key", func() (*CursorPagination, error) {
+			t.Setenv("APP_KEY", "other-key")
+			return posts.CursorPaginate(cursor, 1, "score")
+		}, ErrInvalidCursor},
+		{"missing key", func() (*CursorPagination, error) {
+			t.Setenv("APP_KEY", "")
+			return posts.CursorPaginate("", 1, "score")
+		}, math.ErrMissingAppKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.paginate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCursorPaginateUnknownColumn(t *testing.T) {
+	t.Setenv("APP_KEY", "test-key")
+	posts := NewRepository[cursorPost](NewModel(testdb.Open(t, &cursorPost{})))
+	if _, err := posts.CursorPaginate("", 10, "password"); err == nil {
+		t.Error("sorting on an unknown column was accepted")
+	}
+	if _, err := posts.ApplyScope(func(db *gorm.DB) *gorm.DB { return db.Where("score > ?", 10) }).CursorPaginate("", 10); err != nil {
+		t.Errorf("an empty page failed: %s", err)
+	}
+}
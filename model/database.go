@@ -1,31 +1,26 @@
 package model
 
 import (
+	"GoAPIfy/core/cache"
+	"GoAPIfy/core/database"
+	"GoAPIfy/core/search"
+	"context"
 	"fmt"
 	"math"
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// AutoMigration automatically migrates the models to the database schema.
-// It takes in a pointer to the GORM database object, and returns an error if one occurs.
-// New models should be added here to ensure their corresponding database tables are created or updated during migration.
-func AutoMigration(db *gorm.DB) error {
-	err := db.AutoMigrate(
-		&User{},
-		&EmailVerification{},
-	)
-	return err
-}
-
 // Model is the interface that must be implemented by models.
 // It defines the common methods that will be used across different models.
 type Model interface {
-	Find(id uint) error
+	Find(id interface{}) error
 	Where(query interface{}, args ...interface{}) *model
 	Or(query interface{}, args ...interface{}) *model
 	Save() error
+	Create() error
 	Delete() error
 	Load(model interface{}) *model
 	Count() (int64, error)
@@ -37,6 +32,13 @@ type Model interface {
 	Select(query interface{}, args ...interface{}) *model
 	Joins(query string, args ...interface{}) *model
 	WithCondition(relation string, args ...interface{}) *model
+	Transaction(fc func(tx Model) error) error
+	OnPrimary() *model
+	On(name string) *model
+	WithContext(ctx context.Context) *model
+	ForTenant(tenantID uint) *model
+	WithoutTenant() *model
+	Search(text string, filter string, sort ...string) error
 }
 
 // model is the concrete type that implements the Model interface.
@@ -45,6 +47,26 @@ type model struct {
 	db       *gorm.DB
 	tempData interface{}
 	memoryDB *gorm.DB
+	txDepth  int
+
+	// cache stores query results, remember holds the caching requested on the current query chain,
+	// and pendingTags collects the cache tags to flush once the current transaction is committed.
+	cache       *cache.Cache
+	remember    *cacheOptions
+	pendingTags *[]string
+
+	// search keeps the indexes of Searchable models up to date,
+	// and pendingSearch collects the index updates to queue once the current transaction is committed.
+	search        search.Engine
+	pendingSearch *[]func()
+
+	// pivot is the pivot table joined by Related, which WherePivot filters on.
+	pivot string
+
+	// connections holds the named database connections selected with On, and connection is the name of the
+	// connection selected on the current chain, empty for the default one.
+	connections *database.Connections
+	connection  string
 }
 
 // Pagination represents pagination information.
@@ -57,9 +79,21 @@ type Pagination struct {
 }
 
 // NewModel creates a new instance of the model type with the specified database connection.
+// It registers the tenant scope of TenantScoped models and the blind indexes of encrypted attributes on the connection,
+// which becomes the default connection; use WithConnections to add the other named connections.
 func NewModel(db *gorm.DB) *model {
+	useTenancy(db)
+	useEncryption(db)
 	var v interface{}
-	return &model{db, v, db}
+	return &model{db: db, tempData: v, memoryDB: db, connections: database.NewConnections(db)}
+}
+
+// derive returns a copy of the model that runs on the given database connection.
+// The copy keeps the settings of the model, such as the transaction it belongs to, so they survive Load and repository chains.
+func (m *model) derive(db *gorm.DB) *model {
+	next := *m
+	next.db = db
+	return &next
 }
 
 // modelLoad specifies the model to be used for subsequent database operations.
@@ -69,22 +103,27 @@ func NewModel(db *gorm.DB) *model {
 // Load loads data into the specified model object.
 // It takes in a pointer to the model object, loads it into the model, and returns a pointer to the model object.
 // This method loads the specified model object into the current model, allowing it to be used for subsequent database operations.
+// A ConnectionBound model switches to its connection, unless a connection was selected with On.
 func (m *model) Load(entity interface{}) *model {
+	if name := boundConnection(entity); name != "" && m.connection == "" {
+		m = m.On(name)
+	}
 
 	// Create a new instance of model with the updated tempData
-	newModelInstance := &model{
-		db:       m.db.Model(entity),
-		tempData: entity,
-	}
+	newModelInstance := m.derive(m.db.Model(entity))
+	newModelInstance.tempData = entity
 	return newModelInstance
 }
 
 // Find searches for a model with the specified ID and returns it.
-// It takes in a uint representing the ID to search for, and returns the found data and an error, if any.
+// It takes in the ID to search for, a number for gorm.Model or a string for UUIDModel and ULIDModel,
+// and returns the found data and an error, if any.
 // If the data is found, the function returns the data with a nil error. If an error occurs, it returns an error object with the corresponding error message.
-func (m *model) Find(id uint) error {
-	err := m.db.First(m.tempData, id).Error
-	return err
+func (m *model) Find(id interface{}) error {
+	return m.cached("find", m.tempData, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		// The ID is always compared with the primary key, a string ID passed to First would be read as SQL.
+		return db.Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(dest)
+	})
 }
 
 // Where applies the specified query to the model.
@@ -95,6 +134,17 @@ func (m *model) Where(query interface{}, args ...interface{}) *model {
 	return m
 }
 
+// OnPrimary makes the following queries run on the primary database, even when read replicas are configured.
+// Reads are otherwise served by a replica, which may lag behind, so use it to read data right after writing it.
+// It returns a new model and leaves the receiver untouched, so it can be called on the shared application model.
+//
+// Example usage:
+//
+//	err := s.Model.OnPrimary().Load(&user).Find(user.ID)
+func (m *model) OnPrimary() *model {
+	return m.derive(database.OnPrimary(m.db))
+}
+
 func (m *model) Debug() *model {
 	m.db = m.db.Debug()
 	return m
@@ -117,21 +167,23 @@ func (m *model) Or(query interface{}, args ...interface{}) *model {
 // It takes no input parameters and returns an error, if any.
 // If the retrieval is successful, the function returns nil. If an error occurs, it returns an error object with the corresponding error message.
 func (m *model) Get() error {
-	return m.db.Find(m.tempData).Error
+	return m.cached("get", m.tempData, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Find(dest)
+	})
 }
 
 // OrderBy specifies the order in which the records should be retrieved.
 // It takes in two strings representing the column and the mode of the order, respectively, and returns a pointer to the model object.
 // This method orders the records in the database by the specified column, with the specified mode of order (ASC or DESC).
+// The column name is quoted and any mode other than DESC sorts ascending, so neither argument can inject SQL.
 func (m *model) OrderBy(column string, mode string) *model {
-	mode = strings.ToUpper(mode)
-	order := fmt.Sprintf("%s %s", column, mode)
-	m.db = m.db.Order(order)
+	desc := strings.ToUpper(strings.TrimSpace(mode)) == "DESC"
+	m.db = m.db.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc})
 	return m
 }
 
 func (m *model) Joins(query string, args ...interface{}) *model {
-	m.db = m.db.Joins(query, args)
+	m.db = m.db.Joins(query, args...)
 	return m
 }
 
@@ -165,8 +217,72 @@ func (m *model) Limit(limit int) *model {
 // It takes no input parameters and returns an error, if any.
 // If the deletion is successful, the function returns nil. If an error occurs, it returns an error object with the corresponding error message.
 func (m *model) Delete() error {
-	err := m.db.Delete(m.tempData).Error
-	return err
+	if !m.tracked(Deleting, Deleted) {
+		return m.written(m.db.Delete(m.tempData).Error)
+	}
+	return m.written(m.observe(func(tx *model) ([]change, error) {
+		return tx.deleteChanges(false)
+	}, func(tx *model) error {
+		return tx.db.Delete(tx.tempData).Error
+	}))
+}
+
+// WithTrashed includes soft deleted records in the current query.
+// It takes no input parameters and returns a pointer to the model object.
+func (m *model) WithTrashed() *model {
+	m.db = m.db.Unscoped()
+	return m
+}
+
+// OnlyTrashed limits the current query to soft deleted records.
+// It takes no input parameters and returns a pointer to the model object.
+// Models without a gorm.DeletedAt field have no trashed records, so the query matches nothing.
+func (m *model) OnlyTrashed() *model {
+	m.db = m.db.Unscoped().Where(trashedCondition(m.db, m.tempData))
+	return m
+}
+
+// Restore brings the loaded soft deleted record back by clearing its deleted_at column.
+// It takes no input parameters and returns an error, if any.
+// The loaded model must have its primary key set, or the current query must have conditions; restoring a whole table is refused.
+func (m *model) Restore() error {
+	column, ok := softDeleteColumn(m.db, m.tempData)
+	if !ok {
+		return fmt.Errorf("%T is not soft deletable", m.tempData)
+	}
+	if !m.tracked(Restoring, Restored) {
+		return m.written(m.db.Unscoped().Model(m.tempData).Update(column, nil).Error)
+	}
+	return m.written(m.observe(func(tx *model) ([]change, error) {
+		records, err := tx.currentRecords(true)
+		if err != nil {
+			return nil, err
+		}
+		var changes []change
+		for _, record := range records {
+			restored, err := tx.applyValues(record, map[string]interface{}{column: nil})
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change{before: Restoring, after: Restored, old: record, new: restored})
+		}
+		return changes, nil
+	}, func(tx *model) error {
+		return tx.db.Unscoped().Model(tx.tempData).Update(column, nil).Error
+	}))
+}
+
+// ForceDelete permanently deletes the loaded record, even if the model supports soft deletes.
+// It takes no input parameters and returns an error, if any.
+func (m *model) ForceDelete() error {
+	if !m.tracked(Deleting, Deleted) {
+		return m.written(m.db.Unscoped().Delete(m.tempData).Error)
+	}
+	return m.written(m.observe(func(tx *model) ([]change, error) {
+		return tx.deleteChanges(true)
+	}, func(tx *model) error {
+		return tx.db.Unscoped().Delete(tx.tempData).Error
+	}))
 }
 
 // Count returns the total number of records that match the current query.
@@ -174,7 +290,9 @@ func (m *model) Delete() error {
 // If the counting is successful, the function returns the total number of records with a nil error. If an error occurs, it returns an error object with the corresponding error message.
 func (m *model) Count() (int64, error) {
 	var count int64
-	err := m.db.Count(&count).Error
+	err := m.cached("count", &count, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Count(dest.(*int64))
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -188,14 +306,33 @@ func (m *model) Count() (int64, error) {
 // If the pagination is successful, the function returns a pointer to the Pagination object with a nil error. If an error occurs, it returns an error object with the corresponding error message.
 func (m *model) Paginate(model interface{}, page int, perPage int) (*Pagination, error) {
 	var totalRecords int64
-	m.db.Model(model).Count(&totalRecords)
-
 	offset := (page - 1) * perPage
-	m.db = m.db.Offset(offset).Limit(perPage)
 
-	err := m.db.Find(model).Error
-	if err != nil {
-		return nil, err
+	if m.remember != nil {
+		if m.tempData == nil {
+			m.tempData = model
+		}
+		// Cached queries run on copies of the chain, so the count and the page do not affect each other.
+		err := m.cached("paginate:count", &totalRecords, func(db *gorm.DB, dest interface{}) *gorm.DB {
+			return db.Model(model).Count(dest.(*int64))
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = m.cached(fmt.Sprintf("paginate:%d:%d", page, perPage), model, func(db *gorm.DB, dest interface{}) *gorm.DB {
+			return db.Offset(offset).Limit(perPage).Find(dest)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		m.db.Model(model).Count(&totalRecords)
+		m.db = m.db.Offset(offset).Limit(perPage)
+
+		err := m.db.Find(model).Error
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	totalPages := int(math.Ceil(float64(int(totalRecords)) / float64(perPage)))
@@ -215,11 +352,32 @@ func (m *model) Paginate(model interface{}, page int, perPage int) (*Pagination,
 // It takes no input parameters and returns an error, if any.
 // If the creation or update is successful, the function returns nil. If an error occurs, it returns an error object with the corresponding error message.
 func (m *model) Save() error {
-	err := m.db.Save(m.tempData).Error
-	if err != nil {
-		return err
+	if !m.tracked(Creating, Created, Updating, Updated) {
+		return m.written(m.save())
 	}
-	return nil
+	return m.written(m.observe(func(tx *model) ([]change, error) {
+		return tx.saveChanges()
+	}, func(tx *model) error {
+		return tx.save()
+	}))
+}
+
+// Create inserts the loaded model as a new record, even if its ID is already set.
+// It takes no input parameters and returns an error, if any.
+// On success the generated fields of the model, such as the ID and timestamps, are filled in.
+func (m *model) Create() error {
+	if !m.tracked(Creating, Created) {
+		return m.written(m.db.Create(m.tempData).Error)
+	}
+	return m.written(m.observe(func(tx *model) ([]change, error) {
+		var changes []change
+		for _, entity := range tx.loadedEntities() {
+			changes = append(changes, change{before: Creating, after: Created, new: entity.Interface()})
+		}
+		return changes, nil
+	}, func(tx *model) error {
+		return tx.db.Create(tx.tempData).Error
+	}))
 }
 
 // With adds an eager load for the specified relation.
@@ -235,9 +393,90 @@ func (m *model) WithCondition(relation string, args ...interface{}) *model {
 	return m
 }
 
+// Transaction runs fc inside a database transaction and passes it a Model bound to that transaction.
+// The transaction is committed when fc returns nil, and rolled back when fc returns an error or panics;
+// a panic is re-raised after the rollback. Calling Transaction on the tx Model nests the transaction using a SAVEPOINT,
+// so an error in the inner function only rolls back the work done inside it.
+// The receiver is never modified, so it is safe to call on the shared AppService.Model from concurrent requests.
+//
+// Example usage:
+//
+//	err := s.Model.Transaction(func(tx model.Model) error {
+//		users := model.NewRepository[model.User](tx)
+//		return users.Create(&user)
+//	})
+func (m *model) Transaction(fc func(tx Model) error) error {
+	return m.transaction(m.db.Session(&gorm.Session{NewDB: true}), func(tx *model) error {
+		tx.tempData = nil
+		return fc(tx)
+	})
+}
+
+// transaction runs fc inside a transaction, or inside a savepoint when the model already belongs to one.
+// conn decides what the tx model starts from: a NewDB session for a clean connection, or a plain session to keep the current query.
+func (m *model) transaction(conn *gorm.DB, fc func(tx *model) error) (err error) {
+	if m.txDepth == 0 {
+		var pendingTags []string
+		var pendingSearch []func()
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			txModel := m.derive(tx)
+			txModel.memoryDB, txModel.txDepth, txModel.pendingTags, txModel.pendingSearch = tx, 1, &pendingTags, &pendingSearch
+			return fc(txModel)
+		})
+		if err == nil && m.cache != nil && len(pendingTags) > 0 {
+			flushCache(conn.Statement.Context, m.cache, pendingTags)
+		}
+		if err == nil {
+			for _, update := range pendingSearch {
+				update()
+			}
+		}
+		return err
+	}
+
+	// Nested transaction, every depth has its own savepoint name so an inner savepoint never replaces an outer one.
+	savepoint := fmt.Sprintf("goapify_sp%d", m.txDepth)
+	if err := conn.SavePoint(savepoint).Error; err != nil {
+		return err
+	}
+
+	// The cache tags and search updates queued inside the savepoint are dropped with it when it is rolled back,
+	// the records they refer to are back as they were.
+	tags, updates := pendingLen(m.pendingTags), pendingLen(m.pendingSearch)
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			conn.RollbackTo(savepoint)
+			if m.pendingTags != nil {
+				*m.pendingTags = (*m.pendingTags)[:tags]
+			}
+			if m.pendingSearch != nil {
+				*m.pendingSearch = (*m.pendingSearch)[:updates]
+			}
+		}
+	}()
+
+	txModel := m.derive(conn)
+	txModel.memoryDB, txModel.txDepth = conn, m.txDepth+1
+	err = fc(txModel)
+	panicked = false
+	return err
+}
+
+// pendingLen returns the number of queued items of a pending list of the transaction, zero without one.
+func pendingLen[T any](pending *[]T) int {
+	if pending == nil {
+		return 0
+	}
+	return len(*pending)
+}
+
 // BeginTransaction starts a new database transaction and returns a pointer to
 // the model instance, allowing for method chaining. It saves the current database
 // connection state in memoryDB before starting the transaction.
+//
+// Deprecated: BeginTransaction modifies the model in place, which leaks the transaction into every
+// user of a shared model and leaves rollback on panic to the caller. Use Transaction instead.
 func (m *model) BeginTransaction() *model {
 	tx := m.db.Begin()
 	m.memoryDB = m.db
@@ -247,12 +486,16 @@ func (m *model) BeginTransaction() *model {
 
 // CommitTransaction commits the current database transaction and returns an error
 // if any issues occur during the commit operation.
+//
+// Deprecated: use Transaction instead.
 func (m *model) CommitTransaction() error {
 	return m.db.Commit().Error
 }
 
 // RollbackTransaction rolls back the current database transaction and returns an
 // error if any issues occur during the rollback operation.
+//
+// Deprecated: use Transaction instead.
 func (m *model) RollbackTransaction() error {
 	return m.db.Rollback().Error
 }
@@ -294,14 +537,33 @@ func (m *model) Distinct(columns ...string) *model {
 // returns an error if any issues occur during the update operation. This method does
 // not trigger callbacks or validations.
 func (m *model) UpdateColumn(column string, value interface{}) error {
-	return m.db.UpdateColumn(column, value).Error
+	return m.UpdateColumns(map[string]interface{}{column: value})
 }
 
 // UpdateColumns updates multiple columns in the model's query with the given values map
 // and returns an error if any issues occur during the update operation. This method does
 // not trigger callbacks or validations.
 func (m *model) UpdateColumns(values map[string]interface{}) error {
-	return m.db.UpdateColumns(values).Error
+	if !m.tracked(Updating, Updated) {
+		return m.written(m.db.UpdateColumns(m.versionedValues(values)).Error)
+	}
+	return m.written(m.observe(func(tx *model) ([]change, error) {
+		records, err := tx.currentRecords(tx.db.Statement.Unscoped)
+		if err != nil {
+			return nil, err
+		}
+		var changes []change
+		for _, record := range records {
+			updated, err := tx.applyValues(record, values)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change{before: Updating, after: Updated, old: record, new: updated})
+		}
+		return changes, nil
+	}, func(tx *model) error {
+		return tx.db.UpdateColumns(tx.versionedValues(values)).Error
+	}))
 }
 
 // Max finds the maximum value of the specified column for the records that match
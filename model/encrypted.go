@@ -0,0 +1,384 @@
+package model
+
+import (
+	"GoAPIfy/core/math"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer encrypts a field on write and decrypts it on read, selected with the `serializer:encrypted` GORM tag.
+// Values are encrypted with AES-256-GCM under a key derived from APP_KEY, see math.Seal, so changing APP_KEY makes the
+// stored values unreadable. Strings and byte slices are encrypted as they are, every other type, such as a struct,
+// a map or a time.Time, as its JSON encoding; a nil pointer, slice or map is stored as NULL.
+// The encrypted value is longer than the plain one, so give the column a text type.
+//
+// An encrypted column cannot be compared in SQL. Add `blind_index:<field>` to its tag, naming a string field that
+// receives a keyed hash of the value on every create and update, to look records up with WhereEncrypted.
+// Encrypted columns and their blind indexes are left out of the audit trail, and queries on a model with encrypted
+// columns skip the query cache, so the decrypted values are never stored outside of the database.
+//
+// Example usage:
+//
+//	type Customer struct {
+//		gorm.Model
+//		SSN         string            `gorm:"serializer:encrypted;type:text;blind_index:SSNIndex" json:"-"`
+//		SSNIndex    string            `gorm:"size:64;index" json:"-"`
+//		APIToken    *string           `gorm:"serializer:encrypted;type:text" json:"-"`
+//		Credentials map[string]string `gorm:"serializer:encrypted;type:text" json:"-"`
+//		BornAt      time.Time         `gorm:"serializer:encrypted;type:text"`
+//	}
+type EncryptedSerializer struct{}
+
+// Scan decrypts the database value into the field.
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	value := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var sealed string
+		switch v := dbValue.(type) {
+		case []byte:
+			sealed = string(v)
+		case string:
+			sealed = v
+		default:
+			return fmt.Errorf("cannot decrypt the %s column from a %T", field.DBName, dbValue)
+		}
+		plaintext, err := math.Unseal(sealed)
+		if err != nil {
+			return fmt.Errorf("cannot decrypt the %s column: %w", field.DBName, err)
+		}
+		if err := decodePlaintext(plaintext, value); err != nil {
+			return fmt.Errorf("cannot decode the %s column: %w", field.DBName, err)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(value.Elem())
+	return nil
+}
+
+// Value encrypts the field value for the database.
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok, err := encodePlaintext(fieldValue)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return math.Seal(plaintext)
+}
+
+// encodePlaintext returns the bytes encrypted for the given value, or false for a nil value.
+func encodePlaintext(value interface{}) ([]byte, bool, error) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case !v.IsValid():
+		return nil, false, nil
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil():
+		return nil, false, nil
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), true, nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), true, nil
+	}
+	encoded, err := json.Marshal(v.Interface())
+	return encoded, err == nil, err
+}
+
+// decodePlaintext decodes the decrypted bytes into the value the given pointer points to, the reverse of encodePlaintext.
+func decodePlaintext(plaintext []byte, ptr reflect.Value) error {
+	target := ptr.Elem()
+	for target.Kind() == reflect.Ptr {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+	switch {
+	case target.Kind() == reflect.String:
+		target.SetString(string(plaintext))
+		return nil
+	case target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.Uint8:
+		target.SetBytes(append([]byte(nil), plaintext...))
+		return nil
+	}
+	return json.Unmarshal(plaintext, target.Addr().Interface())
+}
+
+// isEncrypted reports whether the field uses the EncryptedSerializer.
+func isEncrypted(field *schema.Field) bool {
+	_, ok := field.Serializer.(EncryptedSerializer)
+	return ok
+}
+
+// blindIndex is an encrypted field together with the field holding its blind index.
+type blindIndex struct {
+	field, index *schema.Field
+}
+
+// blindIndexes returns the encrypted fields of the schema that declare a blind index.
+func blindIndexes(s *schema.Schema) ([]blindIndex, error) {
+	var indexes []blindIndex
+	for _, field := range s.Fields {
+		name := field.TagSettings["BLIND_INDEX"]
+		if name == "" || !isEncrypted(field) {
+			continue
+		}
+		index := s.LookUpField(name)
+		if index == nil || index.DBName == "" {
+			return nil, fmt.Errorf("the blind index %s of %s.%s is not a column of the model", name, s.Name, field.Name)
+		}
+		indexes = append(indexes, blindIndex{field: field, index: index})
+	}
+	return indexes, nil
+}
+
+// of returns the blind index of the given value, or the zero value of the index field for a nil value.
+func (b blindIndex) of(value interface{}) (interface{}, error) {
+	plaintext, ok, err := encodePlaintext(value)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return reflect.Zero(b.index.FieldType).Interface(), nil
+	}
+	return math.BlindIndex(b.field.DBName, plaintext)
+}
+
+// encryptedColumns returns the columns of the schema holding encrypted values or their blind indexes.
+func encryptedColumns(s *schema.Schema) map[string]bool {
+	columns := map[string]bool{}
+	for _, field := range s.Fields {
+		if !isEncrypted(field) {
+			continue
+		}
+		columns[field.DBName] = true
+		if index := s.LookUpField(field.TagSettings["BLIND_INDEX"]); index != nil {
+			columns[index.DBName] = true
+		}
+	}
+	return columns
+}
+
+// readsEncrypted reports whether a query on the given model, with the eager loads of the current query, reads encrypted columns.
+func (m *model) readsEncrypted(model interface{}) bool {
+	stmt := &gorm.Statement{DB: m.db}
+	if model == nil || stmt.Parse(model) != nil {
+		return false
+	}
+	if len(encryptedColumns(stmt.Schema)) > 0 {
+		return true
+	}
+	for preload := range m.db.Statement.Preloads {
+		s := stmt.Schema
+		for _, name := range strings.Split(preload, ".") {
+			relation, ok := s.Relationships.Relations[name]
+			if !ok {
+				break
+			}
+			s = relation.FieldSchema
+			if len(encryptedColumns(s)) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WhereEncrypted adds a condition matching the records whose encrypted column equals the given value.
+// It compares the blind index of the column, declared with `blind_index:<field>` in its tag, so the table is never decrypted.
+// A nil value matches the records whose column is NULL.
+//
+// Example usage:
+//
+//	var customers []model.Customer
+//	err := s.Model.Load(&customers).WhereEncrypted("ssn", ssn).Get()
+func (m *model) WhereEncrypted(column string, value interface{}) *model {
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		m.db.AddError(err)
+		return m
+	}
+	field := stmt.Schema.LookUpField(column)
+	if field == nil || !isEncrypted(field) {
+		m.db.AddError(fmt.Errorf("%s is not an encrypted column of %s", column, stmt.Schema.Name))
+		return m
+	}
+	if _, ok, _ := encodePlaintext(value); !ok {
+		m.db = m.db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: nil})
+		return m
+	}
+
+	indexes, err := blindIndexes(stmt.Schema)
+	if err != nil {
+		m.db.AddError(err)
+		return m
+	}
+	for _, b := range indexes {
+		if b.field != field {
+			continue
+		}
+		index, err := b.of(value)
+		if err != nil {
+			m.db.AddError(err)
+			return m
+		}
+		m.db = m.db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: b.index.DBName}, Value: index})
+		return m
+	}
+	m.db.AddError(fmt.Errorf("the encrypted column %s of %s has no blind index, add blind_index to its gorm tag", column, stmt.Schema.Name))
+	return m
+}
+
+// errEncryptedExpression is returned when an encrypted column is set with an SQL expression, which cannot be encrypted.
+var errEncryptedExpression = errors.New("an encrypted column cannot be set with an SQL expression")
+
+// encryption is a GORM plugin that keeps the blind indexes of encrypted columns up to date, and encrypts the values
+// of encrypted columns set through a map, which GORM writes without running the serializer.
+type encryption struct{}
+
+// Name returns the name of the plugin.
+func (encryption) Name() string {
+	return "goapify:encryption"
+}
+
+// Initialize registers the callbacks of the plugin.
+func (e encryption) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("goapify:encryption_create", e.create); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("goapify:encryption_update", e.update)
+}
+
+// useEncryption registers the encryption plugin on the given connection, once.
+func useEncryption(db *gorm.DB) {
+	if err := db.Use(encryption{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		panic(fmt.Sprintf("cannot register the encryption of attributes: %v", err))
+	}
+}
+
+// create sets the blind indexes of the created records.
+func (e encryption) create(db *gorm.DB) {
+	e.prepare(db, false)
+}
+
+// update sets the blind indexes of the updated columns.
+func (e encryption) update(db *gorm.DB) {
+	e.prepare(db, true)
+}
+
+// prepare encrypts the encrypted values given in a map and sets the blind indexes of the written values.
+// On updates from a struct, the zero values that are not selected are not written, so their blind index is left alone.
+func (encryption) prepare(db *gorm.DB, update bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return
+	}
+	if values, ok := stmt.Dest.(map[string]interface{}); ok {
+		if err := encryptMap(stmt, values); err != nil {
+			db.AddError(err)
+		}
+		return
+	}
+
+	indexes, err := blindIndexes(stmt.Schema)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if len(indexes) == 0 {
+		return
+	}
+	records := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	each := func(record reflect.Value) {
+		for record.Kind() == reflect.Ptr {
+			record = record.Elem()
+		}
+		if record.Kind() != reflect.Struct || record.Type() != stmt.Schema.ModelType || !record.CanAddr() {
+			return
+		}
+		for _, b := range indexes {
+			// ValueOf wraps the values of serialized fields, so read the field itself.
+			value := b.field.ReflectValueOf(stmt.Context, record)
+			if update && value.IsZero() && !selected(stmt, b.field) {
+				continue
+			}
+			index, err := b.of(value.Interface())
+			if err == nil {
+				err = b.index.Set(stmt.Context, record, index)
+			}
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	}
+	if records.Kind() == reflect.Slice || records.Kind() == reflect.Array {
+		for i := 0; i < records.Len(); i++ {
+			each(records.Index(i))
+		}
+		return
+	}
+	each(records)
+}
+
+// encryptMap encrypts the encrypted values of a map of column values and adds their blind indexes to it.
+// The plain values are set on the model, since GORM would otherwise copy the encrypted ones into it.
+func encryptMap(stmt *gorm.Statement, values map[string]interface{}) error {
+	indexes, err := blindIndexes(stmt.Schema)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		field := stmt.Schema.LookUpField(key)
+		if field == nil || !isEncrypted(field) {
+			continue
+		}
+		switch value.(type) {
+		case clause.Expr, *gorm.DB:
+			return fmt.Errorf("%w: %s", errEncryptedExpression, field.DBName)
+		}
+
+		sealed, err := field.Serializer.Value(stmt.Context, field, stmt.ReflectValue, value)
+		if err != nil {
+			return err
+		}
+		values[key] = clause.Expr{SQL: "?", Vars: []interface{}{sealed}}
+		if stmt.ReflectValue.Kind() == reflect.Struct && stmt.ReflectValue.CanAddr() {
+			field.Set(stmt.Context, stmt.ReflectValue, value)
+		}
+
+		for _, b := range indexes {
+			if b.field != field {
+				continue
+			}
+			index, err := b.of(value)
+			if err != nil {
+				return err
+			}
+			values[b.index.DBName] = index
+		}
+	}
+	return nil
+}
+
+// selected reports whether the statement explicitly selects the field.
+func selected(stmt *gorm.Statement, field *schema.Field) bool {
+	for _, column := range stmt.Selects {
+		if column == "*" || column == field.Name || column == field.DBName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package model
+
+import (
+	"GoAPIfy/core/math"
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// secretCustomer is the model of the encrypted attribute tests.
+type secretCustomer struct {
+	gorm.Model
+	Name        string
+	SSN         string            `gorm:"serializer:encrypted;type:text;blind_index:SSNIndex"`
+	SSNIndex    string            `gorm:"size:64;index"`
+	Token       *string           `gorm:"serializer:encrypted;type:text"`
+	Credentials map[string]string `gorm:"serializer:encrypted;type:text"`
+	BornAt      time.Time         `gorm:"serializer:encrypted;type:text"`
+	Scan        []byte            `gorm:"serializer:encrypted;type:text"`
+}
+
+// secretRow is the stored row of a secretCustomer, as read without decrypting it.
+type secretRow struct {
+	ID          uint
+	SSN         *string
+	SSNIndex    string
+	Token       *string
+	Credentials *string
+	BornAt      *string
+	Scan        *string
+}
+
+// seedCustomers creates the customers ann, with every attribute set, and bob, with the optional ones nil.
+func seedCustomers(t *testing.T) *Repository[secretCustomer] {
+	t.Helper()
+	t.Setenv("APP_KEY", "0123456789abcdef0123456789abcdef")
+	customers := NewRepository[secretCustomer](NewModel(testdb.Open(t, &secretCustomer{})))
+	token := "tok_ann"
+	for _, c := range []secretCustomer{
+		{Name: "ann", SSN: "111-11-1111", Token: &token, Credentials: map[string]string{"user": "ann"},
+			BornAt: time.Date(1990, 5, 17, 8, 30, 0, 0, time.UTC), Scan: []byte{0, 1, 2}},
+		{Name: "bob", SSN: "222-22-2222"},
+	} {
+		if err := customers.Create(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return customers
+}
+
+// storedRow reads the stored row of the customer with the given ID.
+func storedRow(t *testing.T, customers *Repository[secretCustomer], id uint) secretRow {
+	t.Helper()
+	var row secretRow
+	if err := customers.query().Session(&gorm.Session{NewDB: true}).Table("secret_customers").Where("id = ?", id).Take(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	return row
+}
+
+// unseal decrypts a stored value, "NULL" for a NULL column.
+func unseal(t *testing.T, sealed *string) string {
+	t.Helper()
+	if sealed == nil {
+		return "NULL"
+	}
+	plaintext, err := math.Unseal(*sealed)
+	if err != nil {
+		t.Fatalf("stored value %q is not sealed: %v", *sealed, err)
+	}
+	return string(plaintext)
+}
+
+func TestEncryptedAttributes(t *testing.T) {
+	customers := seedCustomers(t)
+	tests := []struct {
+		id     uint
+		stored func(row secretRow) *string
+		want   string
+	}{
+		{1, func(row secretRow) *string { return row.SSN }, "111-11-1111"},
+		{1, func(row secretRow) *string { return row.Token }, "tok_ann"},
+		{1, func(row secretRow) *string { return row.Credentials }, `{"user":"ann"}`},
+		{1, func(row secretRow) *string { return row.BornAt }, `"1990-05-17T08:30:00Z"`},
+		{1, func(row secretRow) *string { return row.Scan }, "\x00\x01\x02"},
+		{2, func(row secretRow) *string { return row.Token }, "NULL"},
+		{2, func(row secretRow) *string { return row.Credentials }, "NULL"},
+		{2, func(row secretRow) *string { return row.Scan }, "NULL"},
+		// A zero time is not nil, it is stored like any other time.
+		{2, func(row secretRow) *string { return row.BornAt }, `"0001-01-01T00:00:00Z"`},
+	}
+	for i, tt := range tests {
+		row := storedRow(t, customers, tt.id)
+		stored := tt.stored(row)
+		if stored != nil && strings.Contains(*stored, strings.Trim(tt.want, `"`)) {
+			t.Errorf("case %d: the stored value %q holds the plain value", i, *stored)
+		}
+		if got := unseal(t, stored); got != tt.want {
+			t.Errorf("case %d: stored %q, want %q", i, got, tt.want)
+		}
+	}
+
+	ann, err := customers.Find(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ann.SSN != "111-11-1111" || ann.Token == nil || *ann.Token != "tok_ann" || ann.Credentials["user"] != "ann" ||
+		!ann.BornAt.Equal(time.Date(1990, 5, 17, 8, 30, 0, 0, time.UTC)) || string(ann.Scan) != "\x00\x01\x02" {
+		t.Errorf("decrypted ann = %+v", ann)
+	}
+	bob, err := customers.Find(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.Token != nil || bob.Credentials != nil || bob.Scan != nil || !bob.BornAt.IsZero() {
+		t.Errorf("decrypted bob = %+v, want the optional attributes nil", bob)
+	}
+
+	// Values sealed under another APP_KEY cannot be read.
+	t.Setenv("APP_KEY", "another key")
+	if _, err := customers.Find(1); err == nil || !strings.Contains(err.Error(), "cannot decrypt the ssn column") {
+		t.Errorf("Find with another APP_KEY = %v, want a decryption error", err)
+	}
+}
+
+func TestEncryptedWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func(customers *Repository[secretCustomer], ann *secretCustomer) error
+		wantErr error
+		wantSSN string
+	}{
+		{
+			name: "update",
+			write: func(customers *Repository[secretCustomer], ann *secretCustomer) error {
+				ann.SSN = "333-33-3333"
+				return customers.Update(ann)
+			},
+			wantSSN: "333-33-3333",
+		},
+		{
+			name: "update columns",
+			write: func(customers *Repository[secretCustomer], ann *secretCustomer) error {
+				return customers.entity(ann).UpdateColumns(map[string]interface{}{"ssn": "333-33-3333"})
+			},
+			wantSSN: "333-33-3333",
+		},
+		{
+			name: "bulk update",
+			write: func(customers *Repository[secretCustomer], ann *secretCustomer) error {
+				_, err := customers.Where("name = ?", "ann").UpdateWhere(map[string]interface{}{"ssn": "333-33-3333"})
+				return err
+			},
+			wantSSN: "333-33-3333",
+		},
+		{
+			name: "update of other columns keeps the blind index",
+			write: func(customers *Repository[secretCustomer], ann *secretCustomer) error {
+				_, err := customers.Where("name = ?", "ann").UpdateWhere(map[string]interface{}{"name": "anne"})
+				return err
+			},
+			wantSSN: "111-11-1111",
+		},
+		{
+			name: "SQL expression",
+			write: func(customers *Repository[secretCustomer], ann *secretCustomer) error {
+				_, err := customers.Where("name = ?", "ann").UpdateWhere(map[string]interface{}{"ssn": gorm.Expr("upper(ssn)")})
+				return err
+			},
+			wantErr: errEncryptedExpression,
+			wantSSN: "111-11-1111",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customers := seedCustomers(t)
+			ann, err := customers.Find(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.write(customers, &ann); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if ann.SSN != tt.wantSSN && ann.SSN != "111-11-1111" {
+				t.Errorf("SSN of the entity = %q, want a plain value", ann.SSN)
+			}
+			if got := unseal(t, storedRow(t, customers, 1).SSN); got != tt.wantSSN {
+				t.Errorf("stored SSN = %q, want %q", got, tt.wantSSN)
+			}
+			found, err := customers.entity(&secretCustomer{}).WhereEncrypted("ssn", tt.wantSSN).Count()
+			if err != nil || found != 1 {
+				t.Errorf("%d customers found by the blind index of the SSN (%v), want 1", found, err)
+			}
+		})
+	}
+}
+
+func TestWhereEncrypted(t *testing.T) {
+	customers := seedCustomers(t)
+	tests := []struct {
+		name      string
+		column    string
+		value     interface{}
+		wantNames string
+		wantErr   string
+	}{
+		{"match", "ssn", "222-22-2222", "bob", ""},
+		{"field name", "SSN", "111-11-1111", "ann", ""},
+		{"no match", "ssn", "999-99-9999", "", ""},
+		{"pointer value", "ssn", func() *string { s := "111-11-1111"; return &s }(), "ann", ""},
+		{"nil value matches NULL", "token", nil, "bob", ""},
+		{"nil pointer matches NULL", "credentials", (map[string]string)(nil), "bob", ""},
+		{"column without a blind index", "token", "tok_ann", "", "has no blind index"},
+		{"column that is not encrypted", "name", "ann", "", "name is not an encrypted column"},
+		{"unknown column", "email", "ann@example.com", "", "email is not an encrypted column"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found []secretCustomer
+			err := customers.m.Load(&found).WhereEncrypted(tt.column, tt.value).Order("id").Get()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, c := range found {
+				names = append(names, c.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.wantNames {
+				t.Errorf("found %q, want %q", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+// TestEncryptedBlindIndexOfSlices makes sure the blind indexes of every created record are set.
+func TestEncryptedBlindIndexOfSlices(t *testing.T) {
+	customers := seedCustomers(t)
+	created := []secretCustomer{{Name: "cid", SSN: "444-44-4444"}, {Name: "dee", SSN: "555-55-5555"}}
+	if _, err := customers.CreateInBatches(created, 10); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range created {
+		found, err := customers.m.Load(&secretCustomer{}).WhereEncrypted("ssn", c.SSN).Count()
+		if err != nil || found != 1 {
+			t.Errorf("%d customers found with the SSN of %s (%v), want 1", found, c.Name, err)
+		}
+	}
+}
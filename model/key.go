@@ -0,0 +1,64 @@
+package model
+
+import (
+	"GoAPIfy/core/math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UUIDModel is a base model like gorm.Model, with a random UUID primary key instead of an auto-increment one,
+// so the IDs in public URLs do not reveal the number of records. The ID is generated on create when it is empty,
+// and can also be generated before, for instance offline, with uuid.NewString.
+// A model that defines its own BeforeCreate hook must call the one of UUIDModel.
+//
+// Example usage:
+//
+//	type Invoice struct {
+//		model.UUIDModel
+//		Total int
+//	}
+//
+//	invoice, err := model.NewRepository[model.Invoice](s.Model).Find(c.Param("id"))
+type UUIDModel struct {
+	ID        string `gorm:"primaryKey;size:36"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// BeforeCreate generates the ID of the record when it is empty.
+func (m *UUIDModel) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// ULIDModel is a base model like gorm.Model, with a ULID primary key instead of an auto-increment one.
+// ULIDs are unique like UUIDs but sort by creation time, which keeps the inserts at the end of the primary key
+// index and makes ChunkByID and cursor pagination walk the records in the order they were created.
+// The ID is generated on create when it is empty, and can also be generated before with math.NewULID.
+// A model that defines its own BeforeCreate hook must call the one of ULIDModel.
+//
+// Example usage:
+//
+//	type Event struct {
+//		model.ULIDModel
+//		Name string
+//	}
+type ULIDModel struct {
+	ID        string `gorm:"primaryKey;size:26"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// BeforeCreate generates the ID of the record when it is empty.
+func (m *ULIDModel) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = math.NewULID()
+	}
+	return nil
+}
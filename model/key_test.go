@@ -0,0 +1,166 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// keyInvoice has a UUID primary key, keyEvent a ULID one.
+type keyInvoice struct {
+	UUIDModel
+	Total int
+}
+
+type keyEvent struct {
+	ULIDModel
+	Name string
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidPattern = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+)
+
+func TestGeneratedKeys(t *testing.T) {
+	db := testdb.Open(t, &keyInvoice{}, &keyEvent{})
+	invoices := NewRepository[keyInvoice](NewModel(db))
+	events := NewRepository[keyEvent](NewModel(db))
+
+	tests := []struct {
+		name string
+		// create creates a record and returns its ID.
+		create  func() (string, error)
+		pattern *regexp.Regexp
+		wantID  string
+	}{
+		{"uuid", func() (string, error) {
+			invoice := keyInvoice{Total: 10}
+			err := invoices.Create(&invoice)
+			return invoice.ID, err
+		}, uuidPattern, ""},
+		{"ulid", func() (string, error) {
+			event := keyEvent{Name: "signup"}
+			err := events.Create(&event)
+			return event.ID, err
+		}, ulidPattern, ""},
+		{"uuid set before create", func() (string, error) {
+			invoice := keyInvoice{UUIDModel: UUIDModel{ID: "b3a1c8b2-4f3e-4a51-9b3c-1d2e3f4a5b6c"}, Total: 10}
+			err := invoices.Create(&invoice)
+			return invoice.ID, err
+		}, uuidPattern, "b3a1c8b2-4f3e-4a51-9b3c-1d2e3f4a5b6c"},
+		{"ulid set before create", func() (string, error) {
+			event := keyEvent{ULIDModel: ULIDModel{ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV"}, Name: "import"}
+			err := events.Create(&event)
+			return event.ID, err
+		}, ulidPattern, "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.create()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.pattern.MatchString(id) || tt.wantID != "" && id != tt.wantID {
+				t.Errorf("created ID = %q, want %q matching %s", id, tt.wantID, tt.pattern)
+			}
+		})
+	}
+
+	// Every created record has a generated or given ID that Find accepts.
+	allInvoices, err := invoices.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allEvents, err := events.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allInvoices) != 2 || len(allEvents) != 2 {
+		t.Fatalf("%d invoices and %d events stored, want 2 of each", len(allInvoices), len(allEvents))
+	}
+	for _, invoice := range allInvoices {
+		if !uuidPattern.MatchString(invoice.ID) {
+			t.Errorf("invoice ID %q is not a UUID", invoice.ID)
+		}
+		if found, err := invoices.Find(invoice.ID); err != nil || found.ID != invoice.ID {
+			t.Errorf("Find(%q) = %q, %v", invoice.ID, found.ID, err)
+		}
+	}
+	for _, event := range allEvents {
+		if !ulidPattern.MatchString(event.ID) {
+			t.Errorf("event ID %q is not a ULID", event.ID)
+		}
+		if found, err := events.Find(event.ID); err != nil || found.ID != event.ID {
+			t.Errorf("Find(%q) = %q, %v", event.ID, found.ID, err)
+		}
+	}
+}
+
+func TestFindByKey(t *testing.T) {
+	db := testdb.Open(t, &keyInvoice{}, &chunkItem{})
+	invoices := NewRepository[keyInvoice](NewModel(db))
+	items := NewRepository[chunkItem](NewModel(db))
+	invoice := keyInvoice{Total: 10}
+	if err := invoices.Create(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	if err := items.Create(&chunkItem{N: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		find    func() error
+		wantErr error
+	}{
+		{"string key", func() error { _, err := invoices.Find(invoice.ID); return err }, nil},
+		{"unknown string key", func() error { _, err := invoices.Find("4f7c2a10-0000-4000-8000-000000000000"); return err }, gorm.ErrRecordNotFound},
+		// A string ID is compared with the primary key, not added to the query as SQL.
+		{"string key that looks like SQL", func() error { _, err := invoices.Find("1 = 1 OR id"); return err }, gorm.ErrRecordNotFound},
+		{"number key", func() error { _, err := items.Find(uint(1)); return err }, nil},
+		{"untyped number key", func() error { _, err := items.Find(1); return err }, nil},
+		{"unknown number key", func() error { _, err := items.Find(uint(2)); return err }, gorm.ErrRecordNotFound},
+		{"model", func() error { return NewModel(db).Load(&keyInvoice{}).Find(invoice.ID) }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.find(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestULIDOrder makes sure records with ULID keys are walked in the order they were created.
+func TestULIDOrder(t *testing.T) {
+	events := NewRepository[keyEvent](NewModel(testdb.Open(t, &keyEvent{})))
+	var names []string
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := events.Create(&keyEvent{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	var walked []string
+	err := events.ChunkByID(context.Background(), 2, func(chunk []keyEvent) error {
+		for _, event := range chunk {
+			walked = append(walked, event.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(walked, ","), strings.Join(names, ","); got != want {
+		t.Errorf("ChunkByID walked %q, want %q", got, want)
+	}
+}
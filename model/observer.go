@@ -0,0 +1,355 @@
+package model
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// EventName identifies a point in the lifecycle of a model.
+type EventName string
+
+// Lifecycle events fired by the model wrapper. Listeners of the "-ing" events run before the write
+// and can veto it by returning an error; listeners of the "-ed" events run after the write.
+const (
+	Creating  EventName = "creating"
+	Created   EventName = "created"
+	Updating  EventName = "updating"
+	Updated   EventName = "updated"
+	Deleting  EventName = "deleting"
+	Deleted   EventName = "deleted"
+	Restoring EventName = "restoring"
+	Restored  EventName = "restored"
+)
+
+// Event is passed to the listeners of a model of type T.
+// Old holds the record as stored before the write and is nil when creating.
+// New holds the record as written and is nil when deleting.
+// Model is bound to the transaction the write runs in, so listeners can write related records atomically with it.
+type Event[T any] struct {
+	Name    EventName
+	Old     *T
+	New     *T
+	Model   Model
+	Context context.Context
+}
+
+// event is the untyped form of Event used inside the registry.
+type event struct {
+	name     EventName
+	old, new interface{}
+	model    Model
+	ctx      context.Context
+}
+
+type listener func(e *event) error
+
+// observers holds the registered listeners by model type and event name.
+var observers = struct {
+	sync.RWMutex
+	listeners map[reflect.Type]map[EventName][]listener
+}{listeners: map[reflect.Type]map[EventName][]listener{}}
+
+// Observe registers a listener for the given lifecycle event of the model type T.
+// Listeners run in the order they were registered. When a "before" listener (Creating, Updating, Deleting, Restoring)
+// returns an error, the write is cancelled and the error is returned to the caller. When an "after" listener returns
+// an error, the write is rolled back, since writes of observed models run inside a transaction.
+// Listeners fire for writes made through the model wrapper and model.Repository: Create, Save, Delete,
+// ForceDelete, Restore, UpdateColumn and UpdateColumns.
+//
+// Example usage:
+//
+//	model.Observe(model.Updating, func(e *model.Event[model.User]) error {
+//		if e.Old.Email != e.New.Email {
+//			e.New.VerifiedAt = nil
+//		}
+//		return nil
+//	})
+func Observe[T any](name EventName, fn func(e *Event[T]) error) {
+	modelType := reflect.TypeOf((*T)(nil)).Elem()
+
+	observers.Lock()
+	defer observers.Unlock()
+	if observers.listeners[modelType] == nil {
+		observers.listeners[modelType] = map[EventName][]listener{}
+	}
+	observers.listeners[modelType][name] = append(observers.listeners[modelType][name], func(e *event) error {
+		typed := &Event[T]{Name: e.name, Model: e.model, Context: e.ctx}
+		if e.old != nil {
+			typed.Old = e.old.(*T)
+		}
+		if e.new != nil {
+			typed.New = e.new.(*T)
+		}
+		return fn(typed)
+	})
+}
+
+// observed reports whether any listener is registered for one of the events of the given model type,
+// or whether the writes of the model type are audited, which needs the changes collected the same way.
+func observed(modelType reflect.Type, names ...EventName) bool {
+	if modelType == nil {
+		return false
+	}
+	if isAuditable(modelType) {
+		return true
+	}
+	observers.RLock()
+	defer observers.RUnlock()
+	for _, name := range names {
+		if len(observers.listeners[modelType][name]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// tracked reports whether the writes of the loaded model must collect the affected records for one of the given events:
+// for its listeners, its audit trail or its search index.
+func (m *model) tracked(names ...EventName) bool {
+	modelType := entityType(m.tempData)
+	return observed(modelType, names...) || (m.search != nil && isSearchable(modelType))
+}
+
+// dispatch runs the listeners of an event and stops at the first error.
+func dispatch(modelType reflect.Type, e *event) error {
+	observers.RLock()
+	listeners := observers.listeners[modelType][e.name]
+	observers.RUnlock()
+
+	for _, fn := range listeners {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// change is a single record affected by an observed write.
+type change struct {
+	before, after EventName
+	old, new      interface{}
+}
+
+// entityType returns the struct type of the loaded model, also when a slice of models is loaded.
+func entityType(value interface{}) reflect.Type {
+	if value == nil {
+		return nil
+	}
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// observe runs a write together with the listeners of the affected records inside a single transaction,
+// records the changes of Auditable models and queues the index updates of Searchable models once the write succeeded.
+// collect returns the affected records, it runs inside the transaction before any listener.
+func (m *model) observe(collect func(tx *model) ([]change, error), write func(tx *model) error) error {
+	modelType := entityType(m.tempData)
+
+	return m.transaction(m.db.Session(&gorm.Session{}), func(tx *model) error {
+		changes, err := collect(tx)
+		if err != nil {
+			return err
+		}
+
+		listenerModel := tx.derive(tx.db.Session(&gorm.Session{NewDB: true}))
+		listenerModel.tempData = nil
+		ctx := tx.db.Statement.Context
+
+		for _, c := range changes {
+			if err := dispatch(modelType, &event{name: c.before, old: c.old, new: c.new, model: listenerModel, ctx: ctx}); err != nil {
+				return err
+			}
+		}
+		if err := write(tx); err != nil {
+			return err
+		}
+		if isAuditable(modelType) {
+			if err := tx.audit(changes); err != nil {
+				return err
+			}
+		}
+		if tx.search != nil && isSearchable(modelType) {
+			if err := tx.index(changes); err != nil {
+				return err
+			}
+		}
+		for _, c := range changes {
+			if err := dispatch(modelType, &event{name: c.after, old: c.old, new: c.new, model: listenerModel, ctx: ctx}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadedEntities returns pointers to the loaded entities: the entity itself, or every element of a loaded slice.
+func (m *model) loadedEntities() []reflect.Value {
+	value := reflect.ValueOf(m.tempData)
+	for value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	switch value.Elem().Kind() {
+	case reflect.Struct:
+		return []reflect.Value{value}
+	case reflect.Slice, reflect.Array:
+		var entities []reflect.Value
+		slice := value.Elem()
+		for i := 0; i < slice.Len(); i++ {
+			element := slice.Index(i)
+			if element.Kind() == reflect.Ptr {
+				entities = append(entities, element)
+			} else {
+				entities = append(entities, element.Addr())
+			}
+		}
+		return entities
+	}
+	return nil
+}
+
+// primaryKeyConditions returns the primary key conditions of an entity, or false if its primary key is not set.
+func (m *model) primaryKeyConditions(s *schema.Schema, entity reflect.Value) ([]clause.Expression, bool) {
+	if len(s.PrimaryFields) == 0 {
+		return nil, false
+	}
+	var conditions []clause.Expression
+	for _, field := range s.PrimaryFields {
+		value, zero := field.ValueOf(m.db.Statement.Context, entity.Elem())
+		if zero {
+			return nil, false
+		}
+		conditions = append(conditions, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value})
+	}
+	return conditions, true
+}
+
+// currentRecords loads the stored state of the records the pending write applies to.
+// Loaded entities with a primary key are looked up by that key, otherwise the current query conditions are used.
+func (m *model) currentRecords(unscoped bool) ([]interface{}, error) {
+	modelType := entityType(m.tempData)
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return nil, err
+	}
+
+	conn := m.db.Session(&gorm.Session{NewDB: true})
+	if unscoped {
+		conn = conn.Unscoped()
+	}
+
+	var keyed [][]clause.Expression
+	for _, entity := range m.loadedEntities() {
+		conditions, ok := m.primaryKeyConditions(stmt.Schema, entity)
+		if !ok {
+			keyed = nil
+			break
+		}
+		keyed = append(keyed, conditions)
+	}
+
+	var records []interface{}
+	if len(keyed) > 0 {
+		for _, conditions := range keyed {
+			record := reflect.New(modelType)
+			err := conn.Where(clause.And(conditions...)).Take(record.Interface()).Error
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record.Interface())
+		}
+		return records, nil
+	}
+
+	query := m.db.Session(&gorm.Session{})
+	if unscoped {
+		query = query.Unscoped()
+	}
+	found := reflect.New(reflect.SliceOf(modelType))
+	if err := query.Find(found.Interface()).Error; err != nil {
+		return nil, err
+	}
+	for i := 0; i < found.Elem().Len(); i++ {
+		records = append(records, found.Elem().Index(i).Addr().Interface())
+	}
+	return records, nil
+}
+
+// applyValues returns a copy of the record with the given column values applied.
+func (m *model) applyValues(record interface{}, values map[string]interface{}) (interface{}, error) {
+	copied := reflect.New(reflect.TypeOf(record).Elem())
+	copied.Elem().Set(reflect.ValueOf(record).Elem())
+
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(record); err != nil {
+		return nil, err
+	}
+	for column, value := range values {
+		if field := stmt.Schema.LookUpField(column); field != nil {
+			if err := field.Set(m.db.Statement.Context, copied.Elem(), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return copied.Interface(), nil
+}
+
+// saveChanges collects the changes of a Save: entities without a stored record are created, the others are updated.
+func (m *model) saveChanges() ([]change, error) {
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return nil, err
+	}
+	conn := m.db.Session(&gorm.Session{NewDB: true})
+
+	var changes []change
+	for _, entity := range m.loadedEntities() {
+		conditions, ok := m.primaryKeyConditions(stmt.Schema, entity)
+		if !ok {
+			changes = append(changes, change{before: Creating, after: Created, new: entity.Interface()})
+			continue
+		}
+
+		old := reflect.New(entity.Type().Elem())
+		err := conn.Where(clause.And(conditions...)).Take(old.Interface()).Error
+		if err == gorm.ErrRecordNotFound {
+			changes = append(changes, change{before: Creating, after: Created, new: entity.Interface()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change{before: Updating, after: Updated, old: old.Interface(), new: entity.Interface()})
+	}
+	return changes, nil
+}
+
+// deleteChanges collects the records a Delete or ForceDelete applies to.
+func (m *model) deleteChanges(unscoped bool) ([]change, error) {
+	records, err := m.currentRecords(unscoped)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]change, len(records))
+	for i, record := range records {
+		changes[i] = change{before: Deleting, after: Deleted, old: record}
+	}
+	return changes, nil
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// obsPost is the observed model of the observer tests.
+type obsPost struct {
+	gorm.Model
+	Title string
+}
+
+// obsComment is written by the Creating listener of posts titled "with comment".
+type obsComment struct {
+	ID   uint
+	Body string
+}
+
+// obsLog is the log of the obsPost listeners, each entry names the event with the titles it saw;
+// obsFail makes the listener of an event fail.
+var (
+	obsLog      []string
+	obsFail     EventName
+	observeOnce sync.Once
+)
+
+var errObsVeto = errors.New("vetoed by the listener")
+
+// observePosts registers a listener for every event of obsPost, once for the whole test binary.
+func observePosts() {
+	observeOnce.Do(func() {
+		for _, name := range []EventName{Creating, Created, Updating, Updated, Deleting, Deleted, Restoring, Restored} {
+			Observe(name, func(e *Event[obsPost]) error {
+				entry := string(e.Name)
+				if e.Old != nil {
+					entry += " old=" + e.Old.Title
+				}
+				if e.New != nil {
+					entry += " new=" + e.New.Title
+				}
+				obsLog = append(obsLog, entry)
+				if e.Name == Creating && e.New.Title == "with comment" {
+					if err := NewRepository[obsComment](e.Model).Create(&obsComment{Body: "hi"}); err != nil {
+						return err
+					}
+				}
+				if e.Name == obsFail {
+					return errObsVeto
+				}
+				return nil
+			})
+		}
+	})
+}
+
+func TestObserve(t *testing.T) {
+	observePosts()
+	tests := []struct {
+		name       string
+		write      func(posts *Repository[obsPost], post *obsPost) error
+		fail       EventName
+		wantLog    string
+		wantErr    error
+		wantTitles string
+	}{
+		{
+			name:       "create",
+			write:      func(posts *Repository[obsPost], _ *obsPost) error { return posts.Create(&obsPost{Title: "new"}) },
+			wantLog:    "creating new=new|created new=new",
+			wantTitles: "first,new",
+		},
+		{
+			name: "update",
+			write: func(posts *Repository[obsPost], post *obsPost) error {
+				post.Title = "renamed"
+				return posts.Update(post)
+			},
+			wantLog:    "updating old=first new=renamed|updated old=first new=renamed",
+			wantTitles: "renamed",
+		},
+		{
+			name: "update columns",
+			write: func(posts *Repository[obsPost], post *obsPost) error {
+				return posts.entity(post).UpdateColumns(map[string]interface{}{"title": "renamed"})
+			},
+			wantLog:    "updating old=first new=renamed|updated old=first new=renamed",
+			wantTitles: "renamed",
+		},
+		{
+			name:       "delete",
+			write:      func(posts *Repository[obsPost], post *obsPost) error { return posts.Delete(post) },
+			wantLog:    "deleting old=first|deleted old=first",
+			wantTitles: "",
+		},
+		{
+			name: "restore",
+			write: func(posts *Repository[obsPost], post *obsPost) error {
+				if err := posts.Delete(post); err != nil {
+					return err
+				}
+				obsLog = nil
+				return posts.Restore(post)
+			},
+			wantLog:    "restoring old=first new=first|restored old=first new=first",
+			wantTitles: "first",
+		},
+		{
+			name:       "force delete",
+			write:      func(posts *Repository[obsPost], post *obsPost) error { return posts.ForceDelete(post) },
+			wantLog:    "deleting old=first|deleted old=first",
+			wantTitles: "",
+		},
+		{
+			name:       "a before listener vetoes the write",
+			write:      func(posts *Repository[obsPost], _ *obsPost) error { return posts.Create(&obsPost{Title: "new"}) },
+			fail:       Creating,
+			wantLog:    "creating new=new",
+			wantErr:    errObsVeto,
+			wantTitles: "first",
+		},
+		{
+			name: "an after listener rolls the write back",
+			write: func(posts *Repository[obsPost], post *obsPost) error {
+				post.Title = "renamed"
+				return posts.Update(post)
+			},
+			fail:       Updated,
+			wantLog:    "updating old=first new=renamed|updated old=first new=renamed",
+			wantErr:    errObsVeto,
+			wantTitles: "first",
+		},
+		{
+			name:       "a vetoed delete keeps the record",
+			write:      func(posts *Repository[obsPost], post *obsPost) error { return posts.Delete(post) },
+			fail:       Deleting,
+			wantLog:    "deleting old=first",
+			wantErr:    errObsVeto,
+			wantTitles: "first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := NewRepository[obsPost](NewModel(testdb.Open(t, &obsPost{})))
+			obsFail = ""
+			post := obsPost{Title: "first"}
+			if err := posts.Create(&post); err != nil {
+				t.Fatal(err)
+			}
+
+			obsLog, obsFail = nil, tt.fail
+			defer func() { obsFail = "" }()
+			if err := tt.write(posts, &post); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("write error = %v, want %v", err, tt.wantErr)
+			}
+			if got := strings.Join(obsLog, "|"); got != tt.wantLog {
+				t.Errorf("events = %q, want %q", got, tt.wantLog)
+			}
+
+			stored, err := posts.Order("id").All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, p := range stored {
+				titles = append(titles, p.Title)
+			}
+			if got := strings.Join(titles, ","); got != tt.wantTitles {
+				t.Errorf("stored titles = %q, want %q", got, tt.wantTitles)
+			}
+		})
+	}
+}
+
+// TestObserveListenerModel makes sure the listeners write through the transaction of the write,
+// so their writes are rolled back with it.
+func TestObserveListenerModel(t *testing.T) {
+	observePosts()
+	for _, tt := range []struct {
+		fail         EventName
+		wantErr      error
+		wantComments int64
+	}{
+		{"", nil, 1},
+		{Created, errObsVeto, 0},
+	} {
+		t.Run(fmt.Sprintf("fail %q", tt.fail), func(t *testing.T) {
+			m := NewModel(testdb.Open(t, &obsPost{}, &obsComment{}))
+			obsFail = tt.fail
+			defer func() { obsFail = "" }()
+			if err := NewRepository[obsPost](m).Create(&obsPost{Title: "with comment"}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create error = %v, want %v", err, tt.wantErr)
+			}
+			if count, _ := NewRepository[obsComment](m).Count(); count != tt.wantComments {
+				t.Errorf("%d comments written by the listener, want %d", count, tt.wantComments)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"GoAPIfy/core/database"
+	"strings"
+)
+
+// registered holds every model registered with Register, in the order they were registered.
+var registered []interface{}
+
+// Register adds models to the list of application models, the tables `apify db:export` backs up.
+// It is called from the init function of each model file, `apify entity` adds the call to the models it creates.
+//
+// Example usage:
+//
+//	func init() {
+//		Register(&Invoice{}, &InvoiceLine{})
+//	}
+func Register(models ...interface{}) {
+	registered = append(registered, models...)
+}
+
+// Registered returns the registered models stored on the named database connection, in the order they were registered.
+// A model is stored on the default connection, database.DefaultConnection, unless it is ConnectionBound.
+func Registered(connection string) []interface{} {
+	var models []interface{}
+	for _, m := range registered {
+		name := boundConnection(m)
+		if name == "" {
+			name = database.DefaultConnection
+		}
+		if strings.EqualFold(name, connection) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
@@ -0,0 +1,646 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Pivot holds the values of the extra columns of a pivot row, such as a role.
+// Pivot columns other than the two keys need a join model registered with GORM before the first query:
+//
+//	type UserRole struct {
+//		UserID    uint `gorm:"primaryKey"`
+//		RoleID    uint `gorm:"primaryKey"`
+//		Role      string
+//		CreatedAt time.Time
+//		UpdatedAt time.Time
+//	}
+//
+//	err := db.SetupJoinTable(&model.User{}, "Roles", &model.UserRole{})
+//
+// created_at and updated_at are filled in automatically when the join model has them.
+type Pivot map[string]interface{}
+
+// SyncResult reports the related keys a Sync, SyncWithoutDetaching or Toggle attached and detached,
+// and the attached keys whose pivot columns were updated.
+type SyncResult struct {
+	Attached []interface{}
+	Detached []interface{}
+	Updated  []interface{}
+}
+
+// pivotMode is the way changePivot reconciles the attached keys with the given ones.
+type pivotMode int
+
+const (
+	pivotAttach pivotMode = iota
+	pivotSync
+	pivotSyncWithoutDetaching
+	pivotToggle
+)
+
+// Attach adds rows to the pivot table of a many-to-many relation of the loaded model, linking it to the given records.
+// It takes in the name of the relation field, the related keys, and optional pivot values stored on every new row.
+// The keys can be a single key, a slice of keys or of related records, or a map from key to Pivot to store
+// different pivot values per row. Keys that are already attached are left untouched, and keys of records that
+// do not exist, or belong to another tenant, are refused with gorm.ErrRecordNotFound.
+//
+// Example usage:
+//
+//	err := s.Model.Load(&user).Attach("Roles", []uint{1, 2}, model.Pivot{"role": "editor"})
+func (m *model) Attach(relation string, ids interface{}, pivot ...Pivot) error {
+	var shared Pivot
+	if len(pivot) > 0 {
+		shared = pivot[0]
+	}
+	_, err := m.changePivot(relation, ids, pivotAttach, shared)
+	return err
+}
+
+// Detach removes the pivot rows linking the loaded model to the given related keys, or to every related record
+// when ids is nil, and returns the number of removed rows. The related records themselves are not deleted.
+//
+// Example usage:
+//
+//	detached, err := s.Model.Load(&user).Detach("Roles", []uint{2})
+func (m *model) Detach(relation string, ids interface{}) (int64, error) {
+	p, err := m.pivotOf(relation)
+	if err != nil {
+		return 0, err
+	}
+	keys, _, err := p.parse(m.db.Statement.Context, ids)
+	if err != nil {
+		return 0, err
+	}
+	if ids != nil && len(keys) == 0 {
+		return 0, nil
+	}
+
+	query := m.db.Session(&gorm.Session{NewDB: true}).Table(p.table).Where(p.ownerCondition())
+	if ids != nil {
+		query = query.Where(clause.IN{Column: clause.Column{Table: p.table, Name: p.column}, Values: keys})
+	}
+	result := query.Delete(map[string]interface{}{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	m.invalidate("table:" + p.table)
+	return result.RowsAffected, nil
+}
+
+// Sync makes the given keys the only records linked to the loaded model through a many-to-many relation:
+// missing keys are attached, the others are detached. When the keys are given as a map from key to Pivot,
+// the pivot columns of keys that are already attached are updated too. Everything runs in one transaction.
+//
+// Example usage:
+//
+//	result, err := s.Model.Load(&user).Sync("Roles", map[uint]model.Pivot{1: {"role": "owner"}, 3: nil})
+func (m *model) Sync(relation string, ids interface{}) (*SyncResult, error) {
+	return m.changePivot(relation, ids, pivotSync, nil)
+}
+
+// SyncWithoutDetaching attaches the given keys that are missing and updates the pivot columns of the ones
+// that are already attached, without detaching any other record. See Sync.
+func (m *model) SyncWithoutDetaching(relation string, ids interface{}) (*SyncResult, error) {
+	return m.changePivot(relation, ids, pivotSyncWithoutDetaching, nil)
+}
+
+// Toggle detaches the given keys that are attached to the loaded model and attaches the others. See Sync.
+//
+// Example usage:
+//
+//	result, err := s.Model.Load(&user).Toggle("FavoritePosts", post.ID)
+func (m *model) Toggle(relation string, ids interface{}) (*SyncResult, error) {
+	return m.changePivot(relation, ids, pivotToggle, nil)
+}
+
+// Related returns a query for the records linked to the loaded model through the given relation,
+// loaded into dest. Any relation type is supported; on a many-to-many relation the query joins the pivot table,
+// so WherePivot can filter on its columns. The loaded model must have its primary key set.
+//
+// Example usage:
+//
+//	var roles []model.Role
+//	err := s.Model.Load(&user).Related("Roles", &roles).WherePivot("role", "editor").Get()
+func (m *model) Related(relation string, dest interface{}) *model {
+	query := m.derive(m.db.Session(&gorm.Session{NewDB: true})).Load(dest)
+	query.pivot = ""
+	rel, owner, err := m.relationOf(relation)
+	if err != nil {
+		query.db.AddError(err)
+		return query
+	}
+	ctx := m.db.Statement.Context
+
+	switch rel.Type {
+	case schema.Many2Many:
+		p, err := m.pivotOf(relation)
+		if err != nil {
+			query.db.AddError(err)
+			return query
+		}
+		on := clause.Eq{
+			Column: clause.Column{Table: p.table, Name: p.column},
+			Value:  clause.Column{Table: rel.FieldSchema.Table, Name: p.relatedKey.DBName},
+		}
+		query.db = query.db.Joins("JOIN ? ON ?", clause.Table{Name: p.table}, on).Where(p.ownerCondition())
+		query.pivot = p.table
+	case schema.BelongsTo:
+		for _, ref := range rel.References {
+			value, _ := ref.ForeignKey.ValueOf(ctx, owner)
+			query.db = query.db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: ref.PrimaryKey.DBName}, Value: value})
+		}
+	default:
+		for column, value := range foreignValues(ctx, rel, owner) {
+			query.db = query.db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: value})
+		}
+	}
+	return query
+}
+
+// WherePivot filters a many-to-many query built with Related on a column of the pivot table.
+// A slice value matches any of its elements.
+//
+// Example usage:
+//
+//	err := s.Model.Load(&user).Related("Roles", &roles).WherePivot("role", []string{"owner", "editor"}).Get()
+func (m *model) WherePivot(column string, value interface{}) *model {
+	if m.pivot == "" {
+		m.db.AddError(fmt.Errorf("WherePivot needs a many-to-many relation queried with Related"))
+		return m
+	}
+	target := clause.Column{Table: m.pivot, Name: column}
+	if values := reflect.ValueOf(value); values.Kind() == reflect.Slice && values.Type().Elem().Kind() != reflect.Uint8 {
+		in := clause.IN{Column: target}
+		for i := 0; i < values.Len(); i++ {
+			in.Values = append(in.Values, values.Index(i).Interface())
+		}
+		m.db = m.db.Where(in)
+		return m
+	}
+	m.db = m.db.Where(clause.Eq{Column: target, Value: value})
+	return m
+}
+
+// Associate links the given records to the loaded model through a belongs-to, has-one or has-many relation.
+// On a belongs-to relation it takes the parent record and sets the foreign key of the loaded model to it;
+// on a has-one or has-many relation it takes the child records, or pointers to slices of them, and sets their
+// foreign key to the loaded model. The foreign keys are saved right away, through the model observers,
+// and the related records must have been created before.
+//
+// Example usage:
+//
+//	err := s.Model.Load(&post).Associate("Author", &user)
+//	err := s.Model.Load(&user).Associate("Posts", &post, &otherPost)
+func (m *model) Associate(relation string, related ...interface{}) error {
+	rel, owner, err := m.relationOf(relation)
+	if err != nil {
+		return err
+	}
+	ctx := m.db.Statement.Context
+	root := m.derive(m.db.Session(&gorm.Session{NewDB: true}))
+
+	switch rel.Type {
+	case schema.BelongsTo:
+		if len(related) != 1 {
+			return fmt.Errorf("associating %s needs exactly one record", relation)
+		}
+		parent := reflect.Indirect(reflect.ValueOf(related[0]))
+		if parent.Kind() != reflect.Struct {
+			return fmt.Errorf("cannot associate %T with %s", related[0], relation)
+		}
+		values := map[string]interface{}{}
+		for _, ref := range rel.References {
+			value, zero := ref.PrimaryKey.ValueOf(ctx, parent)
+			if zero {
+				return fmt.Errorf("cannot associate %s, the record has no %s yet", relation, ref.PrimaryKey.Name)
+			}
+			values[ref.ForeignKey.DBName] = value
+		}
+		if _, err := m.ownForeignKeys(root).UpdateWhere(values); err != nil {
+			return err
+		}
+		return rel.Field.Set(ctx, owner, related[0])
+	case schema.HasOne, schema.HasMany:
+		values := foreignValues(ctx, rel, owner)
+		return forEachRecord(related, func(child interface{}) error {
+			_, err := root.Load(child).UpdateWhere(values)
+			return err
+		})
+	}
+	return fmt.Errorf("cannot associate %s, use Attach for many-to-many relations", relation)
+}
+
+// Dissociate unlinks records from the loaded model by setting their foreign key to NULL, so the foreign key
+// column must be nullable. On a belongs-to relation it clears the foreign key of the loaded model and takes no records;
+// on a has-one or has-many relation it clears the foreign key of the given child records, or of every child
+// of the loaded model when none are given. The related records themselves are not deleted.
+//
+// Example usage:
+//
+//	err := s.Model.Load(&post).Dissociate("Author")
+func (m *model) Dissociate(relation string, related ...interface{}) error {
+	rel, owner, err := m.relationOf(relation)
+	if err != nil {
+		return err
+	}
+	ctx := m.db.Statement.Context
+	root := m.derive(m.db.Session(&gorm.Session{NewDB: true}))
+
+	switch rel.Type {
+	case schema.BelongsTo:
+		values := map[string]interface{}{}
+		for _, ref := range rel.References {
+			values[ref.ForeignKey.DBName] = nil
+		}
+		if _, err := m.ownForeignKeys(root).UpdateWhere(values); err != nil {
+			return err
+		}
+		for _, ref := range rel.References {
+			ref.ForeignKey.ReflectValueOf(ctx, owner).Set(reflect.Zero(ref.ForeignKey.FieldType))
+		}
+		rel.Field.ReflectValueOf(ctx, owner).Set(reflect.Zero(rel.Field.FieldType))
+		return nil
+	case schema.HasOne, schema.HasMany:
+		values := map[string]interface{}{}
+		conditions := foreignValues(ctx, rel, owner)
+		for _, ref := range rel.References {
+			if ref.OwnPrimaryKey {
+				values[ref.ForeignKey.DBName] = nil
+			}
+		}
+		if len(related) == 0 {
+			_, err := root.Load(reflect.New(rel.FieldSchema.ModelType).Interface()).Where(conditions).UpdateWhere(values)
+			return err
+		}
+		return forEachRecord(related, func(child interface{}) error {
+			_, err := root.Load(child).Where(conditions).UpdateWhere(values)
+			return err
+		})
+	}
+	return fmt.Errorf("cannot dissociate %s, use Detach for many-to-many relations", relation)
+}
+
+// Attach adds pivot rows linking the given entity to the related keys. See model.Attach.
+func (r *Repository[T]) Attach(entity *T, relation string, ids interface{}, pivot ...Pivot) error {
+	return r.entity(entity).Attach(relation, ids, pivot...)
+}
+
+// Detach removes the pivot rows linking the given entity to the related keys, or to every related record when
+// ids is nil, and returns the number of removed rows. See model.Detach.
+func (r *Repository[T]) Detach(entity *T, relation string, ids interface{}) (int64, error) {
+	return r.entity(entity).Detach(relation, ids)
+}
+
+// Sync makes the given keys the only records linked to the entity through a many-to-many relation. See model.Sync.
+func (r *Repository[T]) Sync(entity *T, relation string, ids interface{}) (*SyncResult, error) {
+	return r.entity(entity).Sync(relation, ids)
+}
+
+// SyncWithoutDetaching attaches the given keys that are missing without detaching any other record.
+// See model.SyncWithoutDetaching.
+func (r *Repository[T]) SyncWithoutDetaching(entity *T, relation string, ids interface{}) (*SyncResult, error) {
+	return r.entity(entity).SyncWithoutDetaching(relation, ids)
+}
+
+// Toggle detaches the given keys that are attached to the entity and attaches the others. See model.Toggle.
+func (r *Repository[T]) Toggle(entity *T, relation string, ids interface{}) (*SyncResult, error) {
+	return r.entity(entity).Toggle(relation, ids)
+}
+
+// Associate links the given records to the entity through a belongs-to, has-one or has-many relation.
+// See model.Associate.
+func (r *Repository[T]) Associate(entity *T, relation string, related ...interface{}) error {
+	return r.entity(entity).Associate(relation, related...)
+}
+
+// Dissociate unlinks records from the entity by setting their foreign key to NULL. See model.Dissociate.
+func (r *Repository[T]) Dissociate(entity *T, relation string, related ...interface{}) error {
+	return r.entity(entity).Dissociate(relation, related...)
+}
+
+// changePivot reconciles the pivot rows of the loaded model with the given keys, in one transaction.
+func (m *model) changePivot(relation string, ids interface{}, mode pivotMode, shared Pivot) (*SyncResult, error) {
+	p, err := m.pivotOf(relation)
+	if err != nil {
+		return nil, err
+	}
+	ctx := m.db.Statement.Context
+	keys, pivots, err := p.parse(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+	err = m.transaction(m.db.Session(&gorm.Session{NewDB: true}), func(tx *model) error {
+		current, err := p.attached(tx.db)
+		if err != nil {
+			return err
+		}
+		wanted := map[string]bool{}
+		for _, key := range keys {
+			wanted[fmt.Sprint(key)] = true
+		}
+		existing := map[string]bool{}
+		for _, key := range current {
+			existing[fmt.Sprint(key)] = true
+			if !wanted[fmt.Sprint(key)] && mode == pivotSync {
+				result.Detached = append(result.Detached, key)
+			}
+		}
+
+		var attach []interface{}
+		for _, key := range keys {
+			id := fmt.Sprint(key)
+			switch {
+			case !existing[id]:
+				attach = append(attach, key)
+			case mode == pivotToggle:
+				result.Detached = append(result.Detached, key)
+			case (mode == pivotSync || mode == pivotSyncWithoutDetaching) && len(pivots[id]) > 0:
+				if err := p.update(tx.db, key, pivots[id]); err != nil {
+					return err
+				}
+				result.Updated = append(result.Updated, key)
+			}
+		}
+
+		if len(result.Detached) > 0 {
+			detach := clause.IN{Column: clause.Column{Table: p.table, Name: p.column}, Values: result.Detached}
+			if err := tx.db.Table(p.table).Where(p.ownerCondition()).Where(detach).Delete(map[string]interface{}{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(attach) > 0 {
+			if err := p.insert(tx.db, attach, shared, pivots); err != nil {
+				return err
+			}
+			result.Attached = attach
+		}
+		if len(result.Attached)+len(result.Detached)+len(result.Updated) > 0 {
+			tx.invalidate("table:" + p.table)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ownForeignKeys returns a query updating the foreign keys of the loaded model itself. The associations of the
+// loaded model are left out, as GORM would otherwise set its belongs-to foreign keys back to the parents it holds.
+func (m *model) ownForeignKeys(root *model) *model {
+	query := root.Load(m.tempData)
+	query.db = query.db.Omit(clause.Associations)
+	return query
+}
+
+// relationOf returns the relation with the given name of the loaded model, and the loaded model itself.
+func (m *model) relationOf(name string) (*schema.Relationship, reflect.Value, error) {
+	owner := reflect.ValueOf(m.tempData)
+	if owner.Kind() != reflect.Ptr || owner.Elem().Kind() != reflect.Struct {
+		return nil, reflect.Value{}, fmt.Errorf("relations are managed on a pointer to a single model, got %T", m.tempData)
+	}
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return nil, reflect.Value{}, err
+	}
+	rel, ok := stmt.Schema.Relationships.Relations[name]
+	if !ok {
+		return nil, reflect.Value{}, fmt.Errorf("%s has no relation %s", stmt.Schema.Name, name)
+	}
+	for _, field := range stmt.Schema.PrimaryFields {
+		if _, zero := field.ValueOf(m.db.Statement.Context, owner.Elem()); zero {
+			return nil, reflect.Value{}, fmt.Errorf("cannot manage %s of %s, it has no %s yet", name, stmt.Schema.Name, field.Name)
+		}
+	}
+	return rel, owner.Elem(), nil
+}
+
+// foreignValues returns the values of the foreign key columns of the records owned through a has-one or has-many
+// relation, including the type column of polymorphic relations.
+func foreignValues(ctx context.Context, rel *schema.Relationship, owner reflect.Value) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, ref := range rel.References {
+		if ref.OwnPrimaryKey {
+			values[ref.ForeignKey.DBName], _ = ref.PrimaryKey.ValueOf(ctx, owner)
+		} else if ref.PrimaryValue != "" {
+			values[ref.ForeignKey.DBName] = ref.PrimaryValue
+		}
+	}
+	return values
+}
+
+// forEachRecord calls fn with a pointer to every record among the given pointers to records and to slices of records.
+func forEachRecord(records []interface{}, fn func(record interface{}) error) error {
+	for _, record := range records {
+		value := reflect.ValueOf(record)
+		if value.Kind() != reflect.Ptr {
+			return fmt.Errorf("related records must be passed as pointers, got %T", record)
+		}
+		if value.Elem().Kind() != reflect.Slice {
+			if err := fn(record); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := 0; i < value.Elem().Len(); i++ {
+			item := value.Elem().Index(i)
+			if item.Kind() != reflect.Ptr {
+				item = item.Addr()
+			}
+			if err := fn(item.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pivotRelation is the pivot table of a many-to-many relation of a loaded model.
+type pivotRelation struct {
+	table  string
+	schema *schema.Schema
+
+	// owner holds the pivot columns identifying the loaded model and their values.
+	owner map[string]interface{}
+	// column is the pivot column holding the key of the related records, field its field in the join model,
+	// and relatedKey the key field of the related model it references.
+	column     string
+	field      *schema.Field
+	relatedKey *schema.Field
+	related    *schema.Schema
+}
+
+// pivotOf returns the pivot table of the many-to-many relation with the given name of the loaded model.
+func (m *model) pivotOf(name string) (*pivotRelation, error) {
+	rel, owner, err := m.relationOf(name)
+	if err != nil {
+		return nil, err
+	}
+	if rel.Type != schema.Many2Many || rel.JoinTable == nil {
+		return nil, fmt.Errorf("%s is not a many-to-many relation", name)
+	}
+
+	p := &pivotRelation{table: rel.JoinTable.Table, schema: rel.JoinTable, owner: map[string]interface{}{}, related: rel.FieldSchema}
+	for _, ref := range rel.References {
+		switch {
+		case ref.PrimaryValue != "":
+			p.owner[ref.ForeignKey.DBName] = ref.PrimaryValue
+		case ref.OwnPrimaryKey:
+			p.owner[ref.ForeignKey.DBName], _ = ref.PrimaryKey.ValueOf(m.db.Statement.Context, owner)
+		case p.field != nil:
+			return nil, fmt.Errorf("%s references a composite key, which is not supported", name)
+		default:
+			p.column, p.field, p.relatedKey = ref.ForeignKey.DBName, ref.ForeignKey, ref.PrimaryKey
+		}
+	}
+	if p.field == nil {
+		return nil, fmt.Errorf("%s has no related key", name)
+	}
+	return p, nil
+}
+
+// ownerCondition limits a query on the pivot table to the rows of the loaded model.
+func (p *pivotRelation) ownerCondition() clause.Expression {
+	columns := make([]string, 0, len(p.owner))
+	for column := range p.owner {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	conditions := make([]clause.Expression, 0, len(columns))
+	for _, column := range columns {
+		conditions = append(conditions, clause.Eq{Column: clause.Column{Table: p.table, Name: column}, Value: p.owner[column]})
+	}
+	return clause.And(conditions...)
+}
+
+// parse converts the keys given to a pivot operation to the type of the pivot column,
+// and returns them in order together with the pivot values given per key.
+func (p *pivotRelation) parse(ctx context.Context, ids interface{}) ([]interface{}, map[string]Pivot, error) {
+	pivots := map[string]Pivot{}
+	if ids == nil {
+		return nil, pivots, nil
+	}
+
+	var raw []interface{}
+	value := reflect.ValueOf(ids)
+	switch value.Kind() {
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			raw = append(raw, key.Interface())
+		}
+		sort.Slice(raw, func(i, j int) bool { return fmt.Sprint(raw[i]) < fmt.Sprint(raw[j]) })
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			raw = append(raw, ids)
+			break
+		}
+		for i := 0; i < value.Len(); i++ {
+			raw = append(raw, value.Index(i).Interface())
+		}
+	default:
+		raw = append(raw, ids)
+	}
+
+	keys := make([]interface{}, 0, len(raw))
+	seen := map[string]bool{}
+	scratch := reflect.New(p.schema.ModelType).Elem()
+	for _, id := range raw {
+		source := id
+		// Related records are attached by their key.
+		if record := reflect.Indirect(reflect.ValueOf(id)); record.Kind() == reflect.Struct && record.Type() == p.related.ModelType {
+			source, _ = p.relatedKey.ValueOf(ctx, record)
+		}
+		if err := p.field.Set(ctx, scratch, source); err != nil {
+			return nil, nil, fmt.Errorf("invalid key %v for %s: %w", id, p.column, err)
+		}
+		key, _ := p.field.ValueOf(ctx, scratch)
+		if seen[fmt.Sprint(key)] {
+			continue
+		}
+		seen[fmt.Sprint(key)] = true
+		keys = append(keys, key)
+
+		if value.Kind() == reflect.Map {
+			switch values := value.MapIndex(reflect.ValueOf(id)).Interface().(type) {
+			case Pivot:
+				pivots[fmt.Sprint(key)] = values
+			case map[string]interface{}:
+				pivots[fmt.Sprint(key)] = values
+			}
+		}
+	}
+	return keys, pivots, nil
+}
+
+// attached returns the keys of the records currently linked to the loaded model.
+func (p *pivotRelation) attached(tx *gorm.DB) ([]interface{}, error) {
+	rows := reflect.New(reflect.SliceOf(p.field.FieldType))
+	if err := tx.Table(p.table).Where(p.ownerCondition()).Pluck(p.column, rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, rows.Elem().Len())
+	for i := range keys {
+		keys[i] = rows.Elem().Index(i).Interface()
+	}
+	return keys, nil
+}
+
+// insert adds the pivot rows of the given keys, after checking that the related records exist.
+// The check runs through the tenant scope, so records of another tenant cannot be attached.
+func (p *pivotRelation) insert(tx *gorm.DB, keys []interface{}, shared Pivot, pivots map[string]Pivot) error {
+	var found int64
+	related := reflect.New(p.related.ModelType).Interface()
+	keyColumn := clause.Column{Table: clause.CurrentTable, Name: p.relatedKey.DBName}
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(related).Where(clause.IN{Column: keyColumn, Values: keys}).Count(&found).Error; err != nil {
+		return err
+	}
+	if found != int64(len(keys)) {
+		return fmt.Errorf("%w: attaching %v to %s", gorm.ErrRecordNotFound, keys, p.table)
+	}
+
+	now := tx.NowFunc()
+	rows := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		row := map[string]interface{}{}
+		for _, column := range []string{"created_at", "updated_at"} {
+			if _, ok := p.schema.FieldsByDBName[column]; ok {
+				row[column] = now
+			}
+		}
+		for column, value := range shared {
+			row[column] = value
+		}
+		for column, value := range pivots[fmt.Sprint(key)] {
+			row[column] = value
+		}
+		for column, value := range p.owner {
+			row[column] = value
+		}
+		row[p.column] = key
+		rows = append(rows, row)
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Table(p.table).Create(&rows).Error
+}
+
+// update sets the given pivot columns of the row of a single key.
+func (p *pivotRelation) update(tx *gorm.DB, key interface{}, values Pivot) error {
+	assignments := map[string]interface{}{}
+	if _, ok := p.schema.FieldsByDBName["updated_at"]; ok {
+		assignments["updated_at"] = tx.NowFunc()
+	}
+	for column, value := range values {
+		assignments[column] = value
+	}
+	row := clause.Eq{Column: clause.Column{Table: p.table, Name: p.column}, Value: key}
+	return tx.Session(&gorm.Session{NewDB: true}).Table(p.table).Where(p.ownerCondition()).Where(row).Updates(assignments).Error
+}
@@ -0,0 +1,488 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// relUser has many-to-many roles, whose pivot rows carry a role and timestamps, and has many posts.
+type relUser struct {
+	ID    uint
+	Name  string
+	Roles []relRole `gorm:"many2many:rel_user_roles"`
+	Posts []relPost `gorm:"foreignKey:AuthorID"`
+}
+
+type relRole struct {
+	ID   uint
+	Name string
+}
+
+// relUserRole is the join model of the roles of a user.
+type relUserRole struct {
+	RelUserID uint `gorm:"primaryKey"`
+	RelRoleID uint `gorm:"primaryKey"`
+	Role      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// relPost belongs to an author, through a nullable foreign key.
+type relPost struct {
+	ID       uint
+	Title    string
+	AuthorID *uint
+	Author   *relUser
+}
+
+// seedRelations creates the users ann and bob, the roles 1 to 4 and the posts p1 to p3 without an author.
+// Ann has the roles 1 as "owner" and 2 as "editor", bob has the role 1 as "viewer".
+func seedRelations(t *testing.T) (*Repository[relUser], *relUser) {
+	t.Helper()
+	db := testdb.Open(t)
+	if err := db.SetupJoinTable(&relUser{}, "Roles", &relUserRole{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&relUser{}, &relRole{}, &relPost{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []interface{}{
+		&[]relRole{{Name: "admin"}, {Name: "writer"}, {Name: "reader"}, {Name: "guest"}},
+		&[]relPost{{Title: "p1"}, {Title: "p2"}, {Title: "p3"}},
+		&[]relUser{{Name: "ann"}, {Name: "bob"}},
+		&[]relUserRole{{RelUserID: 1, RelRoleID: 1, Role: "owner"}, {RelUserID: 1, RelRoleID: 2, Role: "editor"}, {RelUserID: 2, RelRoleID: 1, Role: "viewer"}},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewRepository[relUser](NewModel(db)), &relUser{ID: 1, Name: "ann"}
+}
+
+// userRoles returns the pivot rows of the given user as "role:pivot role" ordered by role,
+// and fails when a row has no timestamps.
+func userRoles(t *testing.T, users *Repository[relUser], userID uint) string {
+	t.Helper()
+	var rows []relUserRole
+	if err := users.query().Session(&gorm.Session{NewDB: true}).Where("rel_user_id = ?", userID).Order("rel_role_id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	var roles []string
+	for _, row := range rows {
+		if row.CreatedAt.IsZero() || row.UpdatedAt.IsZero() {
+			t.Errorf("pivot row %d of user %d has no timestamps", row.RelRoleID, userID)
+		}
+		roles = append(roles, fmt.Sprintf("%d:%s", row.RelRoleID, row.Role))
+	}
+	return strings.Join(roles, ",")
+}
+
+// formatSync formats a SyncResult as "+attached -detached ~updated".
+func formatSync(result *SyncResult) string {
+	return fmt.Sprintf("+%v -%v ~%v", result.Attached, result.Detached, result.Updated)
+}
+
+func TestPivot(t *testing.T) {
+	tests := []struct {
+		name string
+		// change changes the roles of ann and returns its result formatted.
+		change     func(users *Repository[relUser], ann *relUser) (string, error)
+		wantResult string
+		wantErr    bool
+		wantRoles  string
+	}{
+		{
+			name: "attach with shared pivot values",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(ann, "Roles", []uint{2, 3}, Pivot{"role": "viewer"})
+			},
+			wantRoles: "1:owner,2:editor,3:viewer",
+		},
+		{
+			name: "attach with pivot values per key",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(ann, "Roles", map[uint]Pivot{3: {"role": "reader"}, 4: {"role": "guest"}})
+			},
+			wantRoles: "1:owner,2:editor,3:reader,4:guest",
+		},
+		{
+			name: "attach records and repeated keys",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(ann, "Roles", []interface{}{relRole{ID: 3}, &relRole{ID: 4}, "3"})
+			},
+			wantRoles: "1:owner,2:editor,3:,4:",
+		},
+		{
+			name: "attach a missing record",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(ann, "Roles", []uint{3, 9})
+			},
+			wantErr:   true,
+			wantRoles: "1:owner,2:editor",
+		},
+		{
+			name: "detach",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				detached, err := users.Detach(ann, "Roles", []uint{1, 4})
+				return fmt.Sprint(detached), err
+			},
+			wantResult: "1",
+			wantRoles:  "2:editor",
+		},
+		{
+			name: "detach everything",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				detached, err := users.Detach(ann, "Roles", nil)
+				return fmt.Sprint(detached), err
+			},
+			wantResult: "2",
+			wantRoles:  "",
+		},
+		{
+			name: "sync",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				result, err := users.Sync(ann, "Roles", []uint{2, 3})
+				return formatSync(result), err
+			},
+			wantResult: "+[3] -[1] ~[]",
+			wantRoles:  "2:editor,3:",
+		},
+		{
+			name: "sync with pivot values",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				result, err := users.Sync(ann, "Roles", map[uint]Pivot{2: {"role": "owner"}, 4: nil})
+				return formatSync(result), err
+			},
+			wantResult: "+[4] -[1] ~[2]",
+			wantRoles:  "2:owner,4:",
+		},
+		{
+			name: "sync to nothing",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				result, err := users.Sync(ann, "Roles", []uint{})
+				return formatSync(result), err
+			},
+			wantResult: "+[] -[1 2] ~[]",
+			wantRoles:  "",
+		},
+		{
+			name: "sync with a missing record changes nothing",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				_, err := users.Sync(ann, "Roles", []uint{3, 9})
+				return "", err
+			},
+			wantErr:   true,
+			wantRoles: "1:owner,2:editor",
+		},
+		{
+			name: "sync without detaching",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				result, err := users.SyncWithoutDetaching(ann, "Roles", map[uint]Pivot{1: {"role": "admin"}, 3: nil})
+				return formatSync(result), err
+			},
+			wantResult: "+[3] -[] ~[1]",
+			wantRoles:  "1:admin,2:editor,3:",
+		},
+		{
+			name: "toggle",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				result, err := users.Toggle(ann, "Roles", []uint{2, 3})
+				return formatSync(result), err
+			},
+			wantResult: "+[3] -[2] ~[]",
+			wantRoles:  "1:owner,3:",
+		},
+		{
+			name: "not a many-to-many relation",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(ann, "Posts", 1)
+			},
+			wantErr:   true,
+			wantRoles: "1:owner,2:editor",
+		},
+		{
+			name: "unknown relation",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(ann, "Groups", 1)
+			},
+			wantErr:   true,
+			wantRoles: "1:owner,2:editor",
+		},
+		{
+			name: "record without a key",
+			change: func(users *Repository[relUser], ann *relUser) (string, error) {
+				return "", users.Attach(&relUser{}, "Roles", 3)
+			},
+			wantErr:   true,
+			wantRoles: "1:owner,2:editor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, ann := seedRelations(t)
+			result, err := tt.change(users, ann)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if err == nil && result != tt.wantResult {
+				t.Errorf("result = %q, want %q", result, tt.wantResult)
+			}
+			if got := userRoles(t, users, 1); got != tt.wantRoles {
+				t.Errorf("roles of ann = %q, want %q", got, tt.wantRoles)
+			}
+			if got := userRoles(t, users, 2); got != "1:viewer" {
+				t.Errorf("roles of bob = %q, want them untouched", got)
+			}
+		})
+	}
+}
+
+func TestAttachMissingRecord(t *testing.T) {
+	users, ann := seedRelations(t)
+	if err := users.Attach(ann, "Roles", 9); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Attach of a missing role = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	users, ann := seedRelations(t)
+	if err := users.Associate(ann, "Posts", &relPost{ID: 1}, &relPost{ID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		query     func(dest interface{}) error
+		dest      func() interface{}
+		wantNames string
+		wantErr   bool
+	}{
+		{
+			name: "many-to-many",
+			query: func(dest interface{}) error {
+				return users.entity(ann).Related("Roles", dest).Order("id").Get()
+			},
+			dest:      func() interface{} { return &[]relRole{} },
+			wantNames: "admin,writer",
+		},
+		{
+			name: "filtered on a pivot column",
+			query: func(dest interface{}) error {
+				return users.entity(ann).Related("Roles", dest).WherePivot("role", "editor").Get()
+			},
+			dest:      func() interface{} { return &[]relRole{} },
+			wantNames: "writer",
+		},
+		{
+			name: "filtered on any of several pivot values",
+			query: func(dest interface{}) error {
+				return users.entity(ann).Related("Roles", dest).WherePivot("role", []string{"owner", "viewer"}).Get()
+			},
+			dest:      func() interface{} { return &[]relRole{} },
+			wantNames: "admin",
+		},
+		{
+			name: "has many",
+			query: func(dest interface{}) error {
+				return users.entity(ann).Related("Posts", dest).Order("id").Get()
+			},
+			dest:      func() interface{} { return &[]relPost{} },
+			wantNames: "p1,p3",
+		},
+		{
+			name: "belongs to",
+			query: func(dest interface{}) error {
+				authorID := uint(1)
+				return users.m.Load(&relPost{ID: 1, AuthorID: &authorID}).Related("Author", dest).Get()
+			},
+			dest:      func() interface{} { return &[]relUser{} },
+			wantNames: "ann",
+		},
+		{
+			name: "pivot filter on another relation",
+			query: func(dest interface{}) error {
+				return users.entity(ann).Related("Posts", dest).WherePivot("role", "editor").Get()
+			},
+			dest:    func() interface{} { return &[]relPost{} },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := tt.dest()
+			err := tt.query(dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantErr)
+			}
+			var names []string
+			switch found := dest.(type) {
+			case *[]relRole:
+				for _, role := range *found {
+					names = append(names, role.Name)
+				}
+			case *[]relPost:
+				for _, post := range *found {
+					names = append(names, post.Title)
+				}
+			case *[]relUser:
+				for _, user := range *found {
+					names = append(names, user.Name)
+				}
+			}
+			if got := strings.Join(names, ","); err == nil && got != tt.wantNames {
+				t.Errorf("found %q, want %q", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+// postAuthors returns the stored posts with their author, as "p1:1,p2:-".
+func postAuthors(t *testing.T, users *Repository[relUser]) string {
+	t.Helper()
+	var posts []relPost
+	if err := users.query().Session(&gorm.Session{NewDB: true}).Order("id").Find(&posts).Error; err != nil {
+		t.Fatal(err)
+	}
+	var authors []string
+	for _, post := range posts {
+		author := "-"
+		if post.AuthorID != nil {
+			author = fmt.Sprint(*post.AuthorID)
+		}
+		authors = append(authors, post.Title+":"+author)
+	}
+	return strings.Join(authors, ",")
+}
+
+func TestAssociate(t *testing.T) {
+	tests := []struct {
+		name string
+		// change runs on the posts p1 and p3 of ann, with p2 without an author.
+		change      func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error
+		wantErr     bool
+		wantAuthors string
+	}{
+		{
+			name: "belongs to",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				p2 := relPost{ID: 2, Title: "p2"}
+				if err := posts.Associate(&p2, "Author", users.m.Load(&relUser{ID: 2}).tempData); err != nil {
+					return err
+				}
+				if p2.AuthorID == nil || *p2.AuthorID != 2 || p2.Author == nil || p2.Author.ID != 2 {
+					return fmt.Errorf("associated post = %+v, want bob as its author", p2)
+				}
+				return nil
+			},
+			wantAuthors: "p1:1,p2:2,p3:1",
+		},
+		{
+			name: "belongs to, replacing the loaded parent",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				authorID := uint(1)
+				p1 := relPost{ID: 1, AuthorID: &authorID, Author: ann}
+				return posts.Associate(&p1, "Author", &relUser{ID: 2})
+			},
+			wantAuthors: "p1:2,p2:-,p3:1",
+		},
+		{
+			name: "has many, with a slice",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return users.Associate(&relUser{ID: 2}, "Posts", &[]relPost{{ID: 1}, {ID: 2}})
+			},
+			wantAuthors: "p1:2,p2:2,p3:1",
+		},
+		{
+			name: "dissociate belongs to",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				authorID := uint(1)
+				p1 := relPost{ID: 1, AuthorID: &authorID, Author: ann}
+				if err := posts.Dissociate(&p1, "Author"); err != nil {
+					return err
+				}
+				if p1.AuthorID != nil || p1.Author != nil {
+					return fmt.Errorf("dissociated post = %+v, want no author", p1)
+				}
+				return nil
+			},
+			wantAuthors: "p1:-,p2:-,p3:1",
+		},
+		{
+			name: "dissociate some children",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return users.Dissociate(ann, "Posts", &relPost{ID: 3})
+			},
+			wantAuthors: "p1:1,p2:-,p3:-",
+		},
+		{
+			name: "dissociate the child of another record",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return users.Dissociate(&relUser{ID: 2}, "Posts", &relPost{ID: 3})
+			},
+			wantAuthors: "p1:1,p2:-,p3:1",
+		},
+		{
+			name: "dissociate every child",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return users.Dissociate(ann, "Posts")
+			},
+			wantAuthors: "p1:-,p2:-,p3:-",
+		},
+		{
+			name: "associate a parent that is not saved",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return posts.Associate(&relPost{ID: 2}, "Author", &relUser{Name: "new"})
+			},
+			wantErr:     true,
+			wantAuthors: "p1:1,p2:-,p3:1",
+		},
+		{
+			name: "associate two parents",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return posts.Associate(&relPost{ID: 2}, "Author", ann, &relUser{ID: 2})
+			},
+			wantErr:     true,
+			wantAuthors: "p1:1,p2:-,p3:1",
+		},
+		{
+			name: "associate a many-to-many relation",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return users.Associate(ann, "Roles", &relRole{ID: 3})
+			},
+			wantErr:     true,
+			wantAuthors: "p1:1,p2:-,p3:1",
+		},
+		{
+			name: "associate a record that is not a pointer",
+			change: func(users *Repository[relUser], ann *relUser, posts *Repository[relPost]) error {
+				return users.Associate(ann, "Posts", relPost{ID: 2})
+			},
+			wantErr:     true,
+			wantAuthors: "p1:1,p2:-,p3:1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, ann := seedRelations(t)
+			posts := NewRepository[relPost](users.m)
+			if err := users.Associate(ann, "Posts", &relPost{ID: 1}, &relPost{ID: 3}); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.change(users, ann, posts); (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if got := postAuthors(t, users); got != tt.wantAuthors {
+				t.Errorf("authors = %q, want %q", got, tt.wantAuthors)
+			}
+		})
+	}
+}
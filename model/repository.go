@@ -0,0 +1,246 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository is a typed data access layer for a single model type T.
+// Unlike Load, every finder returns T or []T, so passing the wrong destination is a compile error instead of a runtime surprise.
+// Repositories are immutable: every chain method returns a new Repository and leaves the receiver untouched,
+// so a single repository can safely be stored on a handler and shared between requests.
+//
+// Example usage:
+//
+//	users := model.NewRepository[model.User](s.Model)
+//	user, err := users.Where("email = ?", email).First()
+type Repository[T any] struct {
+	m *model
+}
+
+// NewRepository creates a new Repository for the model type T using the database connection of the given Model.
+func NewRepository[T any](m Model) *Repository[T] {
+	base := m.Load(new(T))
+	base.db = base.db.Session(&gorm.Session{})
+	return &Repository[T]{m: base}
+}
+
+// On returns a new Repository running its queries on the named database connection, see Model.On.
+// The conditions chained on the receiver are not kept, so call it first.
+//
+// Example usage:
+//
+//	customers, err := model.NewRepository[LegacyCustomer](s.Model).On("archive").All()
+func (r *Repository[T]) On(name string) *Repository[T] {
+	return NewRepository[T](r.m.On(name))
+}
+
+// chain returns a new Repository with the given modification applied to a copy of the current query.
+func (r *Repository[T]) chain(apply func(m *model) *model) *Repository[T] {
+	next := r.m.derive(r.m.db.Session(&gorm.Session{}))
+	return &Repository[T]{m: apply(next)}
+}
+
+// query returns a copy of the current query that can be finished without affecting the repository.
+func (r *Repository[T]) query() *gorm.DB {
+	return r.m.db.Session(&gorm.Session{})
+}
+
+// entity returns a model bound to the given entity on a connection without any of the chained conditions.
+// It is used by the write methods, which must only ever touch the given entity.
+func (r *Repository[T]) entity(entity *T) *model {
+	root := r.m.derive(r.m.db.Session(&gorm.Session{NewDB: true}))
+	return root.Load(entity)
+}
+
+// Where adds a WHERE condition to the query and returns a new Repository.
+func (r *Repository[T]) Where(query interface{}, args ...interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Where(query, args...) })
+}
+
+// WhereEncrypted adds a condition on the blind index of an encrypted column and returns a new Repository, see Model.WhereEncrypted.
+func (r *Repository[T]) WhereEncrypted(column string, value interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.WhereEncrypted(column, value) })
+}
+
+// Or adds an OR condition to the query and returns a new Repository.
+func (r *Repository[T]) Or(query interface{}, args ...interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Or(query, args...) })
+}
+
+// Select specifies the columns to retrieve and returns a new Repository.
+func (r *Repository[T]) Select(query interface{}, args ...interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Select(query, args...) })
+}
+
+// With adds an eager load for the specified relation and returns a new Repository.
+func (r *Repository[T]) With(relation string) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.With(relation) })
+}
+
+// WithCondition adds a conditional eager load for the specified relation and returns a new Repository.
+func (r *Repository[T]) WithCondition(relation string, args ...interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.WithCondition(relation, args...) })
+}
+
+// Order specifies the order of the records and returns a new Repository.
+func (r *Repository[T]) Order(value interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Order(value) })
+}
+
+// Joins adds a JOIN clause to the query and returns a new Repository.
+func (r *Repository[T]) Joins(query string, args ...interface{}) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Joins(query, args...) })
+}
+
+// Limit specifies the maximum number of records to retrieve and returns a new Repository.
+func (r *Repository[T]) Limit(limit int) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Limit(limit) })
+}
+
+// ApplyScope applies a scope function to the query and returns a new Repository.
+func (r *Repository[T]) ApplyScope(scope func(*gorm.DB) *gorm.DB) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.ApplyScope(scope) })
+}
+
+// Debug enables SQL logging for the query and returns a new Repository.
+func (r *Repository[T]) Debug() *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Debug() })
+}
+
+// WithTrashed includes soft deleted records in the query and returns a new Repository.
+func (r *Repository[T]) WithTrashed() *Repository[T] {
+	return r.chain(func(m *model) *model { return m.WithTrashed() })
+}
+
+// OnlyTrashed limits the query to soft deleted records and returns a new Repository.
+func (r *Repository[T]) OnlyTrashed() *Repository[T] {
+	return r.chain(func(m *model) *model { return m.OnlyTrashed() })
+}
+
+// OnPrimary makes the queries of the repository run on the primary database and returns a new Repository.
+// See model.OnPrimary.
+func (r *Repository[T]) OnPrimary() *Repository[T] {
+	return r.chain(func(m *model) *model { return m.OnPrimary() })
+}
+
+// Remember caches the results of the finders under the given key for ttl and returns a new Repository.
+// See model.Remember.
+func (r *Repository[T]) Remember(key string, ttl time.Duration) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Remember(key, ttl) })
+}
+
+// Cache caches the results of the finders for ttl under keys derived from the generated SQL and returns a new Repository.
+// See model.Cache.
+func (r *Repository[T]) Cache(ttl time.Duration) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.Cache(ttl) })
+}
+
+// Find retrieves the record with the given primary key.
+// It returns gorm.ErrRecordNotFound if no record matches.
+func (r *Repository[T]) Find(id interface{}) (T, error) {
+	var entity T
+	err := r.m.cached("find", &entity, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(dest)
+	})
+	return entity, err
+}
+
+// First retrieves the first record, ordered by primary key, that matches the current query.
+// It returns gorm.ErrRecordNotFound if no record matches.
+func (r *Repository[T]) First() (T, error) {
+	var entity T
+	err := r.m.cached("first", &entity, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.First(dest)
+	})
+	return entity, err
+}
+
+// All retrieves every record that matches the current query.
+func (r *Repository[T]) All() ([]T, error) {
+	var entities []T
+	err := r.m.cached("all", &entities, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Find(dest)
+	})
+	return entities, err
+}
+
+// Count returns the number of records that match the current query.
+func (r *Repository[T]) Count() (int64, error) {
+	var count int64
+	err := r.m.cached("count", &count, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Count(dest.(*int64))
+	})
+	return count, err
+}
+
+// Exists reports whether at least one record matches the current query.
+func (r *Repository[T]) Exists() (bool, error) {
+	var found []int
+	err := r.query().Select("1").Limit(1).Find(&found).Error
+	return len(found) > 0, err
+}
+
+// Paginate retrieves a single page of the records that match the current query.
+// The Records field of the returned Pagination holds a []T.
+func (r *Repository[T]) Paginate(page int, perPage int) (*Pagination, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+
+	var totalRecords int64
+	err := r.m.cached("paginate:count", &totalRecords, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Count(dest.(*int64))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entities []T
+	err = r.m.cached(fmt.Sprintf("paginate:%d:%d", page, perPage), &entities, func(db *gorm.DB, dest interface{}) *gorm.DB {
+		return db.Offset((page - 1) * perPage).Limit(perPage).Find(dest)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pagination{
+		Records:        entities,
+		TotalRecords:   int(totalRecords),
+		CurrentPage:    page,
+		TotalPages:     int(math.Ceil(float64(totalRecords) / float64(perPage))),
+		RecordsPerPage: perPage,
+	}, nil
+}
+
+// Create inserts the given entity into the database and fills in its generated fields, such as the ID.
+func (r *Repository[T]) Create(entity *T) error {
+	return r.entity(entity).Create()
+}
+
+// Update saves every field of the given entity.
+func (r *Repository[T]) Update(entity *T) error {
+	return r.entity(entity).Save()
+}
+
+// Delete deletes the given entity. Models embedding gorm.Model are soft deleted.
+func (r *Repository[T]) Delete(entity *T) error {
+	return r.entity(entity).Delete()
+}
+
+// Restore brings the given soft deleted entity back.
+func (r *Repository[T]) Restore(entity *T) error {
+	return r.entity(entity).Restore()
+}
+
+// ForceDelete permanently deletes the given entity, even if it supports soft deletes.
+func (r *Repository[T]) ForceDelete(entity *T) error {
+	return r.entity(entity).ForceDelete()
+}
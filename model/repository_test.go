@@ -0,0 +1,189 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// repoArticle is the model of the repository tests.
+type repoArticle struct {
+	gorm.Model
+	Title string
+	Views int
+}
+
+// seedRepoArticles creates articles with the given titles, each with its position as views.
+func seedRepoArticles(t *testing.T, repository *Repository[repoArticle], titles ...string) {
+	t.Helper()
+	for i, title := range titles {
+		if err := repository.Create(&repoArticle{Title: title, Views: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepositoryQueries(t *testing.T) {
+	articles := NewRepository[repoArticle](NewModel(testdb.Open(t, &repoArticle{})))
+	seedRepoArticles(t, articles, "go", "rust", "zig", "gorm")
+
+	tests := []struct {
+		name       string
+		repository *Repository[repoArticle]
+		wantTitles []string
+		wantCount  int64
+	}{
+		{"all", articles, []string{"go", "rust", "zig", "gorm"}, 4},
+		{"where", articles.Where("title LIKE ?", "go%"), []string{"go", "gorm"}, 2},
+		{"or", articles.Where("views = ?", 2).Or("views = ?", 3), []string{"rust", "zig"}, 2},
+		{"order and limit", articles.Order("views DESC").Limit(2), []string{"gorm", "zig"}, 4},
+		{"scope", articles.ApplyScope(func(db *gorm.DB) *gorm.DB { return db.Where("views > ?", 3) }), []string{"gorm"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := tt.repository.All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, article := range found {
+				titles = append(titles, article.Title)
+			}
+			if len(titles) != len(tt.wantTitles) {
+				t.Fatalf("got %v, want %v", titles, tt.wantTitles)
+			}
+			for i := range titles {
+				if titles[i] != tt.wantTitles[i] {
+					t.Fatalf("got %v, want %v", titles, tt.wantTitles)
+				}
+			}
+			count, err := tt.repository.Limit(-1).Count()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("Count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRepositoryIsImmutable(t *testing.T) {
+	articles := NewRepository[repoArticle](NewModel(testdb.Open(t, &repoArticle{})))
+	seedRepoArticles(t, articles, "go", "rust")
+
+	filtered := articles.Where("title = ?", "go")
+	_ = filtered.Where("views = ?", 100)
+	if count, _ := filtered.Count(); count != 1 {
+		t.Errorf("filtered Count = %d after chaining on it, want 1", count)
+	}
+	if count, _ := articles.Count(); count != 2 {
+		t.Errorf("base Count = %d after chaining on it, want 2", count)
+	}
+}
+
+func TestRepositoryFinders(t *testing.T) {
+	articles := NewRepository[repoArticle](NewModel(testdb.Open(t, &repoArticle{})))
+	seedRepoArticles(t, articles, "go", "rust")
+
+	tests := []struct {
+		name      string
+		find      func() (repoArticle, error)
+		wantTitle string
+		wantErr   error
+	}{
+		{"find by id", func() (repoArticle, error) { return articles.Find(2) }, "rust", nil},
+		{"find by string id is not read as SQL", func() (repoArticle, error) { return articles.Find("1 OR 1=1") }, "", gorm.ErrRecordNotFound},
+		{"find missing", func() (repoArticle, error) { return articles.Find(10) }, "", gorm.ErrRecordNotFound},
+		{"first", func() (repoArticle, error) { return articles.Where("views > ?", 0).First() }, "go", nil},
+		{"first missing", func() (repoArticle, error) { return articles.Where("title = ?", "zig").First() }, "", gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article, err := tt.find()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if article.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", article.Title, tt.wantTitle)
+			}
+		})
+	}
+
+	exists, err := articles.Where("title = ?", "rust").Exists()
+	if err != nil || !exists {
+		t.Errorf("Exists = %v, %v, want true", exists, err)
+	}
+	exists, err = articles.Where("title = ?", "zig").Exists()
+	if err != nil || exists {
+		t.Errorf("Exists = %v, %v, want false", exists, err)
+	}
+}
+
+func TestRepositoryPaginate(t *testing.T) {
+	articles := NewRepository[repoArticle](NewModel(testdb.Open(t, &repoArticle{})))
+	seedRepoArticles(t, articles, "a", "b", "c", "d", "e")
+
+	tests := []struct {
+		page, perPage int
+		wantTitles    []string
+		wantPage      int
+		wantPages     int
+	}{
+		{1, 2, []string{"a", "b"}, 1, 3},
+		{3, 2, []string{"e"}, 3, 3},
+		{4, 2, nil, 4, 3},
+		{0, 10, []string{"a", "b", "c", "d", "e"}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		pagination, err := articles.Order("id").Paginate(tt.page, tt.perPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := pagination.Records.([]repoArticle)
+		if len(records) != len(tt.wantTitles) {
+			t.Fatalf("page %d/%d holds %d records, want %v", tt.page, tt.perPage, len(records), tt.wantTitles)
+		}
+		for i, record := range records {
+			if record.Title != tt.wantTitles[i] {
+				t.Errorf("page %d/%d record %d = %q, want %q", tt.page, tt.perPage, i, record.Title, tt.wantTitles[i])
+			}
+		}
+		if pagination.TotalRecords != 5 || pagination.CurrentPage != tt.wantPage || pagination.TotalPages != tt.wantPages {
+			t.Errorf("page %d/%d = %+v", tt.page, tt.perPage, pagination)
+		}
+	}
+}
+
+func TestRepositoryWrites(t *testing.T) {
+	articles := NewRepository[repoArticle](NewModel(testdb.Open(t, &repoArticle{})))
+	article := repoArticle{Title: "draft"}
+	if err := articles.Create(&article); err != nil {
+		t.Fatal(err)
+	}
+	if article.ID == 0 {
+		t.Fatal("Create did not fill in the ID")
+	}
+
+	// The chained conditions of a repository never leak into its writes.
+	filtered := articles.Where("title = ?", "nothing")
+	article.Title = "published"
+	if err := filtered.Update(&article); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := articles.Find(article.ID); err != nil || stored.Title != "published" {
+		t.Fatalf("Find after Update = %+v, %v", stored, err)
+	}
+
+	if err := filtered.Delete(&article); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := articles.Find(article.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Find after Delete = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
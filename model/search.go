@@ -0,0 +1,258 @@
+package model
+
+import (
+	"GoAPIfy/core/search"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Searchable is implemented by models that are kept in a search index.
+// Once the model service has a search engine (see WithSearch), creating, saving, updating, restoring and deleting
+// records through the model wrapper or a Repository queues the matching index updates, which are sent in batches
+// after the transaction they belong to has been committed, Upsert included. Raw SQL does not update the index,
+// use Repository.Reindex afterwards. The ID of the document is the primary key of the record, which must be a single column.
+//
+// Example usage:
+//
+//	func (Post) SearchIndex() string                { return "posts" }
+//	func (p Post) SearchDocument() map[string]interface{} {
+//		return map[string]interface{}{"title": p.Title, "body": p.Body, "published": p.Published}
+//	}
+//	func (Post) FilterableAttributes() []string { return []string{"published"} }
+//	func (Post) SortableAttributes() []string   { return []string{"title"} }
+type Searchable interface {
+	// SearchIndex returns the name of the index the records are stored in.
+	SearchIndex() string
+	// SearchDocument returns the attributes of the record that are indexed. The ID is added automatically.
+	SearchDocument() map[string]interface{}
+	// FilterableAttributes returns the attributes of the document that can be used in search filters.
+	FilterableAttributes() []string
+	// SortableAttributes returns the attributes of the document the results can be sorted by.
+	SortableAttributes() []string
+}
+
+var searchableType = reflect.TypeOf((*Searchable)(nil)).Elem()
+
+// ErrSearchNotConfigured is returned by Search when the model service has no search engine.
+var ErrSearchNotConfigured = errors.New("search is not configured, create the model service with WithSearch")
+
+// isSearchable reports whether the given model type implements Searchable.
+func isSearchable(modelType reflect.Type) bool {
+	return modelType != nil && reflect.PtrTo(modelType).Implements(searchableType)
+}
+
+// WithSearch returns a model that keeps the indexes of Searchable models up to date with the given search engine
+// and can search them, see Search.
+//
+// Example usage:
+//
+//	engine, err := search.New(db)
+//	modelService := model.NewModel(db).WithSearch(engine)
+func (m *model) WithSearch(engine search.Engine) *model {
+	next := m.derive(m.db)
+	next.search = engine
+	return next
+}
+
+// Search runs a full text search on the index of the loaded Searchable model and loads the matching records,
+// by relevance, into the loaded slice. It takes in the search text, a filter in the syntax of Meilisearch such as
+// `published = true AND year > 2020`, which may be empty, and optional "attribute:asc" or "attribute:desc" sort entries.
+// The number of results follows Limit and Offset of the query, or the default of the search engine without them.
+// Records are hydrated from the database with the conditions of the current query, which also keeps the tenant scope
+// of TenantScoped models; a hit that no longer matches the query, or no longer exists, is left out.
+//
+// Example usage:
+//
+//	var posts []model.Post
+//	err := s.Model.Load(&posts).Limit(20).Search("gorm tips", "published = true")
+func (m *model) Search(text string, filter string, sort ...string) error {
+	modelType := entityType(m.tempData)
+	if !isSearchable(modelType) {
+		return fmt.Errorf("%v is not searchable", modelType)
+	}
+	if m.search == nil {
+		return ErrSearchNotConfigured
+	}
+	target := reflect.ValueOf(m.tempData)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("search results must be loaded into a pointer to a slice, got %T", m.tempData)
+	}
+
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return err
+	}
+	index, err := searchIndexOf(stmt.Schema)
+	if err != nil {
+		return err
+	}
+
+	query := search.Query{Index: index, Text: text, Filter: filter, Sort: sort}
+	if limit, ok := m.db.Statement.Clauses["LIMIT"].Expression.(clause.Limit); ok {
+		if limit.Limit != nil {
+			query.Limit = *limit.Limit
+		}
+		query.Offset = limit.Offset
+	}
+	ids, _, err := m.search.Search(query)
+	if err != nil {
+		return err
+	}
+
+	results := reflect.MakeSlice(target.Elem().Type(), 0, len(ids))
+	if len(ids) > 0 {
+		records, err := m.hydrate(stmt.Schema, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if record, ok := records[id]; ok {
+				if target.Elem().Type().Elem().Kind() == reflect.Ptr {
+					record = record.Addr()
+				}
+				results = reflect.Append(results, record)
+			}
+		}
+	}
+	target.Elem().Set(results)
+	return nil
+}
+
+// hydrate loads the records with the given document IDs that match the current query, by ID.
+func (m *model) hydrate(s *schema.Schema, ids []string) (map[string]reflect.Value, error) {
+	primary := s.PrioritizedPrimaryField
+	keys := make([]interface{}, 0, len(ids))
+	scratch := reflect.New(s.ModelType).Elem()
+	for _, id := range ids {
+		// Converting through the field keeps the type of the column, such as a number for an integer key.
+		if err := primary.Set(m.db.Statement.Context, scratch, id); err != nil {
+			return nil, err
+		}
+		key, _ := primary.ValueOf(m.db.Statement.Context, scratch)
+		keys = append(keys, key)
+	}
+
+	// The search decides the number and order of the results, drop both from a private copy of the query.
+	query := m.db.Session(&gorm.Session{}).Clauses()
+	delete(query.Statement.Clauses, "LIMIT")
+	delete(query.Statement.Clauses, "ORDER BY")
+
+	found := reflect.New(reflect.SliceOf(s.ModelType))
+	column := clause.Column{Table: clause.CurrentTable, Name: primary.DBName}
+	if err := query.Where(clause.IN{Column: column, Values: keys}).Find(found.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]reflect.Value, found.Elem().Len())
+	for i := 0; i < found.Elem().Len(); i++ {
+		record := found.Elem().Index(i)
+		records[recordID(m.db.Statement.Context, s, record.Addr().Interface())] = record
+	}
+	return records, nil
+}
+
+// Search runs a full text search on the index of T and returns the matching records by relevance.
+// See model.Search.
+func (r *Repository[T]) Search(text string, filter string, sort ...string) ([]T, error) {
+	var results []T
+	err := r.m.derive(r.query()).Load(&results).Search(text, filter, sort...)
+	return results, err
+}
+
+// Reindex queues every record that matches the current query for indexing, for instance after importing records
+// with raw SQL or after changing SearchDocument. The records are read in chunks and sent in batches.
+func (r *Repository[T]) Reindex(ctx context.Context) error {
+	if r.m.search == nil {
+		return ErrSearchNotConfigured
+	}
+	return r.ChunkByID(ctx, eachChunkSize, func(chunk []T) error {
+		changes := make([]change, len(chunk))
+		for i := range chunk {
+			changes[i] = change{after: Updated, new: &chunk[i]}
+		}
+		return r.m.derive(r.query()).Load(new(T)).index(changes)
+	})
+}
+
+// searchIndexOf returns the index of a Searchable model.
+func searchIndexOf(s *schema.Schema) (search.Index, error) {
+	if len(s.PrimaryFields) != 1 {
+		return search.Index{}, fmt.Errorf("cannot index %s, it must have exactly one primary key", s.Name)
+	}
+	searchable := reflect.New(s.ModelType).Interface().(Searchable)
+	return search.Index{
+		Name:       searchable.SearchIndex(),
+		PrimaryKey: s.PrioritizedPrimaryField.DBName,
+		Filterable: searchable.FilterableAttributes(),
+		Sortable:   searchable.SortableAttributes(),
+	}, nil
+}
+
+// index queues the index updates of the changes of a write on a Searchable model.
+// Inside a transaction the updates wait until the outermost transaction has been committed.
+func (m *model) index(changes []change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(m.tempData); err != nil {
+		return err
+	}
+	index, err := searchIndexOf(stmt.Schema)
+	if err != nil {
+		return err
+	}
+
+	ctx := m.db.Statement.Context
+	primary := stmt.Schema.PrioritizedPrimaryField
+	engine := m.search
+	var updates []func()
+	for _, c := range changes {
+		if c.after == Deleted || trashed(ctx, stmt.Schema, c.new) {
+			record := c.new
+			if record == nil {
+				record = c.old
+			}
+			id := recordID(ctx, stmt.Schema, record)
+			updates = append(updates, func() { engine.Delete(index, id) })
+			continue
+		}
+
+		id := recordID(ctx, stmt.Schema, c.new)
+		document := c.new.(Searchable).SearchDocument()
+		if document == nil {
+			document = map[string]interface{}{}
+		}
+		document[primary.DBName], _ = primary.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(c.new)))
+		updates = append(updates, func() { engine.Upsert(index, id, document) })
+	}
+
+	if m.pendingSearch != nil {
+		*m.pendingSearch = append(*m.pendingSearch, updates...)
+		return nil
+	}
+	for _, update := range updates {
+		update()
+	}
+	return nil
+}
+
+// trashed reports whether the given record is soft deleted.
+func trashed(ctx context.Context, s *schema.Schema, record interface{}) bool {
+	if record == nil {
+		return false
+	}
+	for _, field := range s.Fields {
+		if field.FieldType == deletedAtType {
+			value, _ := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(record)))
+			return value.(gorm.DeletedAt).Valid
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+func init() {
+	Register(&SeederRun{})
+}
+
+// SeederRun is the record stored in the seeders_ran table for every seeder declared to run once,
+// such as the seeders of production reference data. The unique index on Seeder makes a second, concurrent run
+// of the same seeder fail instead of inserting its data twice.
+type SeederRun struct {
+	ID          uint      `gorm:"primaryKey"`
+	Seeder      string    `gorm:"size:255;uniqueIndex"`
+	Environment string    `gorm:"size:64"`
+	RanAt       time.Time `gorm:"not null"`
+}
+
+// TableName returns the name of the table used to record the seeders that ran once.
+func (SeederRun) TableName() string {
+	return "seeders_ran"
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// softDeleteColumn returns the column name of the gorm.DeletedAt field of the given model, if it has one.
+func softDeleteColumn(db *gorm.DB, value interface{}) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(value); err != nil {
+		return "", false
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.FieldType == deletedAtType && field.DBName != "" {
+			return field.DBName, true
+		}
+	}
+	return "", false
+}
+
+// trashedCondition returns the condition that matches the soft deleted records of the given model.
+func trashedCondition(db *gorm.DB, value interface{}) clause.Expression {
+	column, ok := softDeleteColumn(db, value)
+	if !ok {
+		return clause.Expr{SQL: "1 = 0"}
+	}
+	return clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: nil}
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// trashPost is a soft deletable model of the soft delete tests, trashTag is not soft deletable.
+type trashPost struct {
+	gorm.Model
+	Title string
+}
+
+type trashTag struct {
+	ID   uint
+	Name string
+}
+
+// seedTrash creates the posts "live" and "trashed", the second one soft deleted.
+func seedTrash(t *testing.T, posts *Repository[trashPost]) (live trashPost, trashed trashPost) {
+	t.Helper()
+	live, trashed = trashPost{Title: "live"}, trashPost{Title: "trashed"}
+	for _, post := range []*trashPost{&live, &trashed} {
+		if err := posts.Create(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := posts.Delete(&trashed); err != nil {
+		t.Fatal(err)
+	}
+	return live, trashed
+}
+
+func TestSoftDeleteScopes(t *testing.T) {
+	posts := NewRepository[trashPost](NewModel(testdb.Open(t, &trashPost{})))
+	seedTrash(t, posts)
+
+	tests := []struct {
+		name       string
+		repository *Repository[trashPost]
+		wantTitles []string
+	}{
+		{"default scope hides trashed records", posts, []string{"live"}},
+		{"with trashed", posts.WithTrashed(), []string{"live", "trashed"}},
+		{"only trashed", posts.OnlyTrashed(), []string{"trashed"}},
+		{"only trashed with conditions", posts.OnlyTrashed().Where("title = ?", "live"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := tt.repository.Order("id").All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != len(tt.wantTitles) {
+				t.Fatalf("found %d posts, want %v", len(found), tt.wantTitles)
+			}
+			for i, post := range found {
+				if post.Title != tt.wantTitles[i] {
+					t.Errorf("post %d = %q, want %q", i, post.Title, tt.wantTitles[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreAndForceDelete(t *testing.T) {
+	posts := NewRepository[trashPost](NewModel(testdb.Open(t, &trashPost{})))
+	live, trashed := seedTrash(t, posts)
+
+	if err := posts.Restore(&trashed); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := posts.Count(); count != 2 {
+		t.Errorf("%d posts after Restore, want 2", count)
+	}
+	if count, _ := posts.OnlyTrashed().Count(); count != 0 {
+		t.Errorf("%d trashed posts after Restore, want 0", count)
+	}
+
+	if err := posts.ForceDelete(&live); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := posts.WithTrashed().Find(live.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Find with trashed after ForceDelete = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if count, _ := posts.WithTrashed().Count(); count != 1 {
+		t.Errorf("%d posts after ForceDelete, want 1", count)
+	}
+}
+
+func TestSoftDeleteUnsupported(t *testing.T) {
+	m := NewModel(testdb.Open(t, &trashTag{}))
+	tags := NewRepository[trashTag](m)
+	tag := trashTag{Name: "go"}
+	if err := tags.Create(&tag); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tags.Restore(&tag); err == nil {
+		t.Error("Restore of a model without soft deletes succeeded")
+	}
+	if count, err := tags.OnlyTrashed().Count(); err != nil || count != 0 {
+		t.Errorf("OnlyTrashed Count = %d, %v, want 0", count, err)
+	}
+	if err := tags.Delete(&tag); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := tags.WithTrashed().Count(); count != 0 {
+		t.Errorf("Delete of a model without soft deletes left %d records", count)
+	}
+}
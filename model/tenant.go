@@ -0,0 +1,325 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrMissingTenant is returned by every query on a TenantScoped model that runs without a tenant,
+// so forgetting to pass the tenant of the request fails loudly instead of reading the data of every tenant.
+var ErrMissingTenant = errors.New("query on a tenant scoped model without a tenant, use WithContext, ForTenant or WithoutTenant")
+
+// ErrTenantMismatch is returned when a TenantScoped record of another tenant is written,
+// or when an update tries to move a record to another tenant.
+var ErrTenantMismatch = errors.New("the record belongs to another tenant")
+
+// ErrTenantUpsert is returned for upserts of TenantScoped models on MySQL, where ON DUPLICATE KEY UPDATE cannot be
+// limited to the records of the current tenant. Run the upsert with WithoutTenant after checking the records yourself.
+var ErrTenantUpsert = errors.New("upserts of tenant scoped models are not supported on mysql")
+
+// TenantScoped scopes a model to the tenant of the current request when embedded in it, and adds a tenant_id column.
+// Every query, update and delete on the model through the model wrapper, a Repository or an eager load is limited to the
+// records of the current tenant, and created records are assigned to it. A query that runs without a tenant returns
+// ErrMissingTenant, so the records of other tenants can never be read by accident. Pass the tenant resolved by the
+// Tenant middleware with WithContext, set it explicitly with ForTenant, or bypass the scope with WithoutTenant.
+// Raw SQL run with Execute and conditions on joined tables are not scoped.
+//
+// Example usage:
+//
+//	type Project struct {
+//		gorm.Model
+//		model.TenantScoped
+//		Name string
+//	}
+//
+//	projects, err := model.NewRepository[model.Project](s.Model).WithContext(c.Request.Context()).All()
+type TenantScoped struct {
+	TenantID uint `gorm:"index;not null" json:"tenant_id"`
+}
+
+// tenantScoped marks the models that embed TenantScoped.
+func (TenantScoped) tenantScoped() {}
+
+// tenantScopedModel is implemented by every model that embeds TenantScoped.
+type tenantScopedModel interface {
+	tenantScoped()
+}
+
+var tenantScopedType = reflect.TypeOf((*tenantScopedModel)(nil)).Elem()
+
+// tenantColumn is the column of the TenantScoped.TenantID field.
+const tenantColumn = "tenant_id"
+
+// tenantContextKey carries the id of the current tenant in a context.
+type tenantContextKey struct{}
+
+// withoutTenantContextKey marks a context whose queries bypass the tenant scope.
+type withoutTenantContextKey struct{}
+
+// WithTenant returns a copy of the given context that carries the given tenant.
+// The Tenant middleware stores the tenant of the request this way.
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant carried by the given context, and false if there is none.
+func TenantFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(tenantContextKey{}).(uint)
+	return tenantID, ok
+}
+
+// withoutTenant reports whether the given context was marked by WithoutTenant.
+func withoutTenant(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(withoutTenantContextKey{}).(bool)
+	return bypass
+}
+
+// WithContext returns a model whose queries run with the given context, usually the context of the current request.
+// Queries on TenantScoped models are scoped to the tenant it carries, and are cancelled together with it.
+// It returns a new model and leaves the receiver untouched, so it can be called on the shared application model.
+//
+// Example usage:
+//
+//	err := s.Model.WithContext(c.Request.Context()).Load(&projects).Get()
+func (m *model) WithContext(ctx context.Context) *model {
+	return m.derive(m.db.WithContext(ctx))
+}
+
+// ForTenant returns a model whose queries on TenantScoped models are scoped to the given tenant,
+// for code that runs outside of a request, such as cron jobs and seeders.
+// It returns a new model and leaves the receiver untouched.
+func (m *model) ForTenant(tenantID uint) *model {
+	return m.derive(m.db.WithContext(WithTenant(m.db.Statement.Context, tenantID)))
+}
+
+// WithoutTenant returns a model whose queries on TenantScoped models see and write the records of every tenant.
+// It is the only way to bypass the tenant scope, so a search for WithoutTenant lists every cross-tenant access.
+// It returns a new model and leaves the receiver untouched.
+func (m *model) WithoutTenant() *model {
+	return m.derive(m.db.WithContext(context.WithValue(m.db.Statement.Context, withoutTenantContextKey{}, true)))
+}
+
+// WithContext runs the queries of the repository with the given context and returns a new Repository.
+// See model.WithContext.
+func (r *Repository[T]) WithContext(ctx context.Context) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.WithContext(ctx) })
+}
+
+// ForTenant scopes the queries of the repository to the given tenant and returns a new Repository.
+// See model.ForTenant.
+func (r *Repository[T]) ForTenant(tenantID uint) *Repository[T] {
+	return r.chain(func(m *model) *model { return m.ForTenant(tenantID) })
+}
+
+// WithoutTenant bypasses the tenant scope for the queries of the repository and returns a new Repository.
+// See model.WithoutTenant.
+func (r *Repository[T]) WithoutTenant() *Repository[T] {
+	return r.chain(func(m *model) *model { return m.WithoutTenant() })
+}
+
+// tenancy is a GORM plugin that applies the tenant scope to the statements on TenantScoped models.
+type tenancy struct{}
+
+// Name returns the name of the plugin.
+func (tenancy) Name() string {
+	return "goapify:tenancy"
+}
+
+// Initialize registers the callbacks of the plugin.
+func (t tenancy) Initialize(db *gorm.DB) error {
+	// Creates run before any other callback, so the MERGE of SQL Server upserts is built with the tenant condition.
+	if err := db.Callback().Create().Before("*").Register("goapify:tenant_create", t.create); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("goapify:tenant_query", t.scope); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("goapify:tenant_row", t.scope); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("goapify:tenant_update", t.update); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("goapify:tenant_delete", t.delete)
+}
+
+// useTenancy registers the tenancy plugin on the given connection, once.
+func useTenancy(db *gorm.DB) {
+	if err := db.Use(tenancy{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		panic(fmt.Sprintf("cannot register the tenant scope: %v", err))
+	}
+}
+
+// tenant returns the tenant the statement is scoped to. It returns false when the statement is not on a
+// TenantScoped model or bypasses the scope, and adds ErrMissingTenant to the statement when there is no tenant.
+func (tenancy) tenant(db *gorm.DB) (uint, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return 0, false
+	}
+	if !reflect.PtrTo(stmt.Schema.ModelType).Implements(tenantScopedType) || withoutTenant(stmt.Context) {
+		return 0, false
+	}
+	tenantID, ok := TenantFromContext(stmt.Context)
+	if !ok {
+		db.AddError(ErrMissingTenant)
+		return 0, false
+	}
+	return tenantID, true
+}
+
+// scope limits a query to the records of the current tenant.
+func (t tenancy) scope(db *gorm.DB) {
+	if tenantID, ok := t.tenant(db); ok {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{tenantCondition(clause.CurrentTable, tenantID)}})
+	}
+}
+
+// create assigns the created records to the current tenant, refuses records of other tenants,
+// and limits the updates of an upsert to the records of the current tenant.
+func (t tenancy) create(db *gorm.DB) {
+	tenantID, ok := t.tenant(db)
+	if !ok {
+		return
+	}
+	if err := assignTenant(db.Statement.ReflectValue, tenantID); err != nil {
+		db.AddError(err)
+		return
+	}
+
+	onConflict, ok := db.Statement.Clauses["ON CONFLICT"].Expression.(clause.OnConflict)
+	if !ok || onConflict.DoNothing || (!onConflict.UpdateAll && len(onConflict.DoUpdates) == 0) {
+		return
+	}
+	switch db.Dialector.Name() {
+	case "mysql":
+		db.AddError(ErrTenantUpsert)
+		return
+	case "sqlserver":
+		// The MERGE of RegisterMerge honours the condition, the one of the driver only matches on the primary key without it.
+		if len(onConflict.Columns) == 0 {
+			for _, field := range db.Statement.Schema.PrimaryFields {
+				onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+			}
+		}
+	}
+	// A conflicting record of another tenant is left untouched instead of being overwritten.
+	onConflict.Where.Exprs = append(onConflict.Where.Exprs, tenantCondition(db.Statement.Table, tenantID))
+	db.Statement.AddClause(onConflict)
+}
+
+// update limits an update to the records of the current tenant and refuses to move records to another tenant.
+func (t tenancy) update(db *gorm.DB) {
+	tenantID, ok := t.tenant(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	var err error
+	switch values := stmt.Dest.(type) {
+	case map[string]interface{}:
+		err = checkTenantValue(values, tenantID)
+	case []map[string]interface{}:
+		for _, value := range values {
+			if err = checkTenantValue(value, tenantID); err != nil {
+				break
+			}
+		}
+	default:
+		err = assignTenant(reflect.ValueOf(stmt.Dest), tenantID)
+	}
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	if guardMissingWhere(db) {
+		t.scope(db)
+	}
+}
+
+// delete limits a delete to the records of the current tenant.
+func (t tenancy) delete(db *gorm.DB) {
+	if _, ok := t.tenant(db); ok && guardMissingWhere(db) {
+		t.scope(db)
+	}
+}
+
+// guardMissingWhere adds gorm.ErrMissingWhereClause to an update or delete that has no condition of its own.
+// GORM only refuses such statements when they have no WHERE clause at all, which the tenant scope would add,
+// so without the check an update or delete without conditions would change every record of the tenant.
+func guardMissingWhere(db *gorm.DB) bool {
+	stmt := db.Statement
+	if _, ok := stmt.Clauses["WHERE"]; ok || db.AllowGlobalUpdate {
+		return true
+	}
+	// GORM turns the primary key of the updated or deleted model into the condition.
+	values := []reflect.Value{stmt.ReflectValue}
+	if stmt.Model != nil {
+		values = append(values, reflect.ValueOf(stmt.Model))
+	}
+	for _, value := range values {
+		if _, keys := schema.GetIdentityFieldValuesMap(stmt.Context, value, stmt.Schema.PrimaryFields); len(keys) > 0 {
+			return true
+		}
+	}
+	db.AddError(gorm.ErrMissingWhereClause)
+	return false
+}
+
+// tenantCondition returns the condition that matches the records of the given tenant in the given table.
+func tenantCondition(table string, tenantID uint) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: tenantColumn}, Value: tenantID}
+}
+
+// assignTenant sets the tenant of the given struct, or slice of structs, when it is not set,
+// and returns ErrTenantMismatch when it is set to another tenant.
+func assignTenant(value reflect.Value, tenantID uint) error {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := assignTenant(value.Index(i), tenantID); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		field := value.FieldByName("TenantID")
+		if !field.IsValid() || field.Kind() != reflect.Uint {
+			return nil
+		}
+		if field.Uint() == 0 && field.CanSet() {
+			field.SetUint(uint64(tenantID))
+		} else if field.Uint() != uint64(tenantID) {
+			return ErrTenantMismatch
+		}
+	}
+	return nil
+}
+
+// checkTenantValue returns ErrTenantMismatch when the values of an update set the tenant to another one.
+func checkTenantValue(values map[string]interface{}, tenantID uint) error {
+	for _, key := range []string{tenantColumn, "TenantID"} {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if id := reflect.ValueOf(value); !id.CanUint() || id.Uint() != uint64(tenantID) {
+			return ErrTenantMismatch
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"GoAPIfy/internal/testdb"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// tenantProject is the TenantScoped model of the tenancy tests, tenantTask belongs to a project and is not scoped.
+type tenantProject struct {
+	gorm.Model
+	TenantScoped
+	Name  string       `gorm:"uniqueIndex"`
+	Tasks []tenantTask `gorm:"foreignKey:ProjectID"`
+}
+
+type tenantTask struct {
+	ID        uint
+	ProjectID uint
+	Title     string
+}
+
+// seedTenants creates the projects "a1" and "a2" of tenant 1 and "b1" of tenant 2, each with a task.
+func seedTenants(t *testing.T) *Repository[tenantProject] {
+	t.Helper()
+	projects := NewRepository[tenantProject](NewModel(testdb.Open(t, &tenantProject{}, &tenantTask{})))
+	for _, p := range []tenantProject{
+		{TenantScoped: TenantScoped{TenantID: 1}, Name: "a1", Tasks: []tenantTask{{Title: "a1 task"}}},
+		{TenantScoped: TenantScoped{TenantID: 1}, Name: "a2", Tasks: []tenantTask{{Title: "a2 task"}}},
+		{TenantScoped: TenantScoped{TenantID: 2}, Name: "b1", Tasks: []tenantTask{{Title: "b1 task"}}},
+	} {
+		if err := projects.WithoutTenant().Create(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return projects
+}
+
+// projectNames returns the names of every stored project with their tenant, as "a1@1".
+func projectNames(t *testing.T, projects *Repository[tenantProject]) string {
+	t.Helper()
+	all, err := projects.WithoutTenant().Order("name").All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range all {
+		names = append(names, fmt.Sprintf("%s@%d", p.Name, p.TenantID))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTenantQueries(t *testing.T) {
+	projects := seedTenants(t)
+	tests := []struct {
+		name       string
+		repository *Repository[tenantProject]
+		wantNames  string
+		wantErr    error
+	}{
+		{"without a tenant", projects, "", ErrMissingTenant},
+		{"tenant 1", projects.ForTenant(1), "a1,a2", nil},
+		{"tenant 2", projects.ForTenant(2), "b1", nil},
+		{"tenant of the context", projects.WithContext(WithTenant(context.Background(), 2)), "b1", nil},
+		{"conditions stay within the tenant", projects.ForTenant(1).Where("name = ?", "b1"), "", nil},
+		{"or conditions stay within the tenant", projects.ForTenant(2).Where("name = ?", "b1").Or("name = ?", "a1"), "b1", nil},
+		{"without tenant scope", projects.WithoutTenant(), "a1,a2,b1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := tt.repository.Order("name").All()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("All error = %v, want %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, p := range found {
+				names = append(names, p.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.wantNames {
+				t.Errorf("found %q, want %q", got, tt.wantNames)
+			}
+
+			count, err := tt.repository.Count()
+			if !errors.Is(err, tt.wantErr) || count != int64(len(found)) {
+				t.Errorf("Count = %d, %v, want %d, %v", count, err, len(found), tt.wantErr)
+			}
+		})
+	}
+
+	// The records of another tenant cannot be found by id, nor loaded through a relation.
+	if _, err := projects.ForTenant(1).Find(3); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Find of a project of another tenant = %v, want gorm.ErrRecordNotFound", err)
+	}
+	withTasks, err := projects.ForTenant(1).With("Tasks").All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, p := range withTasks {
+		for _, task := range p.Tasks {
+			titles = append(titles, task.Title)
+		}
+	}
+	sort.Strings(titles)
+	if got := strings.Join(titles, ","); got != "a1 task,a2 task" {
+		t.Errorf("eager loaded tasks = %q, want the ones of tenant 1", got)
+	}
+}
+
+func TestTenantWrites(t *testing.T) {
+	tests := []struct {
+		name      string
+		write     func(projects *Repository[tenantProject]) error
+		wantErr   error
+		wantNames string
+	}{
+		{
+			name: "create assigns the tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				return projects.ForTenant(2).Create(&tenantProject{Name: "b2"})
+			},
+			wantNames: "a1@1,a2@1,b1@2,b2@2",
+		},
+		{
+			name: "create for another tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				return projects.ForTenant(2).Create(&tenantProject{TenantScoped: TenantScoped{TenantID: 1}, Name: "a3"})
+			},
+			wantErr:   ErrTenantMismatch,
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+		{
+			name: "create without a tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				return projects.Create(&tenantProject{Name: "x"})
+			},
+			wantErr:   ErrMissingTenant,
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+		{
+			name: "update a record of another tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				b1, err := projects.ForTenant(2).Find(3)
+				if err != nil {
+					return err
+				}
+				b1.Name = "stolen"
+				return projects.ForTenant(1).Update(&b1)
+			},
+			wantErr:   ErrTenantMismatch,
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+		{
+			name: "move a record to another tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				_, err := projects.ForTenant(1).Where("name = ?", "a1").UpdateWhere(map[string]interface{}{"tenant_id": uint(2)})
+				return err
+			},
+			wantErr:   ErrTenantMismatch,
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+		{
+			name: "bulk update stays within the tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				_, err := projects.ForTenant(1).Where("name LIKE ?", "%1").UpdateWhere(map[string]interface{}{"name": gorm.Expr("name || 'x'")})
+				return err
+			},
+			wantNames: "a1x@1,a2@1,b1@2",
+		},
+		{
+			name: "bulk update without conditions",
+			write: func(projects *Repository[tenantProject]) error {
+				return projects.ForTenant(1).m.Load(&tenantProject{}).UpdateColumns(map[string]interface{}{"name": "all"})
+			},
+			wantErr:   gorm.ErrMissingWhereClause,
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+		{
+			name: "delete a record of another tenant",
+			write: func(projects *Repository[tenantProject]) error {
+				return projects.ForTenant(1).ForceDelete(&tenantProject{Model: gorm.Model{ID: 3}})
+			},
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+		{
+			name: "upsert leaves the records of other tenants untouched",
+			write: func(projects *Repository[tenantProject]) error {
+				_, err := projects.ForTenant(1).Upsert([]tenantProject{{Name: "a2"}, {Name: "b1"}}, []string{"name"}, []string{"updated_at"})
+				return err
+			},
+			wantNames: "a1@1,a2@1,b1@2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects := seedTenants(t)
+			if err := tt.write(projects); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := projectNames(t, projects); got != tt.wantNames {
+				t.Errorf("stored %q, want %q", got, tt.wantNames)
+			}
+		})
+	}
+}
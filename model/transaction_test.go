@@ -0,0 +1,221 @@
+package model
+
+import (
+	"GoAPIfy/core/cache"
+	"GoAPIfy/core/search"
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// txNote is the searchable model of the transaction tests.
+type txNote struct {
+	ID   uint
+	Body string
+}
+
+func (txNote) SearchIndex() string { return "tx_notes" }
+func (n txNote) SearchDocument() map[string]interface{} {
+	return map[string]interface{}{"body": n.Body}
+}
+func (txNote) FilterableAttributes() []string { return nil }
+func (txNote) SortableAttributes() []string   { return nil }
+
+var errTxTest = errors.New("rolled back by the test")
+
+// createNote returns a transaction step creating a note with the given body.
+func createNote(body string) func(tx Model) error {
+	return func(tx Model) error {
+		return NewRepository[txNote](tx).Create(&txNote{Body: body})
+	}
+}
+
+// storedNotes returns the bodies of the stored notes, sorted.
+func storedNotes(t *testing.T, m Model) string {
+	t.Helper()
+	notes, err := NewRepository[txNote](m).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	for _, note := range notes {
+		bodies = append(bodies, note.Body)
+	}
+	sort.Strings(bodies)
+	return strings.Join(bodies, ",")
+}
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        func(tx Model) error
+		wantErr   error
+		wantNotes string
+	}{
+		{
+			name:      "commit",
+			fn:        createNote("a"),
+			wantNotes: "a",
+		},
+		{
+			name: "rollback on error",
+			fn: func(tx Model) error {
+				if err := createNote("a")(tx); err != nil {
+					return err
+				}
+				return errTxTest
+			},
+			wantErr: errTxTest,
+		},
+		{
+			name: "failed savepoint only rolls back its own work",
+			fn: func(tx Model) error {
+				if err := createNote("outer")(tx); err != nil {
+					return err
+				}
+				err := tx.Transaction(func(inner Model) error {
+					if err := createNote("inner")(inner); err != nil {
+						return err
+					}
+					return errTxTest
+				})
+				if !errors.Is(err, errTxTest) {
+					return errors.New("the inner error was not returned")
+				}
+				return createNote("after")(tx)
+			},
+			wantNotes: "after,outer",
+		},
+		{
+			name: "nested savepoints keep the outer ones",
+			fn: func(tx Model) error {
+				return tx.Transaction(func(level2 Model) error {
+					if err := createNote("level2")(level2); err != nil {
+						return err
+					}
+					level2.Transaction(func(level3 Model) error {
+						if err := createNote("level3")(level3); err != nil {
+							return err
+						}
+						return errTxTest
+					})
+					return level2.Transaction(createNote("level3b"))
+				})
+			},
+			wantNotes: "level2,level3b",
+		},
+		{
+			name: "error of the outer function rolls back committed savepoints",
+			fn: func(tx Model) error {
+				if err := tx.Transaction(createNote("inner")); err != nil {
+					return err
+				}
+				return errTxTest
+			},
+			wantErr: errTxTest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModel(testdb.Open(t, &txNote{}))
+			if err := m.Transaction(tt.fn); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transaction error = %v, want %v", err, tt.wantErr)
+			}
+			if got := storedNotes(t, m); got != tt.wantNotes {
+				t.Errorf("stored notes = %q, want %q", got, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestTransactionPanic(t *testing.T) {
+	m := NewModel(testdb.Open(t, &txNote{}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic was not re-raised")
+			}
+		}()
+		m.Transaction(func(tx Model) error {
+			createNote("a")(tx)
+			panic("boom")
+		})
+	}()
+
+	// A panic inside a savepoint rolls back to it, the outer transaction goes on once it is recovered.
+	err := m.Transaction(func(tx Model) error {
+		createNote("outer")(tx)
+		func() {
+			defer func() { recover() }()
+			tx.Transaction(func(inner Model) error {
+				createNote("inner")(inner)
+				panic("boom")
+			})
+		}()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := storedNotes(t, m); got != "outer" {
+		t.Errorf("stored notes = %q, want %q", got, "outer")
+	}
+}
+
+// TestTransactionPendingUpdates makes sure the search updates and cache flushes queued inside a rolled back savepoint
+// are dropped, while the ones of the committed work are applied once the outer transaction commits.
+func TestTransactionPendingUpdates(t *testing.T) {
+	engine := search.NewMemory()
+	m := NewModel(testdb.Open(t, &txNote{})).WithSearch(engine).WithCache(cache.NewWithStore(cache.NewLRUStore(100)))
+	notes := NewRepository[txNote](m)
+	if _, err := notes.Cache(time.Minute).All(); err != nil {
+		t.Fatal(err)
+	}
+
+	var pendingTags int
+	err := m.Transaction(func(tx Model) error {
+		if err := createNote("kept")(tx); err != nil {
+			return err
+		}
+		pendingTags = len(*tx.(*model).pendingTags)
+		if pendingTags == 0 {
+			t.Fatal("creating a note queued no cache tag")
+		}
+		tx.Transaction(func(inner Model) error {
+			if err := createNote("dropped")(inner); err != nil {
+				return err
+			}
+			return errTxTest
+		})
+		if got := len(*tx.(*model).pendingTags); got != pendingTags {
+			t.Errorf("%d cache tags pending after the rolled back savepoint, want %d", got, pendingTags)
+		}
+		// Nothing is flushed or indexed before the commit.
+		if ids, _, _ := engine.Search(search.Query{Index: search.Index{Name: "tx_notes"}}); len(ids) != 0 {
+			t.Errorf("indexed %v before the commit", ids)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, _, err := engine.Search(search.Query{Index: search.Index{Name: "tx_notes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("indexed documents %v, want only the kept note 1", ids)
+	}
+	all, err := notes.Cache(time.Minute).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("the cached query returned %d notes after the commit, want the fresh 1", len(all))
+	}
+}
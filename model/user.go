@@ -6,13 +6,18 @@ import (
 	"gorm.io/gorm"
 )
 
-// User is the model representing a user
+func init() {
+	Register(&User{}, &EmailVerification{})
+}
+
+// User is the model representing a user.
+// The anonymise tags replace the personal data of users in `apify db:export --anonymise` snapshots.
 type User struct {
 	gorm.Model
-	Name       string
-	Email      string
+	Name       string `anonymise:"name"`
+	Email      string `anonymise:"email"`
 	Password   string
-	AvatarPath *string
+	AvatarPath *string `anonymise:"null"`
 	VerifiedAt *time.Time
 }
 
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleModel is returned by Save when a Versioned model has been changed by someone else since it was loaded.
+// Check for it with errors.Is; core.SendError answers it with 409 Conflict.
+var ErrStaleModel = errors.New("the record has been modified by someone else, reload it and try again")
+
+// StaleModelError is the error returned by Save for a stale Versioned model. It matches ErrStaleModel with errors.Is.
+type StaleModelError struct {
+	Table   string
+	ID      interface{}
+	Version uint
+}
+
+// Error returns the message of the error.
+func (e *StaleModelError) Error() string {
+	return fmt.Sprintf("%s %v is no longer at version %d: %s", e.Table, e.ID, e.Version, ErrStaleModel)
+}
+
+// Is reports whether the target is ErrStaleModel.
+func (e *StaleModelError) Is(target error) bool {
+	return target == ErrStaleModel
+}
+
+// StatusCode returns the HTTP status code the error is answered with.
+func (e *StaleModelError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// Versioned enables optimistic locking for a model when embedded in it, and adds a version column.
+// Save then only updates the record when its version is still the one that was loaded, increments the version,
+// and returns a StaleModelError when someone else saved the record in between, instead of silently overwriting it.
+// UpdateColumns and UpdateWhere increment the version as well, so they invalidate copies loaded by others.
+// To let clients take part in the check, copy the version they sent in the If-Match header onto the model before saving.
+//
+// Example usage:
+//
+//	type Article struct {
+//		gorm.Model
+//		model.Versioned
+//		Title string
+//	}
+//
+//	if version, ok, err := core.IfMatchVersion(c); err == nil && ok {
+//		article.Version = version
+//	}
+//	err := s.Model.Load(&article).Save() // errors.Is(err, model.ErrStaleModel) when the version is outdated
+type Versioned struct {
+	Version uint `gorm:"not null;default:1" json:"version"`
+}
+
+// versioned returns the embedded Versioned of a model.
+func (v *Versioned) versioned() *Versioned {
+	return v
+}
+
+// versionedModel is implemented by every model that embeds Versioned.
+type versionedModel interface {
+	versioned() *Versioned
+}
+
+// versionColumn is the column of the Versioned.Version field.
+const versionColumn = "version"
+
+// isVersioned reports whether the entities of the given value embed Versioned.
+func isVersioned(value interface{}) bool {
+	modelType := entityType(value)
+	if modelType == nil {
+		return false
+	}
+	_, ok := reflect.New(modelType).Interface().(versionedModel)
+	return ok
+}
+
+// save saves the loaded entities, with optimistic locking for Versioned models.
+func (m *model) save() error {
+	if !isVersioned(m.tempData) {
+		return m.db.Save(m.tempData).Error
+	}
+
+	entities := m.loadedEntities()
+	if len(entities) == 1 {
+		return m.saveVersioned(m.db, entities[0].Interface())
+	}
+	// Every entity of a slice is checked on its own, a stale one rolls back the others.
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, entity := range entities {
+			if err := m.saveVersioned(tx, entity.Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// saveVersioned inserts a new Versioned entity, or updates it only if its version is unchanged and increments the version.
+func (m *model) saveVersioned(db *gorm.DB, entity interface{}) error {
+	version := entity.(versionedModel).versioned()
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		return err
+	}
+	conditions, ok := m.primaryKeyConditions(stmt.Schema, reflect.ValueOf(entity))
+	if !ok {
+		return db.Session(&gorm.Session{NewDB: true}).Create(entity).Error
+	}
+
+	expected := version.Version
+	version.Version++
+	result := db.Session(&gorm.Session{NewDB: true}).Model(entity).
+		Where(clause.And(conditions...)).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: versionColumn}, Value: expected}).
+		Select("*").Updates(entity)
+	if result.Error == nil && result.RowsAffected == 0 {
+		version.Version = expected
+		stale := &StaleModelError{Table: stmt.Schema.Table, Version: expected}
+		if primary := stmt.Schema.PrioritizedPrimaryField; primary != nil {
+			stale.ID, _ = primary.ValueOf(db.Statement.Context, reflect.ValueOf(entity).Elem())
+		}
+		return stale
+	}
+	if result.Error != nil {
+		version.Version = expected
+	}
+	return result.Error
+}
+
+// versionedValues returns the values of a batch update with the increment of the version added for Versioned models.
+func (m *model) versionedValues(values map[string]interface{}) map[string]interface{} {
+	if !isVersioned(m.tempData) {
+		return values
+	}
+	if _, ok := values[versionColumn]; ok {
+		return values
+	}
+
+	incremented := make(map[string]interface{}, len(values)+1)
+	for column, value := range values {
+		incremented[column] = value
+	}
+	incremented[versionColumn] = gorm.Expr(m.db.Statement.Quote(versionColumn) + " + 1")
+	return incremented
+}
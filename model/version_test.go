@@ -0,0 +1,135 @@
+package model
+
+import (
+	"GoAPIfy/core"
+	"GoAPIfy/internal/testdb"
+	"errors"
+	"net/http"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// versionDoc is the Versioned model of the optimistic locking tests.
+type versionDoc struct {
+	gorm.Model
+	Versioned
+	Title string
+}
+
+func TestVersionedSave(t *testing.T) {
+	tests := []struct {
+		name string
+		// write changes the document loaded by someone else before the stale copy is saved, if at all.
+		write       func(docs *Repository[versionDoc], doc versionDoc) error
+		wantErr     error
+		wantTitle   string
+		wantVersion uint
+	}{
+		{
+			name:        "unchanged record",
+			write:       func(docs *Repository[versionDoc], doc versionDoc) error { return nil },
+			wantTitle:   "mine",
+			wantVersion: 2,
+		},
+		{
+			name: "saved by someone else",
+			write: func(docs *Repository[versionDoc], doc versionDoc) error {
+				doc.Title = "theirs"
+				return docs.Update(&doc)
+			},
+			wantErr:     ErrStaleModel,
+			wantTitle:   "theirs",
+			wantVersion: 2,
+		},
+		{
+			name: "columns updated by someone else",
+			write: func(docs *Repository[versionDoc], doc versionDoc) error {
+				return docs.entity(&doc).UpdateColumns(map[string]interface{}{"title": "theirs"})
+			},
+			wantErr:     ErrStaleModel,
+			wantTitle:   "theirs",
+			wantVersion: 2,
+		},
+		{
+			name: "updated in bulk by someone else",
+			write: func(docs *Repository[versionDoc], doc versionDoc) error {
+				_, err := docs.Where("id = ?", doc.ID).UpdateWhere(map[string]interface{}{"title": "theirs"})
+				return err
+			},
+			wantErr:     ErrStaleModel,
+			wantTitle:   "theirs",
+			wantVersion: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := NewRepository[versionDoc](NewModel(testdb.Open(t, &versionDoc{})))
+			doc := versionDoc{Title: "draft"}
+			if err := docs.Create(&doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.Version != 1 {
+				t.Fatalf("version of a new record = %d, want 1", doc.Version)
+			}
+			if err := tt.write(docs, doc); err != nil {
+				t.Fatal(err)
+			}
+
+			doc.Title = "mine"
+			err := docs.Update(&doc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var stale *StaleModelError
+				if !errors.As(err, &stale) || stale.Table != "version_docs" || stale.ID != doc.ID || stale.Version != 1 {
+					t.Errorf("error = %#v, want a StaleModelError of version_docs %d at version 1", err, doc.ID)
+				}
+				if doc.Version != 1 {
+					t.Errorf("version of the stale copy = %d, want it left at 1", doc.Version)
+				}
+				if status := core.ErrorStatus(err); status != http.StatusConflict {
+					t.Errorf("status = %d, want %d", status, http.StatusConflict)
+				}
+			}
+
+			stored, err := docs.Find(doc.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Title != tt.wantTitle || stored.Version != tt.wantVersion {
+				t.Errorf("stored %q at version %d, want %q at version %d", stored.Title, stored.Version, tt.wantTitle, tt.wantVersion)
+			}
+		})
+	}
+}
+
+// TestVersionedSaveSlice makes sure a stale entity of a saved slice rolls back the other ones.
+func TestVersionedSaveSlice(t *testing.T) {
+	m := NewModel(testdb.Open(t, &versionDoc{}))
+	docs := NewRepository[versionDoc](m)
+	loaded := []versionDoc{{Title: "a"}, {Title: "b"}}
+	if err := m.Load(&loaded).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	theirs := loaded[1]
+	theirs.Title = "theirs"
+	if err := docs.Update(&theirs); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded[0].Title, loaded[1].Title = "a2", "b2"
+	if err := m.Load(&loaded).Save(); !errors.Is(err, ErrStaleModel) {
+		t.Fatalf("Save error = %v, want ErrStaleModel", err)
+	}
+	stored, err := docs.Order("id").All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored[0].Title != "a" || stored[0].Version != 1 || stored[1].Title != "theirs" {
+		t.Errorf("stored %q v%d and %q, want the first one untouched", stored[0].Title, stored[0].Version, stored[1].Title)
+	}
+}
@@ -0,0 +1,27 @@
+// Package observer registers the lifecycle listeners of the application models.
+// Listeners keep side effects such as reindexing, cache busting, sending mail and writing audit rows out of the model structs.
+package observer
+
+import (
+	"GoAPIfy/service/appService"
+)
+
+// RegisterObservers registers the model lifecycle listeners of the application.
+// It is called once at startup, before any request is served.
+//
+// Example usage:
+//
+//	model.Observe(model.Updating, func(e *model.Event[model.User]) error {
+//		if e.Old.Email != e.New.Email {
+//			e.New.VerifiedAt = nil
+//		}
+//		return nil
+//	})
+//
+//	model.Observe(model.Deleted, func(e *model.Event[model.User]) error {
+//		return e.Model.Load(&model.EmailVerification{}).Where("user_id = ?", e.Old.ID).Delete()
+//	})
+func RegisterObservers(s appService.AppService) {
+
+	// Register your observers here...
+}
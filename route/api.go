@@ -39,8 +39,29 @@ func API(server *gin.Engine, s appService.AppService) {
 	// Define user group for routes that requires authentication
 	userModGroup := userGroup.Group("/auth")
 
-	// Use authentication middleware for routes that require authentication
-	userModGroup.Use(middleware.Authentication(authService, s))
+	// Use authentication middleware for routes that require authentication, and record the acting user on audits
+	userModGroup.Use(middleware.Authentication(authService, s), middleware.Audit())
+
+	// Admin endpoints expose data of every user, so they are restricted to the users listed in ADMIN_USER_IDS
+	adminGroup := api.Group("/admin")
+	adminGroup.Use(middleware.Authentication(authService, s), middleware.Admin(), middleware.Audit())
+
+	// The user list is filtered, sorted and paginated from the query string, see controller/user/query.go
+	adminGroup.GET("/users", h.UserHandler.Index)
+
+	// Trash endpoints list, restore and purge soft deleted records of the entities registered in controller/trash
+	adminGroup.GET("/trash/:entity", h.TrashHandler.Index)
+	adminGroup.PATCH("/trash/:entity/:id/restore", h.TrashHandler.Restore)
+	adminGroup.DELETE("/trash/:entity/:id", h.TrashHandler.ForceDelete)
+
+	// Routes of tenant scoped models resolve the tenant of the request first, then run their queries with
+	// s.Model.WithContext(c.Request.Context()) so they only see the records of that tenant:
+	//
+	// tenantGroup := api.Group("/")
+	// tenantGroup.Use(middleware.Authentication(authService, s), middleware.Audit(), middleware.Tenant(middleware.TenantIDLookup,
+	// 	middleware.TenantClaimAuthorizer(authService, "tenant_id", middleware.TenantIDLookup),
+	// 	middleware.TenantFromClaim(authService, "tenant_id"),
+	// ))
 
 	// Add more routes as needed
 
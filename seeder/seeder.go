@@ -1,28 +1,209 @@
+// Package seeder fills the database with the data the application needs, such as reference data for every
+// environment or fake data for development. Every seeder lives in its own file and registers itself by name from
+// an init function, and runs with `apify db:seed`, after the seeders it depends on and only in the environments
+// it is enabled in. Seeders never run when the server starts.
 package seeder
 
 import (
-	"GoAPIfy/factory"
 	"GoAPIfy/model"
 	"GoAPIfy/service/appService"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func RegisterSeeders(s appService.AppService) {
+// ErrUnknownSeeder is returned by Run when a seeder, or one of its dependencies, is not registered.
+var ErrUnknownSeeder = errors.New("unknown seeder")
 
-	// Check if the User model has any data in the database
-	if count, err := s.Model.Load(&model.User{}).Count(); err != nil {
-		panic(err)
-	} else if count == 0 {
-		// Create a new instance of your UserFactory
-		userFactory := &factory.UserFactory{}
+// Seeder describes a named seeder.
+// DependsOn lists the names of the seeders that must run before it, which Run adds when they were not selected.
+// Environments lists the environments the seeder is enabled in, such as "development" or "production",
+// every environment when empty, see Environment.
+// A seeder declared Once is recorded in the seeders_ran table and never runs again in that database,
+// which suits reference data; the other seeders run on every call and should check for their own data first.
+// Run receives an AppService whose Model belongs to the transaction of the seeder.
+//
+// Example usage:
+//
+//	func init() {
+//		Register(&Seeder{
+//			Name:         "CountrySeeder",
+//			Environments: []string{"development", "production"},
+//			Once:         true,
+//			Run: func(s appService.AppService) error {
+//				countries := []model.Country{{Code: "ID", Name: "Indonesia"}, {Code: "NL", Name: "Netherlands"}}
+//				_, err := model.NewRepository[model.Country](s.Model).CreateInBatches(countries, 100)
+//				return err
+//			},
+//		})
+//	}
+type Seeder struct {
+	Name         string
+	DependsOn    []string
+	Environments []string
+	Once         bool
+	Run          func(s appService.AppService) error
+}
 
-		// Create a new instance of your UserSeeder
-		userSeeder := NewUserSeeder(s, userFactory)
+// seeders holds every seeder registered by the files in this package, by lower case name.
+var seeders = map[string]*Seeder{}
 
-		// Call the Seed function on your UserSeeder to seed the database with 10 User models
-		if err := userSeeder.Seed(10); err != nil {
-			panic(err)
+// Register adds a seeder to the registry. It is called from the init function of each seeder file.
+// It panics if the seeder has no name or Run function, or if its name is already registered,
+// since that is always a programming error.
+func Register(s *Seeder) {
+	if s.Name == "" || s.Run == nil {
+		panic("seeder: a seeder needs a Name and a Run function")
+	}
+	key := strings.ToLower(s.Name)
+	if _, exists := seeders[key]; exists {
+		panic(fmt.Sprintf("seeder: duplicate seeder name %q", s.Name))
+	}
+	seeders[key] = s
+}
+
+// All returns every registered seeder, sorted by name.
+func All() []*Seeder {
+	all := make([]*Seeder, 0, len(seeders))
+	for _, s := range seeders {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Environment returns the name of the environment the application runs in: APP_ENV when it is set,
+// otherwise "production" when APP_PRODUCTION is true and "development" when it is not.
+func Environment() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return strings.ToLower(env)
+	}
+	if production, _ := strconv.ParseBool(os.Getenv("APP_PRODUCTION")); production {
+		return "production"
+	}
+	return "development"
+}
+
+// enabledIn reports whether the seeder is enabled in the given environment.
+func (s *Seeder) enabledIn(env string) bool {
+	if len(s.Environments) == 0 {
+		return true
+	}
+	for _, e := range s.Environments {
+		if strings.EqualFold(e, env) {
+			return true
 		}
 	}
+	return false
+}
+
+// Run runs the named seeders, or every seeder enabled in the environment when no name is given, together with
+// their dependencies, each one after the seeders it depends on. Every seeder runs in its own transaction, so a failing
+// seeder leaves no partial data, and the seeders declared Once are skipped when the seeders_ran table records them.
+// A named seeder, or a dependency, that is not enabled in the environment makes Run fail before anything runs.
+// It returns the names of the seeders that ran, up to the one that failed.
+//
+// Example usage:
+//
+//	ran, err := seeder.Run(appService, seeder.Environment(), "UserSeeder")
+func Run(s appService.AppService, env string, names ...string) ([]string, error) {
+	ordered, err := resolve(env, names)
+	if err != nil {
+		return nil, err
+	}
 
-	// Repeat the above process for each of your other seeders...
+	var ran []string
+	for _, sd := range ordered {
+		if sd.Once {
+			done, err := model.NewRepository[model.SeederRun](s.Model).Where("seeder = ?", sd.Name).Exists()
+			if err != nil {
+				return ran, err
+			}
+			if done {
+				continue
+			}
+		}
+
+		err := s.Model.Transaction(func(tx model.Model) error {
+			txService := s
+			txService.Model = tx
+			if err := sd.Run(txService); err != nil {
+				return err
+			}
+			if !sd.Once {
+				return nil
+			}
+			return model.NewRepository[model.SeederRun](tx).Create(&model.SeederRun{Seeder: sd.Name, Environment: env, RanAt: time.Now()})
+		})
+		if err != nil {
+			return ran, fmt.Errorf("seeder %s failed: %w", sd.Name, err)
+		}
+		ran = append(ran, sd.Name)
+	}
+	return ran, nil
+}
+
+// resolve returns the named seeders, or every seeder enabled in the environment, with their dependencies,
+// ordered so that every seeder comes after the seeders it depends on.
+func resolve(env string, names []string) ([]*Seeder, error) {
+	if len(names) == 0 {
+		for _, s := range All() {
+			if s.enabledIn(env) {
+				names = append(names, s.Name)
+			}
+		}
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[*Seeder]int{}
+	var ordered []*Seeder
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		s, ok := seeders[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("%w %q", ErrUnknownSeeder, name)
+		}
+		switch state[s] {
+		case visiting:
+			return fmt.Errorf("seeder dependency cycle: %s", strings.Join(append(path, s.Name), " -> "))
+		case visited:
+			return nil
+		}
+		if !s.enabledIn(env) {
+			return fmt.Errorf("seeder %s is not enabled in the %s environment", s.Name, env)
+		}
+
+		state[s] = visiting
+		for _, dependency := range s.DependsOn {
+			if err := visit(dependency, append(path, s.Name)); err != nil {
+				return err
+			}
+		}
+		state[s] = visited
+		ordered = append(ordered, s)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// RegisterSeeders runs every seeder enabled in the current environment and panics on error.
+//
+// Deprecated: seeders no longer run when the server starts, run them with `apify db:seed`, or with Run.
+func RegisterSeeders(s appService.AppService) {
+	if _, err := Run(s, Environment()); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,231 @@
+package seeder
+
+import (
+	"GoAPIfy/internal/testdb"
+	"GoAPIfy/model"
+	"GoAPIfy/service/appService"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// seededRow is a row written by the seeders of the tests, with the name of the seeder that wrote it.
+type seededRow struct {
+	ID     uint
+	Seeder string
+}
+
+// writeRow returns a Run function that writes a row for the named seeder.
+func writeRow(name string) func(s appService.AppService) error {
+	return func(s appService.AppService) error {
+		return model.NewRepository[seededRow](s.Model).Create(&seededRow{Seeder: name})
+	}
+}
+
+// useSeeders replaces the registered seeders with the given ones for the duration of the test.
+func useSeeders(t *testing.T, registered ...*Seeder) {
+	t.Helper()
+	saved := seeders
+	seeders = map[string]*Seeder{}
+	t.Cleanup(func() { seeders = saved })
+	for _, s := range registered {
+		Register(s)
+	}
+}
+
+// storedSeeders returns the names of the seeders of the stored rows, in the order they were written.
+func storedSeeders(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	var names []string
+	if err := db.Model(&seededRow{}).Order("id").Pluck("seeder", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEnvironment(t *testing.T) {
+	tests := []struct {
+		appEnv, production string
+		want               string
+	}{
+		{"", "", "development"},
+		{"", "false", "development"},
+		{"", "true", "production"},
+		{"", "invalid", "development"},
+		{"Staging", "true", "staging"},
+		{"testing", "", "testing"},
+	}
+	for _, tt := range tests {
+		t.Setenv("APP_ENV", tt.appEnv)
+		t.Setenv("APP_PRODUCTION", tt.production)
+		if got := Environment(); got != tt.want {
+			t.Errorf("Environment() with APP_ENV=%q and APP_PRODUCTION=%q = %q, want %q", tt.appEnv, tt.production, got, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	useSeeders(t, &Seeder{Name: "RoleSeeder", Run: writeRow("role")})
+	tests := []struct {
+		name   string
+		seeder *Seeder
+	}{
+		{"no name", &Seeder{Run: writeRow("x")}},
+		{"no run function", &Seeder{Name: "CountrySeeder"}},
+		{"duplicate name", &Seeder{Name: "roleseeder", Run: writeRow("x")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register did not panic")
+				}
+			}()
+			Register(tt.seeder)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	useSeeders(t,
+		&Seeder{Name: "RoleSeeder", Run: writeRow("role")},
+		&Seeder{Name: "UserSeeder", DependsOn: []string{"RoleSeeder"}, Environments: []string{"development", "testing"}, Run: writeRow("user")},
+		&Seeder{Name: "PostSeeder", DependsOn: []string{"UserSeeder", "roleseeder"}, Environments: []string{"Development"}, Run: writeRow("post")},
+		&Seeder{Name: "CountrySeeder", Environments: []string{"production"}, Run: writeRow("country")},
+		&Seeder{Name: "BrokenSeeder", DependsOn: []string{"MissingSeeder"}, Environments: []string{"broken"}, Run: writeRow("broken")},
+		&Seeder{Name: "ASeeder", DependsOn: []string{"BSeeder"}, Environments: []string{"cycle"}, Run: writeRow("a")},
+		&Seeder{Name: "BSeeder", DependsOn: []string{"ASeeder"}, Environments: []string{"cycle"}, Run: writeRow("b")},
+	)
+
+	tests := []struct {
+		name    string
+		env     string
+		names   []string
+		want    string
+		wantErr string
+	}{
+		{"every seeder of the environment", "development", nil, "RoleSeeder,UserSeeder,PostSeeder", ""},
+		{"every seeder of another environment", "production", nil, "CountrySeeder,RoleSeeder", ""},
+		{"named seeder with its dependencies", "development", []string{"postseeder"}, "RoleSeeder,UserSeeder,PostSeeder", ""},
+		{"dependency named first", "testing", []string{"RoleSeeder", "UserSeeder"}, "RoleSeeder,UserSeeder", ""},
+		{"seeder named twice", "testing", []string{"UserSeeder", "userseeder"}, "RoleSeeder,UserSeeder", ""},
+		{"seeder of another environment", "testing", []string{"PostSeeder"}, "", "seeder PostSeeder is not enabled in the testing environment"},
+		{"dependency of another environment", "production", []string{"UserSeeder"}, "", "seeder UserSeeder is not enabled in the production environment"},
+		{"unknown seeder", "development", []string{"TagSeeder"}, "", `unknown seeder "TagSeeder"`},
+		{"unknown dependency", "broken", nil, "", `unknown seeder "MissingSeeder"`},
+		{"dependency cycle", "cycle", []string{"ASeeder"}, "", "seeder dependency cycle: ASeeder -> BSeeder -> ASeeder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := resolve(tt.env, tt.names)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, s := range ordered {
+				names = append(names, s.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("resolved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name  string
+		env   string
+		names []string
+		// runs is the number of times Run is called.
+		runs       int
+		wantRan    string
+		wantStored string
+		wantOnce   string
+		wantErr    error
+	}{
+		{"every seeder", "testing", nil, 1, "CountrySeeder,RoleSeeder,UserSeeder", "country,role,user", "CountrySeeder", nil},
+		{"seeders that run again", "testing", nil, 2, "RoleSeeder,UserSeeder", "country,role,user,role,user", "CountrySeeder", nil},
+		{"seeder run once", "testing", []string{"CountrySeeder"}, 3, "", "country", "CountrySeeder", nil},
+		{"failing seeder", "development", []string{"FailingSeeder"}, 1, "RoleSeeder", "role", "", errFailed},
+		{"unknown seeder", "testing", []string{"TagSeeder"}, 1, "", "", "", ErrUnknownSeeder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSeeders(t,
+				&Seeder{Name: "CountrySeeder", Once: true, Run: writeRow("country")},
+				&Seeder{Name: "RoleSeeder", Run: writeRow("role")},
+				&Seeder{Name: "UserSeeder", DependsOn: []string{"RoleSeeder"}, Run: writeRow("user")},
+				// The failing seeder writes a row before it fails, which its transaction rolls back.
+				&Seeder{Name: "FailingSeeder", DependsOn: []string{"RoleSeeder"}, Environments: []string{"development"}, Run: func(s appService.AppService) error {
+					if err := writeRow("failing")(s); err != nil {
+						return err
+					}
+					return errFailed
+				}},
+			)
+			db := testdb.Open(t, &seededRow{}, &model.SeederRun{})
+			s := appService.AppService{Model: model.NewModel(db)}
+
+			var ran []string
+			var err error
+			for i := 0; i < tt.runs; i++ {
+				ran, err = Run(s, tt.env, tt.names...)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := strings.Join(ran, ","); got != tt.wantRan {
+				t.Errorf("last run ran %q, want %q", got, tt.wantRan)
+			}
+			if got := storedSeeders(t, db); got != tt.wantStored {
+				t.Errorf("stored rows of %q, want %q", got, tt.wantStored)
+			}
+			var once []model.SeederRun
+			if err := db.Order("id").Find(&once).Error; err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, r := range once {
+				if r.Environment != tt.env || r.RanAt.IsZero() {
+					t.Errorf("recorded run %+v, want the %s environment and a time", r, tt.env)
+				}
+				names = append(names, r.Seeder)
+			}
+			if got := strings.Join(names, ","); got != tt.wantOnce {
+				t.Errorf("seeders_ran holds %q, want %q", got, tt.wantOnce)
+			}
+		})
+	}
+}
+
+func TestUserSeeder(t *testing.T) {
+	db := testdb.Open(t, &model.User{})
+	s := appService.AppService{Model: model.NewModel(db)}
+
+	// The user seeder only fills an empty users table, so running it twice seeds 10 users.
+	for run := 0; run < 2; run++ {
+		ran, err := Run(s, "testing", "UserSeeder")
+		if err != nil || len(ran) != 1 {
+			t.Fatalf("Run = %v, %v, want the user seeder", ran, err)
+		}
+	}
+	var count int64
+	if err := db.Model(&model.User{}).Count(&count).Error; err != nil || count != 10 {
+		t.Errorf("%d users seeded (%v), want 10", count, err)
+	}
+	if _, err := Run(s, "production", "UserSeeder"); err == nil {
+		t.Error("the user seeder ran in production")
+	}
+}
@@ -1,37 +1,43 @@
-// Package seeder provides functionality to seed data in a database using factory and appService.
 package seeder
 
 import (
 	"GoAPIfy/factory"
+	"GoAPIfy/model"
 	"GoAPIfy/service/appService"
 )
 
-// UserSeeder struct holds references to appService and UserFactory.
+func init() {
+	Register(&Seeder{
+		Name:         "UserSeeder",
+		Environments: []string{"development", "testing"},
+		Run: func(s appService.AppService) error {
+			// Only seed an empty users table, so the seeder can run again safely
+			count, err := model.NewRepository[model.User](s.Model).Count()
+			if err != nil || count > 0 {
+				return err
+			}
+			return NewUserSeeder(s, factory.User).Seed(10)
+		},
+	})
+}
+
+// UserSeeder struct holds references to appService and the User factory.
 type UserSeeder struct {
 	AppService appService.AppService
-	Factory    *factory.UserFactory
+	Factory    *factory.Factory[model.User]
 }
 
 // NewUserSeeder creates a new instance of UserSeeder.
-func NewUserSeeder(s appService.AppService, f *factory.UserFactory) *UserSeeder {
+func NewUserSeeder(s appService.AppService, f *factory.Factory[model.User]) *UserSeeder {
 	return &UserSeeder{
 		AppService: s,
 		Factory:    f,
 	}
 }
 
-// Seed creates a specified number of user instances using UserFactory's Generate method and inserts them into the database
-// using AppService's Model.Create method.
+// Seed creates a specified number of users with the User factory and inserts them into the database
+// in batches using a model.Repository.
 func (s *UserSeeder) Seed(count int) error {
-	for i := 0; i < count; i++ {
-		user, err := s.Factory.Generate("password")
-		if err != nil {
-			panic(err)
-		}
-
-		if err := s.AppService.Model.Load(&user).Save(); err != nil {
-			return err
-		}
-	}
-	return nil
+	_, err := s.Factory.Create(s.AppService.Model, count)
+	return err
 }
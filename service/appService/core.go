@@ -2,17 +2,17 @@
 
 This module contains the services required for the GoAPIfy application.
 The AppService struct defines the type that encapsulates the application services,
-with a Model property that is of type model.Model, and a MeiliSearch property that is of type *meilisearch.Client.
+with a Model property that is of type model.Model, and a Search property that is of type search.Engine.
 These properties can be accessed and used by the various services of the application.
 */
 
 package appService
 
 import (
+	"GoAPIfy/core/search"
 	"GoAPIfy/model"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/meilisearch/meilisearch-go"
 )
 
 /*
@@ -21,10 +21,10 @@ import (
 	Defines a new type for application services.
 	@typedef {AppService} - The type that encapsulates the application services
 	@property {model.Model} Model - The model instance to be used in the application service
-	@property {meilisearch.Client} MeiliSearch - The MeiliSearch client instance to be used in the application service
+	@property {search.Engine} Search - The search engine selected by SEARCH_DRIVER to be used in the application service
 */
 type AppService struct {
-	Model       model.Model
-	MeiliSearch *meilisearch.Client
-	Redis       *redis.Client
+	Model  model.Model
+	Search search.Engine
+	Redis  *redis.Client
 }
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"GoAPIfy/core/backup"
+	"GoAPIfy/core/database"
+	"GoAPIfy/model"
+	"GoAPIfy/tools/core/color"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// backupConnection connects to the named database connection for db:export and db:import.
+func backupConnection(connection string) *gorm.DB {
+	production, err := strconv.ParseBool(os.Getenv("APP_PRODUCTION"))
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, "Error converting APP_PRODUCTION to boolean."))
+		os.Exit(1)
+	}
+
+	db, err := database.InitConnection(connection, production)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, fmt.Sprintf("Failed to connect to the %s database: %s", connection, err)))
+		os.Exit(1)
+	}
+	return db
+}
+
+// positional returns the arguments that are not options, the value of an option given as "--name value" included.
+func positional(args []string, options ...string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			values = append(values, arg)
+			continue
+		}
+		for _, option := range options {
+			if arg == "--"+option {
+				i++
+			}
+		}
+	}
+	return values
+}
+
+// DatabaseExport writes a snapshot of every registered model table of the main connection, or of the one given
+// with --connection, to the given directory or to backups/<timestamp>. With --anonymise the columns tagged
+// `anonymise` are replaced on export.
+func DatabaseExport(args []string) {
+	connection := connectionOrDefault(args)
+	dir := filepath.Join("backups", time.Now().Format("20060102150405"))
+	if paths := positional(args, "connection"); len(paths) > 0 {
+		dir = paths[0]
+	}
+	anonymise := false
+	for _, arg := range args {
+		anonymise = anonymise || arg == "--anonymise"
+	}
+
+	models := model.Registered(connection)
+	if len(models) == 0 {
+		fmt.Println(color.Colorize(color.Yellow, fmt.Sprintf("No registered models on the %s connection.", connection)))
+		os.Exit(0)
+	}
+
+	fmt.Println(color.Colorize(color.Green, fmt.Sprintf("Exporting the %s connection to %s...", connection, dir)))
+	manifest, err := backup.Export(backupConnection(connection), models, dir, backup.ExportOptions{Anonymise: anonymise})
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+	for _, table := range manifest.Tables {
+		line := fmt.Sprintf("Exported: %s (%d rows)", table.Name, table.Rows)
+		if len(table.Anonymised) > 0 {
+			line += fmt.Sprintf(", anonymised %s", strings.Join(table.Anonymised, ", "))
+		}
+		fmt.Println(color.Colorize(color.Green, line))
+	}
+	os.Exit(0)
+}
+
+// DatabaseImport restores the snapshot in the given directory into the main connection, or into the one given with
+// --connection, inserting --batch rows per statement. With --truncate the rows of the tables are replaced,
+// after asking for confirmation.
+func DatabaseImport(args []string) {
+	paths := positional(args, "connection", "batch")
+	if len(paths) == 0 {
+		fmt.Println(color.Colorize(color.Red, "Not enough arguments, give the directory of the snapshot."))
+		os.Exit(0)
+	}
+	connection := connectionOrDefault(args)
+	options := backup.ImportOptions{}
+	for _, arg := range args {
+		options.Truncate = options.Truncate || arg == "--truncate"
+	}
+	if batch := parseOption(args, "batch"); batch != "" {
+		size, err := strconv.Atoi(batch)
+		if err != nil || size < 1 {
+			fmt.Println(color.Colorize(color.Red, "--batch must be a positive number"))
+			os.Exit(1)
+		}
+		options.BatchSize = size
+	}
+
+	if options.Truncate {
+		if production, _ := strconv.ParseBool(os.Getenv("APP_PRODUCTION")); production {
+			fmt.Println(color.Colorize(color.Red, "APP_PRODUCTION is true, this will replace the data of the production database!"))
+		}
+		fmt.Println(color.Colorize(color.Yellow, fmt.Sprintf("This will delete the rows of every table of the snapshot on the %s connection. Continue? (y/n)", connection)))
+		var input string
+		fmt.Scanln(&input)
+		if input != "y" && input != "Y" {
+			os.Exit(0)
+		}
+	}
+
+	fmt.Println(color.Colorize(color.Green, fmt.Sprintf("Importing %s into the %s connection...", paths[0], connection)))
+	manifest, err := backup.Import(backupConnection(connection), paths[0], options)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+	for _, table := range manifest.Tables {
+		fmt.Println(color.Colorize(color.Green, fmt.Sprintf("Imported: %s (%d rows)", table.Name, table.Rows)))
+	}
+	os.Exit(0)
+}
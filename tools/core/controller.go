@@ -10,7 +10,7 @@ import (
 	"strings"
 )
 
-func Controller(p string) {
+func Controller(p string, id string) {
 	if containsWhitespace(p) || containsUppercase(p) || containsSymbol(p) || !containsOnlyLettersAndNumbers(p) {
 		fmt.Println(color.Colorize(color.Red, "Model name cannot contain whitespace, uppercase, and symbol!"))
 		os.Exit(0)
@@ -26,8 +26,9 @@ func Controller(p string) {
 	controllerName := stringable.Capitalize(p)
 
 	CreateHandler(p, controllerName)
-	CreateFormatter(p, controllerName)
+	CreateFormatter(p, controllerName, primaryKeys[id].idType)
 	CreateInput(p, controllerName)
+	CreateQuery(p, controllerName)
 
 	fmt.Println(color.Colorize(color.Magenta, fmt.Sprintf("%s controller created!", p)))
 }
@@ -67,7 +68,7 @@ func CreateHandler(p string, controllerName string) {
 
 }
 
-func CreateFormatter(p string, controllerName string) {
+func CreateFormatter(p string, controllerName string, idType string) {
 	cmd := exec.Command("go", "list", "-m")
 	output, err := cmd.Output()
 
@@ -86,6 +87,8 @@ func CreateFormatter(p string, controllerName string) {
 	modifiedBytes := []byte(strings.ReplaceAll(string(srcByte), "${controllerName}", controllerName))
 	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${controllerPackage}", p))
 	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${AppName}", appName))
+	// Pad the type of the ID to align the struct tags with the time.Time fields
+	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${idType}", fmt.Sprintf("%-9s", idType)))
 
 	out, err := os.Create(fmt.Sprintf("./controller/%s/formatter.go", p))
 	if err != nil {
@@ -129,3 +132,37 @@ func CreateInput(p string, controllerName string) {
 		os.Exit(0)
 	}
 }
+
+func CreateQuery(p string, controllerName string) {
+	cmd := exec.Command("go", "list", "-m")
+	output, err := cmd.Output()
+
+	appName := strings.TrimSpace(string(output))
+	src, err := os.Open("./tools/templates/controller/query.txt")
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, "GoAPIfy is corrupted, core files is missing!"))
+		os.Exit(0)
+	}
+	defer src.Close()
+	srcByte, err := ioutil.ReadAll(src)
+	if err != nil {
+		panic(err)
+	}
+
+	modifiedBytes := []byte(strings.ReplaceAll(string(srcByte), "${controllerName}", controllerName))
+	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${controllerPackage}", p))
+	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${AppName}", appName))
+
+	out, err := os.Create(fmt.Sprintf("./controller/%s/query.go", p))
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(0)
+	}
+	defer out.Close()
+
+	_, err = out.Write(modifiedBytes)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(0)
+	}
+}
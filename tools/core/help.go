@@ -23,9 +23,25 @@ func Help() {
 	fmt.Println(color.Colorize(color.Green, "     Command to compose up and down the docker for your development.\n"))
 	fmt.Println(color.Colorize(color.Magenta, "   rename"))
 	fmt.Println(color.Colorize(color.Green, "     Command to rename your application based from .env\n     (Please make sure you have changed your APP_NAME in .env).\n"))
-	fmt.Println(color.Colorize(color.Magenta, "   entity [name]"))
-	fmt.Println(color.Colorize(color.Green, "     Create a new entity.\n     Entity is a package of controller and model.\n     It creates controllers file (input, handlers, and formatter) and model.\n     (Entity only contain alphanumeric no symbols and capital letters).\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   entity [name] [--id=increment|uuid|ulid]"))
+	fmt.Println(color.Colorize(color.Green, "     Create a new entity.\n     Entity is a package of controller and model.\n     It creates controllers file (input, handlers, and formatter), model and its migration.\n     The model has an auto-increment ID by default, or a UUID or ULID string ID with --id.\n     (Entity only contain alphanumeric no symbols and capital letters).\n"))
 	fmt.Println(color.Colorize(color.Magenta, "   middleware [name]"))
 	fmt.Println(color.Colorize(color.Green, "     Create a new middleware.\n     Entity is a package of middleware and model.\n     It creates middleware file.\n     (Entity only contain alphanumeric no symbols and capital letters).\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   migration [name]"))
+	fmt.Println(color.Colorize(color.Green, "     Create a new empty migration in the migration folder.\n     (Name only contain lowercase letters, numbers and underscores, e.g. add_phone_to_users).\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   migrate [--connection=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Run every pending migration, on every connection or only on the given one.\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   migrate:rollback [--step=n] [--connection=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Rollback the last batch of migrations, or the last n migrations when --step is given,\n     on the main connection or on the given one.\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   migrate:status [--connection=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Show which migrations have been run and which are pending, on every connection or only on the given one.\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   migrate:fresh [--connection=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Drop all tables and run every migration again (development only!),\n     on the main connection or on the given one.\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   db:seed [--class=name] [--env=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Run every seeder enabled in the environment, or only the given seeders (comma separated)\n     with their dependencies. The environment defaults to APP_ENV, or production/development from APP_PRODUCTION.\n     Seeders declared Once are recorded in the seeders_ran table and never run twice.\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   db:export [directory] [--anonymise] [--connection=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Export every registered model table to gzip compressed NDJSON files and a manifest,\n     in the given directory or in backups/<timestamp>. With --anonymise the columns tagged anonymise are replaced.\n"))
+	fmt.Println(color.Colorize(color.Magenta, "   db:import [directory] [--truncate] [--batch=n] [--connection=name]"))
+	fmt.Println(color.Colorize(color.Green, "     Restore a snapshot of db:export into the database, whatever its driver, in a single transaction.\n     The tables must be empty, or emptied first with --truncate. Run the migrations first.\n"))
 	os.Exit(0)
 }
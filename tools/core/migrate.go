@@ -0,0 +1,246 @@
+package core
+
+import (
+	"GoAPIfy/core/database"
+	"GoAPIfy/core/migrator"
+	"GoAPIfy/core/storage"
+	"GoAPIfy/migration"
+	"GoAPIfy/tools/core/color"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// newMigrator connects to the named database connection and returns a migrator loaded with the application migrations targeting it.
+func newMigrator(connection string) *migrator.Migrator {
+	production, err := strconv.ParseBool(os.Getenv("APP_PRODUCTION"))
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, "Error converting APP_PRODUCTION to boolean."))
+		os.Exit(1)
+	}
+
+	db, err := database.InitConnection(connection, production)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, fmt.Sprintf("Failed to connect to the %s database: %s", connection, err)))
+		os.Exit(1)
+	}
+
+	return migrator.NewForConnection(connection, db, migration.All())
+}
+
+// migrationConnections returns the connection selected with --connection, or every connection targeted by a migration.
+func migrationConnections(args []string) []string {
+	if connection := parseConnection(args); connection != "" {
+		return []string{connection}
+	}
+	return migrator.Connections(migration.All())
+}
+
+// Migrate runs every pending migration, on every connection or on the one given with --connection.
+func Migrate(args []string) {
+	fmt.Println(color.Colorize(color.Green, "Running migrations..."))
+	total := 0
+	for _, connection := range migrationConnections(args) {
+		ran, err := newMigrator(connection).Migrate()
+		printMigrations("Migrated", ran)
+		if err != nil {
+			fmt.Println(color.Colorize(color.Red, fmt.Sprintf("%s (%s connection)", err, connection)))
+			os.Exit(1)
+		}
+		total += len(ran)
+	}
+	if total == 0 {
+		fmt.Println(color.Colorize(color.Green, "Nothing to migrate."))
+	}
+	os.Exit(0)
+}
+
+// MigrateRollback rolls back the last batch of migrations, or the last n migrations when --step is given,
+// on the main connection or on the one given with --connection.
+func MigrateRollback(args []string) {
+	step, err := parseStep(args)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+
+	fmt.Println(color.Colorize(color.Green, "Rolling back migrations..."))
+	reverted, err := newMigrator(connectionOrDefault(args)).Rollback(step)
+	printMigrations("Rolled back", reverted)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+	if len(reverted) == 0 {
+		fmt.Println(color.Colorize(color.Green, "Nothing to rollback."))
+	}
+	os.Exit(0)
+}
+
+// MigrateStatus prints every migration and whether it has been applied, on every connection or on the one given with --connection.
+func MigrateStatus(args []string) {
+	found := false
+	for _, connection := range migrationConnections(args) {
+		statuses, err := newMigrator(connection).Status()
+		if err != nil {
+			fmt.Println(color.Colorize(color.Red, err.Error()))
+			os.Exit(1)
+		}
+		if len(statuses) == 0 {
+			continue
+		}
+		found = true
+
+		fmt.Println(color.Colorize(color.Magenta, fmt.Sprintf("Connection %s:", connection)))
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Println(color.Colorize(color.Green, fmt.Sprintf("  Ran      [batch %d] %s", status.Batch, status.ID)))
+			} else {
+				fmt.Println(color.Colorize(color.Yellow, fmt.Sprintf("  Pending            %s", status.ID)))
+			}
+		}
+	}
+	if !found {
+		fmt.Println(color.Colorize(color.Yellow, "No migrations found."))
+	}
+	os.Exit(0)
+}
+
+// MigrateFresh drops every table and runs all migrations again after asking for confirmation,
+// on the main connection or on the one given with --connection.
+func MigrateFresh(args []string) {
+	connection := connectionOrDefault(args)
+	if production, _ := strconv.ParseBool(os.Getenv("APP_PRODUCTION")); production {
+		fmt.Println(color.Colorize(color.Red, "APP_PRODUCTION is true, this will drop every table in the production database!"))
+	}
+	fmt.Println(color.Colorize(color.Yellow, fmt.Sprintf("This will drop all tables of the %s connection and re-run every migration. Continue? (y/n)", connection)))
+	var input string
+	fmt.Scanln(&input)
+	if input != "y" && input != "Y" {
+		os.Exit(0)
+	}
+
+	fmt.Println(color.Colorize(color.Green, "Dropping all tables..."))
+	ran, err := newMigrator(connection).Fresh()
+	printMigrations("Migrated", ran)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// CreateMigration creates a new empty, timestamped migration file in the migration folder.
+func CreateMigration(p string) {
+	if !isMigrationName(p) {
+		fmt.Println(color.Colorize(color.Red, "Migration name can only contain lowercase letters, numbers and underscores!"))
+		os.Exit(0)
+	}
+
+	path := writeMigration(p, "./tools/templates/migration/migration.txt", nil)
+	fmt.Println(color.Colorize(color.Magenta, fmt.Sprintf("Migration %s created!", path)))
+}
+
+// writeMigration renders a migration template into a new timestamped file and returns its path.
+func writeMigration(name string, template string, replacements map[string]string) string {
+	version := time.Now().Format("20060102150405")
+	id := fmt.Sprintf("%s_%s", version, name)
+	path := fmt.Sprintf("./migration/%s.go", id)
+	if storage.FileExists(path) {
+		fmt.Println(color.Colorize(color.Red, fmt.Sprintf("%s migration is existed, cannot create new migration!", id)))
+		os.Exit(0)
+	}
+
+	src, err := ioutil.ReadFile(template)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, "GoAPIfy is corrupted, core files is missing!"))
+		os.Exit(0)
+	}
+
+	cmd := exec.Command("go", "list", "-m")
+	output, _ := cmd.Output()
+	appName := strings.TrimSpace(string(output))
+
+	content := strings.ReplaceAll(string(src), "${migrationID}", id)
+	content = strings.ReplaceAll(content, "${migrationVersion}", version)
+	content = strings.ReplaceAll(content, "${AppName}", appName)
+	for key, value := range replacements {
+		content = strings.ReplaceAll(content, key, value)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(0)
+	}
+	return path
+}
+
+// parseStep reads the --step option in either "--step=2" or "--step 2" form.
+func parseStep(args []string) (int, error) {
+	for i, arg := range args {
+		var value string
+		switch {
+		case strings.HasPrefix(arg, "--step="):
+			value = strings.TrimPrefix(arg, "--step=")
+		case arg == "--step" && i+1 < len(args):
+			value = args[i+1]
+		default:
+			continue
+		}
+		step, err := strconv.Atoi(value)
+		if err != nil || step < 1 {
+			return 0, fmt.Errorf("--step must be a positive number")
+		}
+		return step, nil
+	}
+	return 0, nil
+}
+
+// parseConnection reads the --connection option in either "--connection=legacy" or "--connection legacy" form.
+func parseConnection(args []string) string {
+	return parseOption(args, "connection")
+}
+
+// parseOption reads the value of the named option in either "--name=value" or "--name value" form.
+func parseOption(args []string, name string) string {
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--"+name+"="):
+			return strings.TrimPrefix(arg, "--"+name+"=")
+		case arg == "--"+name && i+1 < len(args):
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// connectionOrDefault returns the connection selected with --connection, or the main connection.
+func connectionOrDefault(args []string) string {
+	if connection := parseConnection(args); connection != "" {
+		return connection
+	}
+	return database.DefaultConnection
+}
+
+func printMigrations(action string, ids []string) {
+	for _, id := range ids {
+		fmt.Println(color.Colorize(color.Green, fmt.Sprintf("  %s: %s", action, id)))
+	}
+}
+
+func isMigrationName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLower(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -8,9 +8,48 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+
+	"gorm.io/gorm/schema"
 )
 
-func Model(p string) {
+// primaryKey is a kind of primary key the models created by `apify entity` can have.
+type primaryKey struct {
+	// base is the base model embedded in the model, imports the imports it needs and idType the Go type of its ID.
+	// columns are the fields of the base model, frozen in the struct of the migration creating the table.
+	base, imports, idType, columns string
+}
+
+// primaryKeys are the primary keys selected with the --id option of `apify entity`.
+var primaryKeys = map[string]primaryKey{
+	"increment": {base: "gorm.Model", imports: "\nimport (\n\t\"gorm.io/gorm\"\n)\n", idType: "uint", columns: baseColumns("uint `gorm:\"primaryKey\"`")},
+	"uuid":      {base: "UUIDModel", idType: "string", columns: baseColumns("string `gorm:\"primaryKey;size:36\"`")},
+	"ulid":      {base: "ULIDModel", idType: "string", columns: baseColumns("string `gorm:\"primaryKey;size:26\"`")},
+}
+
+// baseColumns returns the fields of a base model with the given ID field type and tag.
+func baseColumns(id string) string {
+	return "\tID        " + id + "\n\tCreatedAt time.Time\n\tUpdatedAt time.Time\n\tDeletedAt gorm.DeletedAt `gorm:\"index\"`"
+}
+
+// ParseIDOption reads the --id option in either "--id=uuid" or "--id uuid" form, and returns increment without it.
+func ParseIDOption(args []string) (string, error) {
+	id := "increment"
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--id="):
+			id = strings.TrimPrefix(arg, "--id=")
+		case arg == "--id" && i+1 < len(args):
+			id = args[i+1]
+		}
+	}
+	if _, ok := primaryKeys[id]; !ok {
+		return "", fmt.Errorf("--id must be increment, uuid or ulid")
+	}
+	return id, nil
+}
+
+// Model creates the model file and the migration of a new model, with the given kind of primary key.
+func Model(p string, id string) {
 	if containsWhitespace(p) || containsUppercase(p) || containsSymbol(p) || !containsOnlyLettersAndNumbers(p) {
 		fmt.Println(color.Colorize(color.Red, "Model name cannot contain whitespace, uppercase, and symbol!"))
 		os.Exit(0)
@@ -35,8 +74,11 @@ func Model(p string) {
 		panic(err)
 	}
 
-	// Replace the substring ${modelName} with "Example"
+	// Replace the substring ${modelName} with "Example", and the base model with the one of the primary key
+	key := primaryKeys[id]
 	modifiedBytes := []byte(strings.ReplaceAll(string(srcByte), "${modelName}", modelName))
+	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${baseModel}", key.base))
+	modifiedBytes = []byte(strings.ReplaceAll(string(modifiedBytes), "${imports}", key.imports))
 
 	out, err := os.Create(fmt.Sprintf("./model/%s.go", p))
 	if err != nil {
@@ -53,35 +95,11 @@ func Model(p string) {
 
 	fmt.Println(color.Colorize(color.Magenta, fmt.Sprintf("%s model created!", p)))
 
-	// update the migration
-	migrationFile, err := os.OpenFile("./model/database.go", os.O_RDWR, 0644)
-	if err != nil {
-		fmt.Println(color.Colorize(color.Red, err.Error()))
-		os.Exit(0)
-	}
-	defer migrationFile.Close()
-
-	migrationBytes, err := ioutil.ReadAll(migrationFile)
-	if err != nil {
-		fmt.Println(color.Colorize(color.Red, err.Error()))
-		os.Exit(0)
-	}
-	// Check if the model already exists in the migration
-	if strings.Contains(string(migrationBytes), fmt.Sprintf("&%s{}", modelName)) {
-		fmt.Println(color.Colorize(color.Green, fmt.Sprintf("%s model already exists in migration", p)))
-		return
-	}
-	// Add the new model to the migration
-	migrationContent := strings.ReplaceAll(string(migrationBytes), "AutoMigrate(\n", fmt.Sprintf("AutoMigrate(\n\t\t&%s{},\n", modelName))
-	_, err = migrationFile.Seek(0, 0)
-	if err != nil {
-		fmt.Println(color.Colorize(color.Red, err.Error()))
-		os.Exit(0)
-	}
-	_, err = migrationFile.WriteString(migrationContent)
-	if err != nil {
-		fmt.Println(color.Colorize(color.Red, err.Error()))
-		os.Exit(0)
-	}
-	fmt.Println(color.Colorize(color.Green, fmt.Sprintf("%s model added to migration", p)))
+	// create the migration for the new model table
+	path := writeMigration(fmt.Sprintf("create_%s_table", p), "./tools/templates/migration/create_table.txt", map[string]string{
+		"${modelName}": modelName,
+		"${tableName}": schema.NamingStrategy{}.TableName(modelName),
+		"${columns}":   key.columns,
+	})
+	fmt.Println(color.Colorize(color.Green, fmt.Sprintf("%s migration created at %s", p, path)))
 }
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"GoAPIfy/core/cache"
+	"GoAPIfy/core/database"
+	"GoAPIfy/core/search"
+	"GoAPIfy/model"
+	"GoAPIfy/observer"
+	"GoAPIfy/seeder"
+	"GoAPIfy/service/appService"
+	"GoAPIfy/tools/core/color"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Seed runs every seeder enabled in the environment, or only the seeders given with --class and their dependencies.
+// The environment is the one given with --env, or the one of APP_ENV and APP_PRODUCTION.
+// The seeders run with the same services as the server, so the cache, the search indexes and the observers stay in sync.
+func Seed(args []string) {
+	production, err := strconv.ParseBool(os.Getenv("APP_PRODUCTION"))
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, "Error converting APP_PRODUCTION to boolean."))
+		os.Exit(1)
+	}
+
+	env := strings.ToLower(parseOption(args, "env"))
+	if env == "" {
+		env = seeder.Environment()
+	}
+	var classes []string
+	if class := parseOption(args, "class"); class != "" {
+		classes = strings.Split(class, ",")
+	}
+
+	connections, err := database.InitConnections(production)
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, fmt.Sprintf("Failed to connect to the database: %s", err)))
+		os.Exit(1)
+	}
+	searchEngine, err := search.New(connections.Default())
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+	redisClient := database.InitRedis()
+	modelService := model.NewModel(connections.Default()).WithCache(cache.New(redisClient)).WithSearch(searchEngine).WithConnections(connections)
+	s := appService.AppService{Model: modelService, Search: searchEngine, Redis: redisClient}
+	observer.RegisterObservers(s)
+
+	fmt.Println(color.Colorize(color.Green, fmt.Sprintf("Seeding the %s environment...", env)))
+	ran, err := seeder.Run(s, env, classes...)
+
+	// Send the queued search index updates before exiting
+	if flushErr := searchEngine.Flush(); flushErr != nil {
+		fmt.Println(color.Colorize(color.Red, fmt.Sprintf("Search index update failed: %s", flushErr)))
+	}
+	for _, name := range ran {
+		fmt.Println(color.Colorize(color.Green, fmt.Sprintf("Seeded: %s", name)))
+	}
+	if err != nil {
+		fmt.Println(color.Colorize(color.Red, err.Error()))
+		os.Exit(1)
+	}
+	if len(ran) == 0 {
+		fmt.Println(color.Colorize(color.Green, "Nothing to seed."))
+	}
+	os.Exit(0)
+}
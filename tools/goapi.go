@@ -101,10 +101,15 @@ func main() {
 	if args[1] == "entity" {
 		core.PrintLogo()
 
-		if len(args) != 3 {
+		if len(args) < 3 {
 			fmt.Println(color.Colorize(color.Red, "Not enough arguments."))
 			os.Exit(0)
 		}
+		id, err := core.ParseIDOption(args[3:])
+		if err != nil {
+			fmt.Println(color.Colorize(color.Red, err.Error()))
+			os.Exit(0)
+		}
 		p := args[2]
 		folder := storage.FolderExists(fmt.Sprintf("./controller/%s", p))
 		formatter := storage.FileExists(fmt.Sprintf("./controller/%s/formatter.go", p))
@@ -130,8 +135,53 @@ func main() {
 				os.Exit(0)
 			}
 		}
-		core.Model(args[2])
-		core.Controller(args[2])
+		core.Model(args[2], id)
+		core.Controller(args[2], id)
+	}
+
+	if args[1] == "migration" {
+		core.PrintLogo()
+
+		if len(args) != 3 {
+			fmt.Println(color.Colorize(color.Red, "Not enough arguments."))
+			os.Exit(0)
+		}
+		core.CreateMigration(args[2])
+	}
+
+	if args[1] == "migrate" {
+		core.PrintLogo()
+		core.Migrate(args[2:])
+	}
+
+	if args[1] == "migrate:rollback" {
+		core.PrintLogo()
+		core.MigrateRollback(args[2:])
+	}
+
+	if args[1] == "migrate:status" {
+		core.PrintLogo()
+		core.MigrateStatus(args[2:])
+	}
+
+	if args[1] == "migrate:fresh" {
+		core.PrintLogo()
+		core.MigrateFresh(args[2:])
+	}
+
+	if args[1] == "db:seed" {
+		core.PrintLogo()
+		core.Seed(args[2:])
+	}
+
+	if args[1] == "db:export" {
+		core.PrintLogo()
+		core.DatabaseExport(args[2:])
+	}
+
+	if args[1] == "db:import" {
+		core.PrintLogo()
+		core.DatabaseImport(args[2:])
 	}
 
 	if args[1] == "docker" {